@@ -1,24 +1,33 @@
 package config
 
 import (
+	"context"
+	grpc_user "golang-gorm/app/delivery/grpc/user"
 	"golang-gorm/app/delivery/http/middleware"
-	http_user "golang-gorm/app/delivery/http/user"
+	"golang-gorm/app/delivery/http/openapi"
+	"golang-gorm/app/event"
+	"golang-gorm/app/job"
+	"golang-gorm/app/pubsub"
+	memoryrepo "golang-gorm/app/repository/memory"
 	postgresrepo "golang-gorm/app/repository/postgres"
-	s3repo "golang-gorm/app/repository/s3"
 	"golang-gorm/app/usecase"
-	usecase_user "golang-gorm/app/usecase/user"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
 type BootstrapConfig struct {
-	GinEngine *gin.Engine
-	DB        *gorm.DB
-	Validator *validator.Validate
-	Timeout   time.Duration
+	// Context is canceled on shutdown, which stops the background jobs.
+	Context    context.Context
+	GinEngine  *gin.Engine
+	GRPCServer *grpc.Server
+	DB         *gorm.DB
+	Validator  *validator.Validate
+	Timeout    time.Duration
 }
 
 func Bootstrap(config BootstrapConfig) {
@@ -26,34 +35,160 @@ func Bootstrap(config BootstrapConfig) {
 	userRepository := postgresrepo.NewUserRepository(config.DB)
 	todoRepository := postgresrepo.NewTodoRepository(config.DB)
 	fileRepository := postgresrepo.NewFileRepository(config.DB)
+	todoAttachmentRepository := postgresrepo.NewTodoAttachmentRepository(config.DB)
+	todoHistoryRepository := postgresrepo.NewTodoHistoryRepository(config.DB)
+	webhookRepository := postgresrepo.NewWebhookRepository(config.DB)
+	webhookDeliveryRepository := postgresrepo.NewWebhookDeliveryRepository(config.DB)
+	outboxRepository := postgresrepo.NewOutboxRepository(config.DB)
+	apiKeyRepository := postgresrepo.NewAPIKeyRepository(config.DB)
 
-	// init s3 repository
-	s3Repository := s3repo.NewS3Repository(config.Timeout)
+	// init idempotency key store
+	var idempotencyKeyRepository postgresrepo.IdempotencyKeyRepository
+	switch viper.GetString("IDEMPOTENCY_STORE") {
+	case "memory":
+		idempotencyKeyRepository = memoryrepo.NewIdempotencyKeyRepository()
+	default:
+		idempotencyKeyRepository = postgresrepo.NewIdempotencyKeyRepository(config.DB)
+	}
+
+	// init pubsub
+	var pubSub pubsub.PubSub
+	switch viper.GetString("PUBSUB_DRIVER") {
+	case "postgres":
+		pubSub = pubsub.NewPostgresPubSub(config.Context, config.DB, viper.GetString("DB_URL"))
+	default:
+		pubSub = pubsub.NewMemoryPubSub()
+	}
+
+	// init domain event bus
+	eventBus := event.NewBus()
+
+	// init blob store
+	blobStore, blobURLSigner := newBlobStore(config.Timeout)
+
+	// init file scanner
+	fileScanner := newFileScanner()
 
 	// init usecase
-	userAuthUsecase := usecase_user.NewAuthUsecase(usecase.UsecaseDependency{
-		UserRepository: userRepository,
-		Validate:       config.Validator,
-		Timeout:        config.Timeout,
-	})
-	userTodoUsecase := usecase_user.NewTodoUsecase(usecase.UsecaseDependency{
-		TodoRepository: todoRepository,
-		Validate:       config.Validator,
-		Timeout:        config.Timeout,
-	})
-	userSettingUsecase := usecase_user.NewSettingUsecase(usecase.UsecaseDependency{
-		UserRepository: userRepository,
-		FileRepository: fileRepository,
-		S3Repository:   s3Repository,
-		Validate:       config.Validator,
-		Timeout:        config.Timeout,
+	userUsecases := newUserUsecases(usecase.UsecaseDependency{
+		Validate:                  config.Validator,
+		Timeout:                   config.Timeout,
+		BlobStore:                 blobStore,
+		FileScanner:               fileScanner,
+		UserRepository:            userRepository,
+		TodoRepository:            todoRepository,
+		FileRepository:            fileRepository,
+		TodoAttachmentRepository:  todoAttachmentRepository,
+		TodoHistoryRepository:     todoHistoryRepository,
+		WebhookRepository:         webhookRepository,
+		WebhookDeliveryRepository: webhookDeliveryRepository,
+		APIKeyRepository:          apiKeyRepository,
+		PubSub:                    pubSub,
 	})
 
+	// subscribe to domain events
+	eventBus.Subscribe("stream", userUsecases.Stream.HandleTodoEvent)
+
+	// init outbox sinks
+	outboxSinks := newOutboxSinks(eventBus, userUsecases.Webhook.HandleTodoEvent)
+
 	// init auth middleware
 	authMiddleware := middleware.NewAuthMiddleware()
 
-	// init http delivery
-	http_user.NewAuthHandler(config.GinEngine, authMiddleware, userAuthUsecase)
-	http_user.NewTodoHandler(config.GinEngine, authMiddleware, userTodoUsecase)
-	http_user.NewSettingHandler(config.GinEngine, authMiddleware, userSettingUsecase)
+	// init idempotency middleware
+	idempotencyTTLHours := viper.GetInt("IDEMPOTENCY_TTL_HOURS")
+	if idempotencyTTLHours <= 0 {
+		idempotencyTTLHours = 24
+	}
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyKeyRepository, time.Duration(idempotencyTTLHours)*time.Hour)
+
+	// init http and graphql delivery
+	registerHTTPRoutes(config.GinEngine, httpDelivery{
+		AuthMiddleware:        authMiddleware,
+		IdempotencyMiddleware: idempotencyMiddleware,
+		AuthUsecase:           userUsecases.Auth,
+		TodoUsecase:           userUsecases.Todo,
+		SettingUsecase:        userUsecases.Setting,
+		FileUsecase:           userUsecases.File,
+		StreamUsecase:         userUsecases.Stream,
+		WebhookUsecase:        userUsecases.Webhook,
+		APIKeyUsecase:         userUsecases.APIKey,
+		BlobStore:             blobStore,
+		BlobURLSigner:         blobURLSigner,
+	})
+
+	// init openapi document, after every other http route
+	openapi.NewOpenAPIHandler(config.GinEngine, newOpenAPISpec())
+
+	// init grpc delivery
+	grpc_user.NewAuthServer(config.GRPCServer, userUsecases.Auth)
+	grpc_user.NewTodoServer(config.GRPCServer, userUsecases.Todo)
+
+	// init background jobs
+	trashRetentionDays := viper.GetInt("TODO_TRASH_RETENTION_DAYS")
+	if trashRetentionDays <= 0 {
+		trashRetentionDays = 30
+	}
+	go job.RunEvery(config.Context, time.Hour, job.NewTodoRetentionJob(todoRepository, time.Duration(trashRetentionDays)*24*time.Hour))
+
+	positionMaxLength := viper.GetInt("TODO_POSITION_MAX_LENGTH")
+	if positionMaxLength <= 0 {
+		positionMaxLength = 32
+	}
+	go job.RunEvery(config.Context, time.Hour, job.NewTodoRebalanceJob(todoRepository, positionMaxLength))
+
+	go job.RunEvery(config.Context, time.Hour, job.NewIdempotencyKeyCleanupJob(idempotencyKeyRepository))
+
+	pendingUploadHours := viper.GetInt("FILE_PENDING_UPLOAD_HOURS")
+	if pendingUploadHours <= 0 {
+		pendingUploadHours = 24
+	}
+	go job.RunEvery(config.Context, time.Hour, job.NewFileUploadCleanupJob(userUsecases.File, time.Duration(pendingUploadHours)*time.Hour))
+
+	if fileScanner.Enabled() {
+		go job.RunEvery(config.Context, 5*time.Minute, job.NewFileScanJob(userUsecases.File, 5*time.Minute))
+	}
+
+	fileSweepGraceHours := viper.GetInt("FILE_SWEEP_GRACE_HOURS")
+	if fileSweepGraceHours <= 0 {
+		fileSweepGraceHours = 24
+	}
+	fileRetentionDays := viper.GetInt("FILE_TRASH_RETENTION_DAYS")
+	if fileRetentionDays <= 0 {
+		fileRetentionDays = 7
+	}
+	go job.RunEvery(config.Context, 6*time.Hour, job.NewFileSweepJob(
+		userUsecases.File,
+		time.Duration(fileSweepGraceHours)*time.Hour,
+		time.Duration(fileRetentionDays)*24*time.Hour,
+		viper.GetBool("FILE_SWEEP_DRY_RUN"),
+	))
+
+	storageUsageHours := viper.GetInt("STORAGE_USAGE_RECOMPUTE_HOURS")
+	if storageUsageHours <= 0 {
+		storageUsageHours = 24
+	}
+	go job.RunEvery(config.Context, time.Duration(storageUsageHours)*time.Hour, job.NewStorageUsageJob(userUsecases.File))
+
+	webhookPollSeconds := viper.GetInt("WEBHOOK_POLL_SECONDS")
+	if webhookPollSeconds <= 0 {
+		webhookPollSeconds = 5
+	}
+	go job.RunEvery(config.Context, time.Duration(webhookPollSeconds)*time.Second, job.NewWebhookDeliveryJob(userUsecases.Webhook))
+
+	outboxPollMilliseconds := viper.GetInt("OUTBOX_POLL_MILLISECONDS")
+	if outboxPollMilliseconds <= 0 {
+		outboxPollMilliseconds = 500
+	}
+	outboxMaxAttempts := viper.GetInt("OUTBOX_MAX_ATTEMPTS")
+	if outboxMaxAttempts <= 0 {
+		outboxMaxAttempts = 20
+	}
+	go job.RunEvery(config.Context, time.Duration(outboxPollMilliseconds)*time.Millisecond, job.NewOutboxRelayJob(outboxRepository, outboxSinks, outboxMaxAttempts))
+
+	outboxRetentionHours := viper.GetInt("OUTBOX_RETENTION_HOURS")
+	if outboxRetentionHours <= 0 {
+		outboxRetentionHours = 168
+	}
+	go job.RunEvery(config.Context, time.Hour, job.NewOutboxCleanupJob(outboxRepository, time.Duration(outboxRetentionHours)*time.Hour))
 }
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	http_blob "golang-gorm/app/delivery/http/blob"
+	blobrepo "golang-gorm/app/repository/blob"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// newBlobStore builds the store chosen by BLOB_STORE. The local and memory
+// stores come with the signer their presigned urls are served with, nil for
+// S3 which serves its own.
+func newBlobStore(timeout time.Duration) (blobrepo.BlobStore, *blobrepo.URLSigner) {
+	var blobStore blobrepo.BlobStore
+	var signer *blobrepo.URLSigner
+	var err error
+
+	switch driver := viper.GetString("BLOB_STORE"); driver {
+	case "", "s3":
+		blobStore, err = blobrepo.NewS3BlobStore(timeout)
+	case "local":
+		signer = newBlobURLSigner()
+		dir := viper.GetString("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = "./storage"
+		}
+		blobStore, err = blobrepo.NewLocalBlobStore(dir, signer)
+	case "memory":
+		signer = newBlobURLSigner()
+		blobStore = blobrepo.NewMemoryBlobStore(signer)
+	default:
+		err = fmt.Errorf("unknown BLOB_STORE %q, use s3, local or memory", driver)
+	}
+	if err != nil {
+		logrus.Fatal("failed to init blob store: ", err)
+	}
+
+	return blobStore, signer
+}
+
+func newBlobURLSigner() *blobrepo.URLSigner {
+	baseURL := viper.GetString("BLOB_PUBLIC_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + viper.GetString("PORT")
+	}
+
+	// a random key works for a single instance until it restarts
+	secret := []byte(viper.GetString("BLOB_SIGNING_KEY"))
+	if len(secret) == 0 {
+		logrus.Warn("BLOB_SIGNING_KEY is not set, presigned urls won't survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			logrus.Fatal("failed to generate blob signing key: ", err)
+		}
+	}
+
+	expiry := time.Duration(viper.GetInt("S3_EXPIRES_TIME")) * time.Second
+	return blobrepo.NewURLSigner(baseURL+http_blob.RoutePrefix, secret, expiry)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	graphql_user "golang-gorm/app/delivery/graphql/user"
+	http_blob "golang-gorm/app/delivery/http/blob"
+	"golang-gorm/app/delivery/http/middleware"
+	"golang-gorm/app/delivery/http/openapi"
+	http_user "golang-gorm/app/delivery/http/user"
+	blobrepo "golang-gorm/app/repository/blob"
+	usecase_user "golang-gorm/app/usecase/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// httpDelivery is what the http and graphql routes are served with.
+type httpDelivery struct {
+	AuthMiddleware        middleware.AuthMiddleware
+	IdempotencyMiddleware middleware.IdempotencyMiddleware
+	AuthUsecase           usecase_user.AuthUsecase
+	TodoUsecase           usecase_user.TodoUsecase
+	SettingUsecase        usecase_user.SettingUsecase
+	FileUsecase           usecase_user.FileUsecase
+	StreamUsecase         usecase_user.StreamUsecase
+	WebhookUsecase        usecase_user.WebhookUsecase
+	APIKeyUsecase         usecase_user.APIKeyUsecase
+	BlobStore             blobrepo.BlobStore
+	BlobURLSigner         *blobrepo.URLSigner
+}
+
+// registerHTTPRoutes registers the http and graphql routes, except for the
+// openapi document which has to come after them.
+func registerHTTPRoutes(ginEngine *gin.Engine, d httpDelivery) {
+	// init http delivery
+	http_user.NewAuthHandler(ginEngine, d.AuthMiddleware, d.AuthUsecase)
+	http_user.NewTodoHandler(ginEngine, d.AuthMiddleware, d.IdempotencyMiddleware, d.TodoUsecase)
+	http_user.NewSettingHandler(ginEngine, d.AuthMiddleware, d.SettingUsecase)
+	http_user.NewFileHandler(ginEngine, d.AuthMiddleware, d.FileUsecase)
+	http_user.NewCalDAVHandler(ginEngine, d.AuthUsecase, d.APIKeyUsecase, d.TodoUsecase)
+	http_user.NewStreamHandler(ginEngine, d.AuthMiddleware, d.StreamUsecase)
+	http_user.NewWebhookHandler(ginEngine, d.AuthMiddleware, d.WebhookUsecase)
+	http_user.NewAPIKeyHandler(ginEngine, d.AuthMiddleware, d.APIKeyUsecase)
+	if d.BlobURLSigner != nil {
+		http_blob.NewBlobHandler(ginEngine, d.BlobStore, d.BlobURLSigner)
+	}
+
+	// init graphql delivery
+	graphql_user.NewGraphQLHandler(ginEngine, d.AuthMiddleware, d.AuthUsecase, d.TodoUsecase, d.SettingUsecase)
+}
+
+// newOpenAPISpec describes every route registered by registerHTTPRoutes.
+func newOpenAPISpec() *openapi.Spec {
+	spec := openapi.NewSpec(openapi.Info{
+		Title:   "golang-gorm",
+		Version: "1.0.0",
+	})
+	http_user.DescribeRoutes(spec)
+	graphql_user.DescribeRoutes(spec)
+	http_blob.DescribeRoutes(spec)
+	return spec
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"golang-gorm/app/delivery/http/middleware"
+	blobrepo "golang-gorm/app/repository/blob"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIDocumentsEveryRoute fails while a route is missing from the
+// openapi document, or the document describes a route that doesn't exist.
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginEngine := gin.New()
+
+	// handlers only keep their usecases, none are called here
+	registerHTTPRoutes(ginEngine, httpDelivery{
+		AuthMiddleware:        middleware.NewAuthMiddleware(),
+		IdempotencyMiddleware: middleware.NewIdempotencyMiddleware(nil, time.Hour),
+		BlobURLSigner:         blobrepo.NewURLSigner("http://localhost", []byte("secret"), time.Minute),
+	})
+
+	document, problems := newOpenAPISpec().Build(ginEngine.Routes())
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+	if len(document.Paths) == 0 {
+		t.Error("the document has no paths")
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang-gorm/app/event"
+	"golang-gorm/app/outbox"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// newOutboxSinks builds the sinks listed in OUTBOX_SINKS. NATS and Kafka
+// fall back to a logging stand-in when their address isn't configured.
+func newOutboxSinks(eventBus event.Bus, webhookHandler event.Handler) []outbox.Sink {
+	names := viper.GetString("OUTBOX_SINKS")
+	if names == "" {
+		names = "bus,webhook"
+	}
+
+	sinks := []outbox.Sink{}
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "bus":
+			sinks = append(sinks, outbox.NewBusSink(eventBus))
+		case "webhook":
+			sinks = append(sinks, outbox.NewWebhookSink(webhookHandler))
+		case "nats":
+			natsURL := viper.GetString("OUTBOX_NATS_URL")
+			if natsURL == "" {
+				logrus.Warn("OUTBOX_NATS_URL is not set, logging nats events instead")
+				sinks = append(sinks, outbox.NewLogSink(name))
+				continue
+			}
+			subjectPrefix := viper.GetString("OUTBOX_NATS_SUBJECT_PREFIX")
+			if subjectPrefix == "" {
+				subjectPrefix = "app"
+			}
+			sink, err := outbox.NewNATSSink(natsURL, subjectPrefix)
+			if err != nil {
+				panic(fmt.Errorf("failed to connect to nats: %w", err))
+			}
+			sinks = append(sinks, sink)
+		case "kafka":
+			kafkaURL := viper.GetString("OUTBOX_KAFKA_REST_URL")
+			if kafkaURL == "" {
+				logrus.Warn("OUTBOX_KAFKA_REST_URL is not set, logging kafka events instead")
+				sinks = append(sinks, outbox.NewLogSink(name))
+				continue
+			}
+			topic := viper.GetString("OUTBOX_KAFKA_TOPIC")
+			if topic == "" {
+				topic = "app-events"
+			}
+			sinks = append(sinks, outbox.NewKafkaSink(kafkaURL, topic))
+		case "":
+		default:
+			panic(fmt.Errorf("unknown outbox sink %q", name))
+		}
+	}
+
+	return sinks
+}
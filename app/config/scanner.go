@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"golang-gorm/app/scanner"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// newFileScanner builds the malware scanner chosen by FILE_SCANNER.
+func newFileScanner() scanner.FileScanner {
+	var fileScanner scanner.FileScanner
+	var err error
+
+	switch driver := viper.GetString("FILE_SCANNER"); driver {
+	case "", "none":
+		return scanner.NewNoopScanner()
+	case "clamav":
+		network := viper.GetString("CLAMAV_NETWORK")
+		if network == "" {
+			network = "tcp"
+		}
+		timeoutSeconds := viper.GetInt("CLAMAV_TIMEOUT_SECONDS")
+		if timeoutSeconds <= 0 {
+			timeoutSeconds = 60
+		}
+		fileScanner, err = scanner.NewClamAVScanner(network, viper.GetString("CLAMAV_ADDRESS"), time.Duration(timeoutSeconds)*time.Second)
+	default:
+		err = fmt.Errorf("unknown FILE_SCANNER %q, use none or clamav", driver)
+	}
+	if err != nil {
+		logrus.Fatal("failed to init file scanner: ", err)
+	}
+
+	// opt-in, clamd may still be loading its signatures at start
+	if viper.GetBool("FILE_SCANNER_CHECK") {
+		if err := scanner.Check(context.Background(), fileScanner); err != nil {
+			logrus.Fatal("file scanner failed its check: ", err)
+		}
+		logrus.Info("file scanner passed its check")
+	}
+
+	return fileScanner
+}
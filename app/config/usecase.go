@@ -0,0 +1,73 @@
+package config
+
+import (
+	"golang-gorm/app/usecase"
+	usecase_user "golang-gorm/app/usecase/user"
+)
+
+// userUsecases are the usecases the deliveries and background jobs are
+// served with.
+type userUsecases struct {
+	Auth    usecase_user.AuthUsecase
+	Todo    usecase_user.TodoUsecase
+	Setting usecase_user.SettingUsecase
+	File    usecase_user.FileUsecase
+	APIKey  usecase_user.APIKeyUsecase
+	Stream  usecase_user.StreamUsecase
+	Webhook usecase_user.WebhookUsecase
+}
+
+// newUserUsecases hands each usecase the dependencies it needs out of d,
+// which holds all of them.
+func newUserUsecases(d usecase.UsecaseDependency) userUsecases {
+	return userUsecases{
+		Auth: usecase_user.NewAuthUsecase(usecase.UsecaseDependency{
+			UserRepository: d.UserRepository,
+			BlobStore:      d.BlobStore,
+			FileScanner:    d.FileScanner,
+			Validate:       d.Validate,
+			Timeout:        d.Timeout,
+		}),
+		Todo: usecase_user.NewTodoUsecase(usecase.UsecaseDependency{
+			UserRepository:           d.UserRepository,
+			TodoRepository:           d.TodoRepository,
+			FileRepository:           d.FileRepository,
+			BlobStore:                d.BlobStore,
+			FileScanner:              d.FileScanner,
+			Validate:                 d.Validate,
+			Timeout:                  d.Timeout,
+			TodoAttachmentRepository: d.TodoAttachmentRepository,
+			TodoHistoryRepository:    d.TodoHistoryRepository,
+		}),
+		Setting: usecase_user.NewSettingUsecase(usecase.UsecaseDependency{
+			UserRepository: d.UserRepository,
+			FileRepository: d.FileRepository,
+			BlobStore:      d.BlobStore,
+			FileScanner:    d.FileScanner,
+			Validate:       d.Validate,
+			Timeout:        d.Timeout,
+		}),
+		File: usecase_user.NewFileUsecase(usecase.UsecaseDependency{
+			UserRepository: d.UserRepository,
+			FileRepository: d.FileRepository,
+			BlobStore:      d.BlobStore,
+			FileScanner:    d.FileScanner,
+			Validate:       d.Validate,
+			Timeout:        d.Timeout,
+		}),
+		APIKey: usecase_user.NewAPIKeyUsecase(usecase.UsecaseDependency{
+			APIKeyRepository: d.APIKeyRepository,
+			Validate:         d.Validate,
+			Timeout:          d.Timeout,
+		}),
+		Stream: usecase_user.NewStreamUsecase(usecase.UsecaseDependency{
+			PubSub: d.PubSub,
+		}),
+		Webhook: usecase_user.NewWebhookUsecase(usecase.UsecaseDependency{
+			WebhookRepository:         d.WebhookRepository,
+			WebhookDeliveryRepository: d.WebhookDeliveryRepository,
+			Validate:                  d.Validate,
+			Timeout:                   d.Timeout,
+		}),
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang-gorm/app/pubsub"
+	blobrepo "golang-gorm/app/repository/blob"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/scanner"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestUserUsecasesAreWired builds the usecases like Bootstrap does and runs
+// what used to panic on a dependency left out of the wiring.
+func TestUserUsecasesAreWired(t *testing.T) {
+	// no database is reached, queries are only built
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := usecase.UsecaseDependency{
+		Validate:                  NewValidator(),
+		Timeout:                   time.Second,
+		BlobStore:                 blobrepo.NewMemoryBlobStore(blobrepo.NewURLSigner("http://localhost", []byte("secret"), time.Minute)),
+		FileScanner:               scanner.NewNoopScanner(),
+		UserRepository:            postgresrepo.NewUserRepository(db),
+		TodoRepository:            postgresrepo.NewTodoRepository(db),
+		FileRepository:            postgresrepo.NewFileRepository(db),
+		TodoAttachmentRepository:  postgresrepo.NewTodoAttachmentRepository(db),
+		TodoHistoryRepository:     postgresrepo.NewTodoHistoryRepository(db),
+		WebhookRepository:         postgresrepo.NewWebhookRepository(db),
+		WebhookDeliveryRepository: postgresrepo.NewWebhookDeliveryRepository(db),
+		APIKeyRepository:          postgresrepo.NewAPIKeyRepository(db),
+		PubSub:                    pubsub.NewMemoryPubSub(),
+	}
+	dependencies := reflect.ValueOf(d)
+	for i := 0; i < dependencies.NumField(); i++ {
+		if dependencies.Field(i).IsZero() {
+			t.Fatalf("the test leaves %s out", dependencies.Type().Field(i).Name)
+		}
+	}
+
+	usecases := newUserUsecases(d)
+
+	// the storage usage job runs right at boot
+	if _, err := usecases.File.RecomputeStorageUsage(context.Background()); err != nil {
+		t.Errorf("recompute storage usage: %v", err)
+	}
+
+	// the quota check looks up the user, saving the file then fails without
+	// a database
+	usecases.File.CreateUploadURL(context.Background(), model.JWTClaimUser{UserID: "user"}, request.CreateUploadURLRequest{
+		Name:     "notes.txt",
+		MimeType: "text/plain",
+		Size:     5,
+		Sha256:   "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	})
+}
@@ -0,0 +1,153 @@
+package graphql_user
+
+import (
+	"encoding/json"
+	"golang-gorm/app/delivery/http/middleware"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type graphQLHandler struct {
+	AuthUsecase    usecase_user.AuthUsecase
+	TodoUsecase    usecase_user.TodoUsecase
+	SettingUsecase usecase_user.SettingUsecase
+	Route          *gin.RouterGroup
+	Middleware     middleware.AuthMiddleware
+	Schema         graphql.Schema
+	MaxDepth       int
+	MaxComplexity  int
+}
+
+func NewGraphQLHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, authUsecase usecase_user.AuthUsecase, todoUsecase usecase_user.TodoUsecase, settingUsecase usecase_user.SettingUsecase) {
+	handler := &graphQLHandler{
+		AuthUsecase:    authUsecase,
+		TodoUsecase:    todoUsecase,
+		SettingUsecase: settingUsecase,
+		Route:          ginEngine.Group(""),
+		Middleware:     middleware,
+		MaxDepth:       viper.GetInt("GRAPHQL_MAX_DEPTH"),
+		MaxComplexity:  viper.GetInt("GRAPHQL_MAX_COMPLEXITY"),
+	}
+	if handler.MaxDepth <= 0 {
+		handler.MaxDepth = 8
+	}
+	if handler.MaxComplexity <= 0 {
+		handler.MaxComplexity = 1000
+	}
+
+	schema, err := handler.newSchema()
+	if err != nil {
+		logrus.Fatal("invalid graphql schema: ", err)
+	}
+	handler.Schema = schema
+
+	handler.handleGraphQLRoute("/graphql")
+}
+
+func (h *graphQLHandler) handleGraphQLRoute(path string) {
+	api := h.Route.Group(path)
+
+	// login and register work without a token, every other field checks it
+	api.GET("", h.Middleware.AuthUserOptional(), h.Query)
+	api.POST("", h.Middleware.AuthUserOptional(), h.Query)
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (r *graphQLHandler) Query(c *gin.Context) {
+	payload := graphQLRequest{}
+	if c.Request.Method == http.MethodGet {
+		payload.Query = c.Query("query")
+		payload.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &payload.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, graphQLError("invalid variables"))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, graphQLError("invalid json data"))
+		return
+	}
+
+	// parse and validate first so limits are checked before anything runs
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(payload.Query),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	validation := graphql.ValidateDocument(&r.Schema, document, nil)
+	if !validation.IsValid {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	if err := checkQueryLimits(document, payload.Variables, r.MaxDepth, r.MaxComplexity); err != nil {
+		c.JSON(http.StatusBadRequest, graphQLError(err.Error()))
+		return
+	}
+
+	// GET requests may be cached or prefetched, they can't change anything
+	if c.Request.Method == http.MethodGet && isMutation(document, payload.OperationName) {
+		c.JSON(http.StatusMethodNotAllowed, graphQLError("mutations have to be sent with POST"))
+		return
+	}
+
+	var claim *model.JWTClaimUser
+	if userData, ok := c.Get("user_data"); ok {
+		userClaim := userData.(model.JWTClaimUser)
+		claim = &userClaim
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        r.Schema,
+		AST:           document,
+		OperationName: payload.OperationName,
+		Args:          payload.Variables,
+		Context:       r.withRequestState(c.Request.Context(), claim),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+func graphQLError(message string) *graphql.Result {
+	return &graphql.Result{
+		Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)},
+	}
+}
+
+// isMutation reports whether the operation that would run is a mutation.
+func isMutation(document *ast.Document, operationName string) bool {
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			if operation.Operation == ast.OperationTypeMutation {
+				return true
+			}
+		}
+	}
+	return false
+}
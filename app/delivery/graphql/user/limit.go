@@ -0,0 +1,113 @@
+package graphql_user
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is assumed for paginated fields queried without a limit,
+// it matches the REST default page size.
+const defaultListSize = 10
+
+// queryCost walks a validated document and returns the depth and complexity
+// of its operations. Every field costs one, the fields below a paginated
+// field count once per item it may return. Introspection fields are free so
+// tooling keeps working under tight limits.
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func checkQueryLimits(doc *ast.Document, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	cost := queryCost{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		depth, complexity := cost.selectionSet(operation.SelectionSet, map[string]bool{})
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, maxDepth)
+		}
+		if complexity > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, maxComplexity)
+		}
+	}
+
+	return nil
+}
+
+func (c queryCost) selectionSet(set *ast.SelectionSet, visiting map[string]bool) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, n int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if len(selection.Name.Value) > 1 && selection.Name.Value[:2] == "__" {
+				continue
+			}
+			childDepth, childComplexity := c.selectionSet(selection.SelectionSet, visiting)
+			d = childDepth + 1
+			n = 1 + childComplexity*c.listSize(selection)
+		case *ast.InlineFragment:
+			d, n = c.selectionSet(selection.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			// validation rejects fragment cycles, this only guards the walk
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			d, n = c.selectionSet(fragment.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+
+		if d > depth {
+			depth = d
+		}
+		complexity += n
+	}
+
+	return depth, complexity
+}
+
+// listSize is how many items a field may return, taken from its limit argument.
+func (c queryCost) listSize(field *ast.Field) int {
+	if !paginatedFields[field.Name.Value] {
+		return 1
+	}
+
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if limit, err := strconv.Atoi(value.Value); err == nil && limit > 0 {
+				return limit
+			}
+		case *ast.Variable:
+			if limit, ok := c.variables[value.Name.Value].(float64); ok && limit > 0 {
+				return int(limit)
+			}
+		}
+	}
+
+	return defaultListSize
+}
@@ -0,0 +1,88 @@
+package graphql_user
+
+import (
+	"context"
+	"sync"
+)
+
+// batchFunc loads the values of many keys at once. Keys missing from the
+// returned map resolve to the zero value.
+type batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// loader batches the loads of one request. Load only queues the key and
+// returns a thunk, the executor resolves thunks after it has walked every
+// sibling field, so a whole list level is fetched with a single batch call.
+type loader[K comparable, V any] struct {
+	batch batchFunc[K, V]
+
+	mu      sync.Mutex
+	current *loaderBatch[K, V]
+	cache   map[K]V
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	once    sync.Once
+	results map[K]V
+	err     error
+}
+
+func newLoader[K comparable, V any](batch batchFunc[K, V]) *loader[K, V] {
+	return &loader[K, V]{
+		batch: batch,
+		cache: map[K]V{},
+	}
+}
+
+func (l *loader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if value, ok := l.cache[key]; ok {
+		return func() (V, error) { return value, nil }
+	}
+
+	if l.current == nil {
+		l.current = &loaderBatch[K, V]{}
+	}
+	b := l.current
+	b.keys = append(b.keys, key)
+
+	return func() (V, error) {
+		b.once.Do(func() { l.dispatch(ctx, b) })
+		return b.results[key], b.err
+	}
+}
+
+func (l *loader[K, V]) dispatch(ctx context.Context, b *loaderBatch[K, V]) {
+	// later loads start a new batch
+	l.mu.Lock()
+	if l.current == b {
+		l.current = nil
+	}
+	keys := uniqueKeys(b.keys)
+	l.mu.Unlock()
+
+	b.results, b.err = l.batch(ctx, keys)
+	if b.err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	for _, key := range keys {
+		l.cache[key] = b.results[key]
+	}
+	l.mu.Unlock()
+}
+
+func uniqueKeys[K comparable](keys []K) []K {
+	seen := make(map[K]bool, len(keys))
+	unique := make([]K, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,30 @@
+package graphql_user
+
+import (
+	"golang-gorm/app/delivery/http/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DescribeRoutes documents the graphql endpoint, the schema itself is
+// available through introspection.
+func DescribeRoutes(spec *openapi.Spec) {
+	spec.Tag("/graphql", "GraphQL")
+
+	spec.Describe(http.MethodGet, "/graphql", openapi.Operation{
+		Summary: "Run a graphql query",
+		Parameters: []openapi.Parameter{
+			openapi.QueryParameter("query", "the graphql document", &openapi.Schema{Type: "string"}),
+			openapi.QueryParameter("operationName", "", &openapi.Schema{Type: "string"}),
+			openapi.QueryParameter("variables", "variables as a json object", &openapi.Schema{Type: "string"}),
+		},
+		Produces: []string{gin.MIMEJSON},
+		Errors:   []int{http.StatusMethodNotAllowed},
+	})
+	spec.Describe(http.MethodPost, "/graphql", openapi.Operation{
+		Summary:  "Run a graphql query or mutation",
+		Body:     graphQLRequest{},
+		Produces: []string{gin.MIMEJSON},
+	})
+}
@@ -0,0 +1,357 @@
+package graphql_user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/graphql-go/graphql"
+)
+
+// maxPageSize caps the limit argument of paginated fields.
+const maxPageSize = 100
+
+var errUnauthenticated = &responseError{
+	status:  http.StatusUnauthorized,
+	message: "Unauthorized: Missing Authorization header",
+}
+
+type requestStateKey struct{}
+
+// requestState is what resolvers share within a single request.
+type requestState struct {
+	claim       *model.JWTClaimUser
+	attachments *loader[string, []*model.File]
+}
+
+func (h *graphQLHandler) withRequestState(ctx context.Context, claim *model.JWTClaimUser) context.Context {
+	state := &requestState{claim: claim}
+	if claim != nil {
+		state.attachments = newLoader(func(ctx context.Context, todoIDs []string) (map[string][]*model.File, error) {
+			response := h.TodoUsecase.GetAttachmentsByTodoIDs(ctx, *claim, todoIDs)
+			if response.Status >= http.StatusBadRequest {
+				return nil, newResponseError(response)
+			}
+			return response.Data.(map[string][]*model.File), nil
+		})
+	}
+	return context.WithValue(ctx, requestStateKey{}, state)
+}
+
+func stateFrom(ctx context.Context) (*requestState, error) {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	if state == nil || state.claim == nil {
+		return nil, errUnauthenticated
+	}
+	return state, nil
+}
+
+// responseError carries the status of a usecase response to the client in
+// the error extensions.
+type responseError struct {
+	status     int
+	message    string
+	validation []helpers.ValidationError
+}
+
+func newResponseError(response helpers.Response) *responseError {
+	return &responseError{
+		status:     response.Status,
+		message:    response.Message,
+		validation: response.Validation,
+	}
+}
+
+func (e *responseError) Error() string {
+	return e.message
+}
+
+func (e *responseError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"status": e.status,
+		"code":   errorCode(e.status),
+	}
+	if len(e.validation) > 0 {
+		extensions["validation"] = e.validation
+	}
+	return extensions
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "BAD_REQUEST"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "FORBIDDEN"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	case http.StatusPreconditionFailed:
+		return "VERSION_CONFLICT"
+	default:
+		return "INTERNAL_SERVER_ERROR"
+	}
+}
+
+// responseData returns the data of a successful usecase response.
+func responseData(response helpers.Response) (interface{}, error) {
+	if response.Status >= http.StatusBadRequest {
+		return nil, newResponseError(response)
+	}
+	return response.Data, nil
+}
+
+func (h *graphQLHandler) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseData(h.AuthUsecase.GetProfile(p.Context, *state.claim))
+}
+
+func (h *graphQLHandler) resolveTodos(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	page, _ := p.Args["page"].(int)
+	limit, _ := p.Args["limit"].(int)
+	if limit > maxPageSize {
+		return nil, &responseError{
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("limit must not exceed %d", maxPageSize),
+		}
+	}
+
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+		if status, ok := filter["status"].(model.TodoStatus); ok {
+			query.Set("status", string(status))
+		}
+	}
+
+	response := h.TodoUsecase.GetAll(p.Context, *state.claim, query)
+	if response.Status >= http.StatusBadRequest {
+		return nil, &responseError{status: response.Status, message: response.Message}
+	}
+
+	// an empty page carries an untyped empty slice
+	todos, _ := response.Data.([]*model.Todo)
+	meta := response.Meta.(map[string]interface{})
+	return &todoPage{
+		Items: todos,
+		Page:  meta["page"].(int),
+		Limit: meta["limit"].(int),
+		Total: meta["total"].(int64),
+	}, nil
+}
+
+func (h *graphQLHandler) resolveTodoCount(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("limit", "1")
+	if status, ok := p.Args["status"].(model.TodoStatus); ok {
+		query.Set("status", string(status))
+	}
+
+	response := h.TodoUsecase.GetAll(p.Context, *state.claim, query)
+	if response.Status >= http.StatusBadRequest {
+		return nil, &responseError{status: response.Status, message: response.Message}
+	}
+
+	return response.Meta.(map[string]interface{})["total"], nil
+}
+
+func (h *graphQLHandler) resolveTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseData(h.TodoUsecase.GetOne(p.Context, *state.claim, p.Args["id"].(string)))
+}
+
+// resolveTodoAttachments queues the todo on the attachment loader, the
+// attachments of every todo in the list are fetched together.
+func (h *graphQLHandler) resolveTodoAttachments(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	load := state.attachments.Load(p.Context, p.Source.(*model.Todo).ID)
+	return func() (interface{}, error) {
+		files, err := load()
+		if files == nil {
+			files = []*model.File{}
+		}
+		return files, err
+	}, nil
+}
+
+func (h *graphQLHandler) resolveRegister(p graphql.ResolveParams) (interface{}, error) {
+	input := p.Args["input"].(map[string]interface{})
+
+	return responseData(h.AuthUsecase.Register(p.Context, request.RegisterRequest{
+		Name:     input["name"].(string),
+		Email:    input["email"].(string),
+		Password: input["password"].(string),
+		Confirm:  input["confirm"].(string),
+	}))
+}
+
+func (h *graphQLHandler) resolveLogin(p graphql.ResolveParams) (interface{}, error) {
+	data, err := responseData(h.AuthUsecase.Login(p.Context, request.LoginRequest{
+		Email:    p.Args["email"].(string),
+		Password: p.Args["password"].(string),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	login := data.(map[string]interface{})
+	return &authPayload{
+		Token: login["token"].(string),
+		User:  login["user"].(*model.User),
+	}, nil
+}
+
+func (h *graphQLHandler) resolveCreateTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	payload := request.CreateTodoRequest{
+		Name: input["name"].(string),
+	}
+	if id, ok := input["id"].(string); ok {
+		payload.ID = &id
+	}
+	if status, ok := input["status"].(model.TodoStatus); ok {
+		payload.Status = status
+	}
+	if externalID, ok := input["externalId"].(string); ok {
+		payload.ExternalID = &externalID
+	}
+
+	data, err := responseData(h.TodoUsecase.Create(p.Context, *state.claim, payload))
+	if err != nil {
+		return nil, err
+	}
+
+	// create responds with the todo by value
+	todo := data.(model.Todo)
+	return &todo, nil
+}
+
+func (h *graphQLHandler) resolveUpdateTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	payload := request.UpdateTodoRequest{
+		Name:   input["name"].(string),
+		Status: input["status"].(model.TodoStatus),
+	}
+	if version, ok := input["version"].(int); ok {
+		v := int64(version)
+		payload.Version = &v
+	}
+
+	return responseData(h.TodoUsecase.UpdateOne(p.Context, *state.claim, p.Args["id"].(string), payload))
+}
+
+func (h *graphQLHandler) resolveDeleteTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := request.DeleteTodoRequest{}
+	if version, ok := p.Args["version"].(int); ok {
+		v := int64(version)
+		payload.Version = &v
+	}
+
+	if _, err := responseData(h.TodoUsecase.DeleteOne(p.Context, *state.claim, p.Args["id"].(string), payload)); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func (h *graphQLHandler) resolveMoveTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := request.MoveTodoRequest{}
+	if beforeID, ok := p.Args["beforeId"].(string); ok {
+		payload.BeforeID = &beforeID
+	}
+	if afterID, ok := p.Args["afterId"].(string); ok {
+		payload.AfterID = &afterID
+	}
+
+	return responseData(h.TodoUsecase.Move(p.Context, *state.claim, p.Args["id"].(string), payload))
+}
+
+func (h *graphQLHandler) resolveRestoreTodo(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseData(h.TodoUsecase.RestoreOne(p.Context, *state.claim, p.Args["id"].(string)))
+}
+
+// resolveUpdateProfile sends the given fields as a merge patch, so fields
+// left out keep their value.
+func (h *graphQLHandler) resolveUpdateProfile(p graphql.ResolveParams) (interface{}, error) {
+	state, err := stateFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	patch := map[string]interface{}{}
+	if name, ok := input["name"]; ok {
+		patch["name"] = name
+	}
+	if profilePicture, ok := input["profilePicture"]; ok {
+		patch["profile_picture"] = profilePicture
+	}
+
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseData(h.SettingUsecase.PatchProfile(p.Context, *state.claim, request.PatchRequest{
+		ContentType: helpers.MergePatchContentType,
+		Patch:       body,
+	}))
+}
@@ -0,0 +1,338 @@
+package graphql_user
+
+import (
+	"golang-gorm/domain/model"
+
+	"github.com/graphql-go/graphql"
+)
+
+// paginatedFields are the list fields taking page and limit arguments, their
+// limit multiplies the complexity of the fields below them.
+var paginatedFields = map[string]bool{
+	"todos": true,
+}
+
+// todoPage is the source of the TodoPage type.
+type todoPage struct {
+	Items []*model.Todo
+	Page  int
+	Limit int
+	Total int64
+}
+
+// authPayload is the source of the AuthPayload type.
+type authPayload struct {
+	Token string
+	User  *model.User
+}
+
+func (h *graphQLHandler) newSchema() (graphql.Schema, error) {
+	todoStatusEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "TodoStatus",
+		Values: graphql.EnumValueConfigMap{
+			"Done":       &graphql.EnumValueConfig{Value: model.TodoStatusDone},
+			"NotStarted": &graphql.EnumValueConfig{Value: model.TodoStatusNotStarted},
+		},
+	})
+
+	fileVariantType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FileVariant",
+		Fields: graphql.Fields{
+			"name":     fileVariantField(graphql.NewNonNull(graphql.String), func(v *model.FileVariant) interface{} { return v.Name }),
+			"mimeType": fileVariantField(graphql.NewNonNull(graphql.String), func(v *model.FileVariant) interface{} { return v.MimeType }),
+			"size":     fileVariantField(graphql.NewNonNull(graphql.Int), func(v *model.FileVariant) interface{} { return v.Size }),
+			"width":    fileVariantField(graphql.NewNonNull(graphql.Int), func(v *model.FileVariant) interface{} { return v.Width }),
+			"height":   fileVariantField(graphql.NewNonNull(graphql.Int), func(v *model.FileVariant) interface{} { return v.Height }),
+			"url":      fileVariantField(graphql.NewNonNull(graphql.String), func(v *model.FileVariant) interface{} { return v.Url }),
+		},
+	})
+
+	fileType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "File",
+		Fields: graphql.Fields{
+			"id":        fileField(graphql.NewNonNull(graphql.ID), func(f *model.File) interface{} { return f.ID }),
+			"name":      fileField(graphql.NewNonNull(graphql.String), func(f *model.File) interface{} { return f.Name }),
+			"mimeType":  fileField(graphql.NewNonNull(graphql.String), func(f *model.File) interface{} { return f.MimeType }),
+			"size":      fileField(graphql.NewNonNull(graphql.Int), func(f *model.File) interface{} { return f.Size }),
+			"url":       fileField(graphql.NewNonNull(graphql.String), func(f *model.File) interface{} { return f.Url }),
+			"createdAt": fileField(graphql.NewNonNull(graphql.DateTime), func(f *model.File) interface{} { return f.CreatedAt }),
+			"variants": fileField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(fileVariantType))), func(f *model.File) interface{} {
+				variants := make([]*model.FileVariant, len(f.Variants))
+				for i := range f.Variants {
+					variants[i] = &f.Variants[i]
+				}
+				return variants
+			}),
+		},
+	})
+
+	todoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Todo",
+		Fields: graphql.Fields{
+			"id":       todoField(graphql.NewNonNull(graphql.ID), func(t *model.Todo) interface{} { return t.ID }),
+			"name":     todoField(graphql.NewNonNull(graphql.String), func(t *model.Todo) interface{} { return t.Name }),
+			"status":   todoField(graphql.NewNonNull(todoStatusEnum), func(t *model.Todo) interface{} { return t.Status }),
+			"position": todoField(graphql.NewNonNull(graphql.String), func(t *model.Todo) interface{} { return t.Position }),
+			"version":  todoField(graphql.NewNonNull(graphql.Int), func(t *model.Todo) interface{} { return t.Version }),
+			"externalId": todoField(graphql.String, func(t *model.Todo) interface{} {
+				if t.ExternalID == nil {
+					return nil
+				}
+				return *t.ExternalID
+			}),
+			"createdAt": todoField(graphql.NewNonNull(graphql.DateTime), func(t *model.Todo) interface{} { return t.CreatedAt }),
+			"updatedAt": todoField(graphql.NewNonNull(graphql.DateTime), func(t *model.Todo) interface{} { return t.UpdatedAt }),
+			"attachments": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(fileType))),
+				Resolve: h.resolveTodoAttachments,
+			},
+		},
+	})
+
+	todoPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoPage",
+		Fields: graphql.Fields{
+			"items": pageField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType))), func(p *todoPage) interface{} { return p.Items }),
+			"page":  pageField(graphql.NewNonNull(graphql.Int), func(p *todoPage) interface{} { return p.Page }),
+			"limit": pageField(graphql.NewNonNull(graphql.Int), func(p *todoPage) interface{} { return p.Limit }),
+			"total": pageField(graphql.NewNonNull(graphql.Int), func(p *todoPage) interface{} { return p.Total }),
+		},
+	})
+
+	todoFilterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TodoFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"status": &graphql.InputObjectFieldConfig{Type: todoStatusEnum},
+		},
+	})
+
+	todosArgs := graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{Type: todoFilterInput},
+		"page":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultListSize},
+	}
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":    userField(graphql.NewNonNull(graphql.ID), func(u *model.User) interface{} { return u.ID }),
+			"name":  userField(graphql.NewNonNull(graphql.String), func(u *model.User) interface{} { return u.Name }),
+			"email": userField(graphql.NewNonNull(graphql.String), func(u *model.User) interface{} { return u.Email }),
+			"plan":  userField(graphql.NewNonNull(graphql.String), func(u *model.User) interface{} { return u.Plan }),
+			"avatar": userField(fileType, func(u *model.User) interface{} {
+				if u.Avatar == nil {
+					return nil
+				}
+				return u.Avatar
+			}),
+			"createdAt": userField(graphql.NewNonNull(graphql.DateTime), func(u *model.User) interface{} { return u.CreatedAt }),
+			"updatedAt": userField(graphql.NewNonNull(graphql.DateTime), func(u *model.User) interface{} { return u.UpdatedAt }),
+			"todos": &graphql.Field{
+				Type:    graphql.NewNonNull(todoPageType),
+				Args:    todosArgs,
+				Resolve: h.resolveTodos,
+			},
+			"todoCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: todoStatusEnum},
+				},
+				Resolve: h.resolveTodoCount,
+			},
+		},
+	})
+
+	authPayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AuthPayload",
+		Fields: graphql.Fields{
+			"token": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*authPayload).Token, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*authPayload).User, nil
+				},
+			},
+		},
+	})
+
+	registerInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RegisterInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"email":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"password": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"confirm":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	createTodoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateTodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id":         &graphql.InputObjectFieldConfig{Type: graphql.ID},
+			"name":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"status":     &graphql.InputObjectFieldConfig{Type: todoStatusEnum},
+			"externalId": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	updateTodoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateTodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"status":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(todoStatusEnum)},
+			"version": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	updateProfileInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateProfileInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"profilePicture": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:    graphql.NewNonNull(userType),
+				Resolve: h.resolveMe,
+			},
+			"todos": &graphql.Field{
+				Type:    graphql.NewNonNull(todoPageType),
+				Args:    todosArgs,
+				Resolve: h.resolveTodos,
+			},
+			"todo": &graphql.Field{
+				Type: todoType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: h.resolveTodo,
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"register": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(registerInput)},
+				},
+				Resolve: h.resolveRegister,
+			},
+			"login": &graphql.Field{
+				Type: graphql.NewNonNull(authPayloadType),
+				Args: graphql.FieldConfigArgument{
+					"email":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.resolveLogin,
+			},
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createTodoInput)},
+				},
+				Resolve: h.resolveCreateTodo,
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateTodoInput)},
+				},
+				Resolve: h.resolveUpdateTodo,
+			},
+			"deleteTodo": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: h.resolveDeleteTodo,
+			},
+			"moveTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"beforeId": &graphql.ArgumentConfig{Type: graphql.ID},
+					"afterId":  &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Resolve: h.resolveMoveTodo,
+			},
+			"restoreTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: h.resolveRestoreTodo,
+			},
+			"updateProfile": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateProfileInput)},
+				},
+				Resolve: h.resolveUpdateProfile,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+}
+
+func todoField(t graphql.Output, get func(*model.Todo) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*model.Todo)), nil
+		},
+	}
+}
+
+func fileField(t graphql.Output, get func(*model.File) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*model.File)), nil
+		},
+	}
+}
+
+func fileVariantField(t graphql.Output, get func(*model.FileVariant) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*model.FileVariant)), nil
+		},
+	}
+}
+
+func userField(t graphql.Output, get func(*model.User) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*model.User)), nil
+		},
+	}
+}
+
+func pageField(t graphql.Output, get func(*todoPage) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*todoPage)), nil
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"golang-gorm/domain/model"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type claimKey struct{}
+
+type authInterceptor struct {
+	secretKeyUser string
+	publicMethods map[string]bool
+}
+
+// NewAuthInterceptor validates the user JWT sent in the authorization
+// metadata. The given methods, health checks and reflection work without a
+// token.
+func NewAuthInterceptor(publicMethods ...string) AuthInterceptor {
+	interceptor := &authInterceptor{
+		secretKeyUser: viper.GetString("JWT_SECRET_KEY_USER"),
+		publicMethods: map[string]bool{},
+	}
+	for _, method := range publicMethods {
+		interceptor.publicMethods[method] = true
+	}
+	return interceptor
+}
+
+type AuthInterceptor interface {
+	Unary() grpc.UnaryServerInterceptor
+	Stream() grpc.StreamServerInterceptor
+}
+
+func (i *authInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *authInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *authInterceptor) isPublic(method string) bool {
+	return i.publicMethods[method] ||
+		strings.HasPrefix(method, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func (i *authInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if i.isPublic(method) {
+		return ctx, nil
+	}
+
+	// get token from metadata
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized: Missing authorization metadata")
+	}
+
+	// check token format
+	splitToken := strings.Split(values[0], "Bearer ")
+	if len(splitToken) != 2 {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized: Invalid token format")
+	}
+
+	// validate token
+	token, err := jwt.ParseWithClaims(splitToken[1], &model.JWTClaimUser{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(i.secretKeyUser), nil
+	})
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized: Invalid token signature")
+	}
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized: Token expired")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	claims, ok := token.Claims.(*model.JWTClaimUser)
+	if !ok || !token.Valid {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized: Invalid token claims")
+	}
+
+	return context.WithValue(ctx, claimKey{}, *claims), nil
+}
+
+// ClaimFrom returns the claims the interceptor stored for an authenticated
+// call.
+func ClaimFrom(ctx context.Context) (model.JWTClaimUser, bool) {
+	claim, ok := ctx.Value(claimKey{}).(model.JWTClaimUser)
+	return claim, ok
+}
+
+// authServerStream swaps the context of a stream for the authenticated one.
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
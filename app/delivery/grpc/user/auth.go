@@ -0,0 +1,77 @@
+package grpc_user
+
+import (
+	"context"
+	"net/http"
+
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	userv1 "golang-gorm/proto/user/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type authServer struct {
+	userv1.UnimplementedAuthServiceServer
+	AuthUsecase usecase_user.AuthUsecase
+}
+
+func NewAuthServer(grpcServer *grpc.Server, authUsecase usecase_user.AuthUsecase) {
+	userv1.RegisterAuthServiceServer(grpcServer, &authServer{
+		AuthUsecase: authUsecase,
+	})
+}
+
+func (s *authServer) Register(ctx context.Context, req *userv1.RegisterRequest) (*userv1.User, error) {
+	response := s.AuthUsecase.Register(ctx, request.RegisterRequest{
+		Name:     req.GetName(),
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+		Confirm:  req.GetConfirm(),
+	})
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toUserMessage(response.Data.(*model.User)), nil
+}
+
+func (s *authServer) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	response := s.AuthUsecase.Login(ctx, request.LoginRequest{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	})
+
+	// wrong credentials are a bad request over http, here they are what
+	// UNAUTHENTICATED is for
+	if response.Status == http.StatusBadRequest && len(response.Validation) == 0 {
+		return nil, status.Error(codes.Unauthenticated, response.Message)
+	}
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	login := response.Data.(map[string]interface{})
+	return &userv1.LoginResponse{
+		Token: login["token"].(string),
+		User:  toUserMessage(login["user"].(*model.User)),
+	}, nil
+}
+
+func (s *authServer) GetProfile(ctx context.Context, _ *emptypb.Empty) (*userv1.User, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.AuthUsecase.GetProfile(ctx, claim)
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toUserMessage(response.Data.(*model.User)), nil
+}
@@ -0,0 +1,70 @@
+package grpc_user
+
+import (
+	"golang-gorm/domain/model"
+	userv1 "golang-gorm/proto/user/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toTodoStatus(todoStatus userv1.TodoStatus) model.TodoStatus {
+	switch todoStatus {
+	case userv1.TodoStatus_TODO_STATUS_NOT_STARTED:
+		return model.TodoStatusNotStarted
+	case userv1.TodoStatus_TODO_STATUS_DONE:
+		return model.TodoStatusDone
+	default:
+		// left empty so the usecase applies its default or rejects it
+		return ""
+	}
+}
+
+func fromTodoStatus(todoStatus model.TodoStatus) userv1.TodoStatus {
+	switch todoStatus {
+	case model.TodoStatusNotStarted:
+		return userv1.TodoStatus_TODO_STATUS_NOT_STARTED
+	case model.TodoStatusDone:
+		return userv1.TodoStatus_TODO_STATUS_DONE
+	default:
+		return userv1.TodoStatus_TODO_STATUS_UNSPECIFIED
+	}
+}
+
+func toTodoMessage(todo *model.Todo) *userv1.Todo {
+	return &userv1.Todo{
+		Id:         todo.ID,
+		ExternalId: todo.ExternalID,
+		Name:       todo.Name,
+		Status:     fromTodoStatus(todo.Status),
+		Position:   todo.Position,
+		Version:    todo.Version,
+		CreatedAt:  timestamppb.New(todo.CreatedAt),
+		UpdatedAt:  timestamppb.New(todo.UpdatedAt),
+	}
+}
+
+func toUserMessage(user *model.User) *userv1.User {
+	message := &userv1.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+	if user.Avatar != nil {
+		message.Avatar = toFileMessage(user.Avatar)
+	}
+	return message
+}
+
+func toFileMessage(file *model.File) *userv1.File {
+	return &userv1.File{
+		Id:        file.ID,
+		Name:      file.Name,
+		MimeType:  file.MimeType,
+		Size:      file.Size,
+		Url:       file.Url,
+		CreatedAt: timestamppb.New(file.CreatedAt),
+		UpdatedAt: timestamppb.New(file.UpdatedAt),
+	}
+}
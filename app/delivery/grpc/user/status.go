@@ -0,0 +1,99 @@
+package grpc_user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang-gorm/app/delivery/grpc/interceptor"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// claimFrom returns the claims of the authenticated caller.
+func claimFrom(ctx context.Context) (model.JWTClaimUser, error) {
+	claim, ok := interceptor.ClaimFrom(ctx)
+	if !ok {
+		return claim, status.Error(codes.Unauthenticated, "Unauthorized: Missing authorization metadata")
+	}
+	return claim, nil
+}
+
+// responseError turns a failed usecase response into a gRPC status, nil when
+// the response succeeded.
+func responseError(ctx context.Context, responseStatus int, message string, responseErr error, validation []helpers.ValidationError) error {
+	if responseStatus < http.StatusBadRequest {
+		return nil
+	}
+
+	// the usecase gave up because the caller went away or ran out of time
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	st := status.New(statusCode(responseStatus, responseErr), message)
+	if len(validation) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range validation {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.FailedField,
+				Description: fmt.Sprintf("failed on the '%s' tag", v.Tag),
+			})
+		}
+		if detailed, err := st.WithDetails(badRequest); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func fromResponse(ctx context.Context, response helpers.Response) error {
+	return responseError(ctx, response.Status, response.Message, response.Err, response.Validation)
+}
+
+func fromPaginatedResponse(ctx context.Context, response helpers.PaginatedResponse) error {
+	return responseError(ctx, response.Status, response.Message, response.Err, nil)
+}
+
+// statusCode maps the http status of a usecase response to a gRPC code. The
+// usecases report missing and duplicate records as bad requests, the error
+// of the response tells them apart.
+func statusCode(responseStatus int, responseErr error) codes.Code {
+	switch responseStatus {
+	case http.StatusBadRequest:
+		if errors.Is(responseErr, helpers.ErrNotFound) {
+			return codes.NotFound
+		}
+		if errors.Is(responseErr, helpers.ErrAlreadyExists) {
+			return codes.AlreadyExists
+		}
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.Aborted
+	case http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
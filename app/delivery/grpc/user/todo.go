@@ -0,0 +1,173 @@
+package grpc_user
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	userv1 "golang-gorm/proto/user/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// maxPageSize caps the limit of ListTodos.
+const maxPageSize = 100
+
+type todoServer struct {
+	userv1.UnimplementedTodoServiceServer
+	TodoUsecase usecase_user.TodoUsecase
+}
+
+func NewTodoServer(grpcServer *grpc.Server, todoUsecase usecase_user.TodoUsecase) {
+	userv1.RegisterTodoServiceServer(grpcServer, &todoServer{
+		TodoUsecase: todoUsecase,
+	})
+}
+
+func (s *todoServer) ListTodos(ctx context.Context, req *userv1.ListTodosRequest) (*userv1.ListTodosResponse, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetLimit() > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must not exceed %d", maxPageSize)
+	}
+
+	query := url.Values{}
+	if req.GetPage() > 0 {
+		query.Set("page", strconv.Itoa(int(req.GetPage())))
+	}
+	if req.GetLimit() > 0 {
+		query.Set("limit", strconv.Itoa(int(req.GetLimit())))
+	}
+	if todoStatus := toTodoStatus(req.GetStatus()); todoStatus != "" {
+		query.Set("status", string(todoStatus))
+	}
+
+	response := s.TodoUsecase.GetAll(ctx, claim, query)
+	if err := fromPaginatedResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	// an empty page carries an untyped empty slice
+	todos, _ := response.Data.([]*model.Todo)
+	meta := response.Meta.(map[string]interface{})
+	result := &userv1.ListTodosResponse{
+		Todos: make([]*userv1.Todo, 0, len(todos)),
+		Page:  int32(meta["page"].(int)),
+		Limit: int32(meta["limit"].(int)),
+		Total: meta["total"].(int64),
+	}
+	for _, todo := range todos {
+		result.Todos = append(result.Todos, toTodoMessage(todo))
+	}
+
+	return result, nil
+}
+
+func (s *todoServer) GetTodo(ctx context.Context, req *userv1.GetTodoRequest) (*userv1.Todo, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.GetOne(ctx, claim, req.GetId())
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toTodoMessage(response.Data.(*model.Todo)), nil
+}
+
+func (s *todoServer) CreateTodo(ctx context.Context, req *userv1.CreateTodoRequest) (*userv1.Todo, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.Create(ctx, claim, request.CreateTodoRequest{
+		ID:         req.Id,
+		Name:       req.GetName(),
+		Status:     toTodoStatus(req.GetStatus()),
+		ExternalID: req.ExternalId,
+	})
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	// create responds with the todo by value
+	todo := response.Data.(model.Todo)
+	return toTodoMessage(&todo), nil
+}
+
+func (s *todoServer) UpdateTodo(ctx context.Context, req *userv1.UpdateTodoRequest) (*userv1.Todo, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.UpdateOne(ctx, claim, req.GetId(), request.UpdateTodoRequest{
+		Name:    req.GetName(),
+		Status:  toTodoStatus(req.GetStatus()),
+		Version: req.Version,
+	})
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toTodoMessage(response.Data.(*model.Todo)), nil
+}
+
+func (s *todoServer) DeleteTodo(ctx context.Context, req *userv1.DeleteTodoRequest) (*emptypb.Empty, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.DeleteOne(ctx, claim, req.GetId(), request.DeleteTodoRequest{
+		Version: req.Version,
+	})
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *todoServer) MoveTodo(ctx context.Context, req *userv1.MoveTodoRequest) (*userv1.Todo, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.Move(ctx, claim, req.GetId(), request.MoveTodoRequest{
+		BeforeID: req.BeforeId,
+		AfterID:  req.AfterId,
+	})
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toTodoMessage(response.Data.(*model.Todo)), nil
+}
+
+func (s *todoServer) RestoreTodo(ctx context.Context, req *userv1.RestoreTodoRequest) (*userv1.Todo, error) {
+	claim, err := claimFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.TodoUsecase.RestoreOne(ctx, claim, req.GetId())
+	if err := fromResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return toTodoMessage(response.Data.(*model.Todo)), nil
+}
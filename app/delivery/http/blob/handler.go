@@ -0,0 +1,143 @@
+package http_blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golang-gorm/app/delivery/http/openapi"
+	blobrepo "golang-gorm/app/repository/blob"
+	"golang-gorm/helpers"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RoutePrefix is where presigned urls of the local and memory blob stores
+// point to.
+const RoutePrefix = "/blobs"
+
+type blobHandler struct {
+	BlobStore blobrepo.BlobStore
+	Signer    *blobrepo.URLSigner
+	Route     *gin.RouterGroup
+}
+
+// NewBlobHandler serves the presigned requests of blob stores without an
+// http endpoint of their own. The signature replaces authentication, like it
+// does for S3.
+func NewBlobHandler(ginEngine *gin.Engine, blobStore blobrepo.BlobStore, signer *blobrepo.URLSigner) {
+	handler := &blobHandler{
+		BlobStore: blobStore,
+		Signer:    signer,
+		Route:     ginEngine.Group(""),
+	}
+
+	handler.handleBlobRoute(RoutePrefix)
+}
+
+func (h *blobHandler) handleBlobRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("/*key", h.Download)
+	api.HEAD("/*key", h.Download)
+	api.PUT("/*key", h.Upload)
+}
+
+func (r *blobHandler) Download(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	objectName := strings.TrimPrefix(c.Param("key"), "/")
+	if _, err := r.Signer.Verify(c.Request.Method, objectName, c.Request.URL.Query()); err != nil {
+		r.abort(c, http.StatusForbidden, err)
+		return
+	}
+
+	body, info, err := r.BlobStore.OpenFile(ctx, objectName)
+	if err != nil {
+		if errors.Is(err, blobrepo.ErrObjectNotFound) {
+			r.abort(c, http.StatusNotFound, err)
+			return
+		}
+		r.abort(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, nil)
+}
+
+// Upload takes exactly the signed size, content type and checksum, like a
+// presigned PutObject.
+func (r *blobHandler) Upload(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	objectName := strings.TrimPrefix(c.Param("key"), "/")
+	signed, err := r.Signer.Verify(c.Request.Method, objectName, c.Request.URL.Query())
+	if err != nil {
+		r.abort(c, http.StatusForbidden, err)
+		return
+	}
+	if c.GetHeader("Content-Type") != signed.ContentType {
+		r.abort(c, http.StatusForbidden, errors.New("content type does not match the signature"))
+		return
+	}
+	if c.Request.ContentLength != signed.Size {
+		r.abort(c, http.StatusForbidden, errors.New("content length does not match the signature"))
+		return
+	}
+
+	body := &checksumReader{
+		reader:   http.MaxBytesReader(c.Writer, c.Request.Body, signed.Size),
+		hash:     sha256.New(),
+		expected: signed.Sha256,
+	}
+	err = r.BlobStore.UploadFile(ctx, objectName, body, signed.ContentType)
+	if errors.Is(err, errChecksumMismatch) {
+		r.abort(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		logrus.Error(err)
+		r.abort(c, http.StatusBadRequest, errors.New("upload failed"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+var errChecksumMismatch = errors.New("content does not match the signed checksum")
+
+// checksumReader fails at the end of the body when its hex SHA-256 isn't
+// the expected one, so the store drops the upload.
+type checksumReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.expected {
+		return n, errChecksumMismatch
+	}
+	return n, err
+}
+
+func (r *blobHandler) abort(c *gin.Context, status int, err error) {
+	c.AbortWithStatusJSON(status, helpers.Response{
+		Data:    nil,
+		Message: err.Error(),
+		Status:  status,
+	})
+}
+
+// DescribeRoutes leaves the blob routes out of the api document, clients
+// only reach them through presigned urls.
+func DescribeRoutes(spec *openapi.Spec) {
+	spec.Ignore(RoutePrefix)
+}
@@ -0,0 +1,88 @@
+package http_blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	blobrepo "golang-gorm/app/repository/blob"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadChecksum(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := blobrepo.NewURLSigner("http://localhost"+RoutePrefix, []byte("secret"), time.Minute)
+	store := blobrepo.NewMemoryBlobStore(signer)
+	engine := gin.New()
+	NewBlobHandler(engine, store, signer)
+
+	content := "hello"
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+
+	put := func(t *testing.T, objectName string, body string, tamper func(query url.Values)) int {
+		t.Helper()
+
+		upload, err := store.PresignUpload(context.Background(), objectName, "text/plain", int64(len(body)), checksum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := url.Parse(upload.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tamper != nil {
+			query := target.Query()
+			tamper(query)
+			target.RawQuery = query.Encode()
+		}
+
+		req := httptest.NewRequest(upload.Method, target.RequestURI(), strings.NewReader(body))
+		for name, values := range upload.Header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("matching content", func(t *testing.T) {
+		if status := put(t, "match.txt", content, nil); status != http.StatusOK {
+			t.Fatalf("got %d, want 200", status)
+		}
+		info, err := store.StatFile(context.Background(), "match.txt")
+		if err != nil || info == nil {
+			t.Fatalf("stat: %v %v", info, err)
+		}
+		if info.Sha256 != checksum {
+			t.Errorf("stored checksum is %q, want %q", info.Sha256, checksum)
+		}
+	})
+
+	t.Run("other content", func(t *testing.T) {
+		if status := put(t, "other.txt", "HELLO", nil); status != http.StatusBadRequest {
+			t.Fatalf("got %d, want 400", status)
+		}
+		if info, _ := store.StatFile(context.Background(), "other.txt"); info != nil {
+			t.Error("object was stored despite the checksum mismatch")
+		}
+	})
+
+	t.Run("tampered checksum", func(t *testing.T) {
+		other := sha256.Sum256([]byte("HELLO"))
+		status := put(t, "tampered.txt", "HELLO", func(query url.Values) {
+			query.Set("sha256", hex.EncodeToString(other[:]))
+		})
+		if status != http.StatusForbidden {
+			t.Fatalf("got %d, want 403", status)
+		}
+	})
+}
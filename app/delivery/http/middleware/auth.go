@@ -24,6 +24,8 @@ func NewAuthMiddleware() AuthMiddleware {
 
 type AuthMiddleware interface {
 	AuthUser() gin.HandlerFunc
+	AuthUserOrQueryToken() gin.HandlerFunc
+	AuthUserOptional() gin.HandlerFunc
 }
 
 func (m *authMiddleware) AuthUser() gin.HandlerFunc {
@@ -95,3 +97,33 @@ func (m *authMiddleware) AuthUser() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AuthUserOrQueryToken also accepts the token from the access_token query
+// parameter, for browser EventSource and WebSocket clients that can't set
+// headers. Only use it on routes that need it, query strings end up in logs
+// and browser history.
+func (m *authMiddleware) AuthUserOrQueryToken() gin.HandlerFunc {
+	authUser := m.AuthUser()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		authUser(c)
+	}
+}
+
+// AuthUserOptional lets requests without an Authorization header through
+// without user_data, for routes that serve both guests and users. A token
+// that is sent still has to be valid.
+func (m *authMiddleware) AuthUserOptional() gin.HandlerFunc {
+	authUser := m.AuthUser()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authUser(c)
+	}
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyMemoryBodySize is how much of a request body is kept in memory
+// while it is fingerprinted, the rest is spooled to a temporary file.
+const idempotencyMemoryBodySize = 1024 * 1024
+
+type idempotencyMiddleware struct {
+	idempotencyKeyRepository postgresrepo.IdempotencyKeyRepository
+	ttl                      time.Duration
+}
+
+func NewIdempotencyMiddleware(idempotencyKeyRepository postgresrepo.IdempotencyKeyRepository, ttl time.Duration) IdempotencyMiddleware {
+	return &idempotencyMiddleware{
+		idempotencyKeyRepository: idempotencyKeyRepository,
+		ttl:                      ttl,
+	}
+}
+
+type IdempotencyMiddleware interface {
+	Idempotent(maxBodySize int64) gin.HandlerFunc
+}
+
+// idempotencyWriter keeps a copy of the response body so it can be stored.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotent replays the stored response when a request is retried with the
+// same Idempotency-Key, and rejects the key when it's reused for a different
+// request. It must run after AuthUser since keys are scoped per user.
+// Requests without the header pass through untouched, bodies larger than
+// maxBodySize are refused.
+func (m *idempotencyMiddleware) Idempotent(maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, helpers.Response{
+				Status:  http.StatusBadRequest,
+				Message: "Idempotency-Key is too long",
+			})
+			return
+		}
+
+		claim, ok := c.Get("user_data")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// hash the body for the fingerprint and put it back for the handler
+		fingerprint := newRequestFingerprint(c.Request)
+		body, err := spoolBody(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize), fingerprint)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, helpers.Response{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: fmt.Sprintf("request body must be less than %dMB", maxBodySize/1024/1024),
+				})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, helpers.Response{
+				Status:  http.StatusBadRequest,
+				Message: "invalid request body",
+			})
+			return
+		}
+		defer body.Close()
+		c.Request.Body = body
+
+		record := &model.IdempotencyKey{
+			UserID:      claim.(model.JWTClaimUser).UserID,
+			Key:         key,
+			Fingerprint: hex.EncodeToString(fingerprint.Sum(nil)),
+			ExpiresAt:   time.Now().Add(m.ttl),
+		}
+
+		// reserve key, or get the request that holds it
+		existing, err := m.idempotencyKeyRepository.Reserve(c.Request.Context(), record)
+		if err != nil {
+			logrus.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, helpers.Response{
+				Status:  http.StatusInternalServerError,
+				Message: "failed to check Idempotency-Key",
+			})
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, helpers.Response{
+					Status:  http.StatusConflict,
+					Message: "Idempotency-Key was already used for a different request",
+				})
+			case existing.IsPending():
+				c.AbortWithStatusJSON(http.StatusConflict, helpers.Response{
+					Status:  http.StatusConflict,
+					Message: "a request with this Idempotency-Key is still in progress",
+				})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.Status, existing.ContentType, existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// the response has been sent by now, don't let a cancelled request
+		// context leave the key pending
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := m.idempotencyKeyRepository.Release(ctx, record); err != nil {
+				logrus.Error(err)
+			}
+		}()
+
+		c.Next()
+
+		// server errors are not stored so the client can retry
+		if writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		record.Status = writer.Status()
+		record.ContentType = writer.Header().Get("Content-Type")
+		record.Body = writer.body.Bytes()
+		if err := m.idempotencyKeyRepository.Complete(ctx, record); err != nil {
+			logrus.Error(err)
+			return
+		}
+		completed = true
+	}
+}
+
+// newRequestFingerprint starts the hash identifying a request by method, uri
+// and body, the body is written to it afterwards.
+func newRequestFingerprint(r *http.Request) hash.Hash {
+	fingerprint := sha256.New()
+	io.WriteString(fingerprint, r.Method)
+	io.WriteString(fingerprint, " ")
+	io.WriteString(fingerprint, r.URL.RequestURI())
+	io.WriteString(fingerprint, "\n")
+	return fingerprint
+}
+
+// spoolBody reads body to the end, writing it to hash as well, and returns a
+// copy to read it again. Small bodies stay in memory, larger ones are kept in
+// a temporary file removed on Close.
+func spoolBody(body io.Reader, hash io.Writer) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	_, err := io.CopyN(io.MultiWriter(&buf, hash), body, idempotencyMemoryBodySize+1)
+	if err == io.EOF {
+		return io.NopCloser(&buf), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "idempotency-body-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledBody{File: file}
+	if _, err := buf.WriteTo(file); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+
+	return spooled, nil
+}
+
+// spooledBody is a request body kept in a temporary file.
+type spooledBody struct {
+	*os.File
+}
+
+func (b *spooledBody) Close() error {
+	err := b.File.Close()
+	if removeErr := os.Remove(b.File.Name()); removeErr != nil && err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"encoding/json"
 	"io"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,7 +25,7 @@ func Logger(writer io.Writer) gin.HandlerFunc {
 				Time:     param.TimeStamp.Format(time.RFC3339),
 				Status:   param.StatusCode,
 				Method:   param.Method,
-				Path:     param.Path,
+				Path:     redactPath(param.Path),
 				Latency:  param.Latency.String(),
 				ClientIP: param.ClientIP,
 				Error:    param.ErrorMessage,
@@ -33,3 +35,19 @@ func Logger(writer io.Writer) gin.HandlerFunc {
 		Output: writer,
 	})
 }
+
+// redactPath hides tokens passed in the query string by stream clients.
+func redactPath(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil || !query.Has("access_token") {
+		return path
+	}
+	query.Set("access_token", "REDACTED")
+
+	return path[:i+1] + query.Encode()
+}
@@ -0,0 +1,70 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+type openAPIHandler struct {
+	Body  []byte
+	Route *gin.RouterGroup
+}
+
+// NewOpenAPIHandler documents every route registered so far, so it has to be
+// called after the other handlers.
+func NewOpenAPIHandler(ginEngine *gin.Engine, spec *Spec) {
+	document, problems := spec.Build(ginEngine.Routes())
+	for _, problem := range problems {
+		logrus.Error("openapi: ", problem)
+	}
+	if len(problems) > 0 && viper.GetBool("OPENAPI_STRICT") {
+		logrus.Fatal("openapi: every route has to be documented")
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		logrus.Fatal("invalid openapi document: ", err)
+	}
+
+	handler := &openAPIHandler{
+		Body:  body,
+		Route: ginEngine.Group(""),
+	}
+
+	handler.handleOpenAPIRoute("")
+}
+
+func (h *openAPIHandler) handleOpenAPIRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("/openapi.json", h.Document)
+	api.GET("/docs", h.SwaggerUI)
+}
+
+func (r *openAPIHandler) Document(c *gin.Context) {
+	c.Data(http.StatusOK, gin.MIMEJSON, r.Body)
+}
+
+func (r *openAPIHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// schemaGenerator turns go types into schemas the way encoding/json and gin
+// binding see them. Named structs and enums become components.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	enums   map[reflect.Type][]interface{}
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+		enums:   map[reflect.Type][]interface{}{},
+	}
+}
+
+// schemaOf returns the json schema of t, a reference for named types.
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	case fileHeaderType:
+		return &Schema{Type: "string", ContentMediaType: "application/octet-stream"}
+	}
+
+	if values, ok := g.enums[t]; ok {
+		return g.component(t, func() *Schema {
+			schema := primitiveSchema(t.Kind())
+			schema.Enum = values
+			return schema
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaOf(t.Elem()))
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, "json")
+		}
+		return g.component(t, func() *Schema {
+			return g.structSchema(t, "json")
+		})
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	default:
+		return primitiveSchema(t.Kind())
+	}
+}
+
+// component registers the schema of a named type once and refers to it.
+func (g *schemaGenerator) component(t reflect.Type, build func() *Schema) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			name = path.Base(t.PkgPath()) + t.Name()
+		}
+		g.names[t] = name
+
+		// placeholder first so recursive types refer to themselves
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema describes the fields of t under the given tag, json for
+// bodies and form for multipart forms.
+func (g *schemaGenerator) structSchema(t reflect.Type, tagKey string) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(schema, t, tagKey)
+	return schema
+}
+
+func (g *schemaGenerator) addFields(schema *Schema, t reflect.Type, tagKey string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get(tagKey), ",")
+
+		// embedded structs are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded, tagKey)
+				continue
+			}
+		}
+
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema, required := g.fieldSchema(field, tagKey)
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// fieldSchema applies the validate and form default tags of a field to its
+// schema.
+func (g *schemaGenerator) fieldSchema(field reflect.StructField, tagKey string) (*Schema, bool) {
+	// forms and query strings have no null, a missing value is the zero value
+	t := field.Type
+	for tagKey == "form" && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := g.schemaOf(t)
+
+	if tagKey == "form" {
+		for _, option := range strings.Split(field.Tag.Get("form"), ",")[1:] {
+			if value, ok := strings.CutPrefix(option, "default="); ok {
+				schema.Default = value
+			}
+		}
+	}
+
+	rules := field.Tag.Get("validate")
+	if rules == "" {
+		return schema, false
+	}
+
+	// rules after dive apply to the items
+	fieldRules, itemRules, dive := strings.Cut(rules, ",dive")
+	required := false
+	for _, rule := range strings.Split(fieldRules, ",") {
+		if rule == "required" {
+			required = true
+			continue
+		}
+		applyRule(schema, field.Type, rule)
+	}
+	if dive && schema.Items != nil {
+		for _, rule := range strings.Split(strings.TrimPrefix(itemRules, ","), ",") {
+			applyRule(schema.Items, field.Type.Elem(), rule)
+		}
+	}
+
+	return schema, required
+}
+
+// applyRule maps a single validator rule to schema constraints. Rules
+// without a schema counterpart, like eqfield, are left to the description of
+// the operation.
+func applyRule(schema *Schema, t reflect.Type, rule string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// constraints can't be added to references of nullable values
+	if schema.OneOf != nil {
+		schema = schema.OneOf[0]
+	}
+	if schema.Ref != "" {
+		return
+	}
+
+	name, param, _ := strings.Cut(rule, "=")
+	switch name {
+	case "email":
+		schema.Format = "email"
+	case "uuid":
+		schema.Format = "uuid"
+	case "url", "http_url":
+		schema.Format = "uri"
+	case "oneof":
+		for _, value := range strings.Fields(param) {
+			schema.Enum = append(schema.Enum, value)
+		}
+	case "min", "max", "len":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		switch t.Kind() {
+		case reflect.String:
+			if name != "max" {
+				schema.MinLength = &n
+			}
+			if name != "min" {
+				schema.MaxLength = &n
+			}
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if name != "max" {
+				schema.MinItems = &n
+			}
+			if name != "min" {
+				schema.MaxItems = &n
+			}
+		default:
+			f := float64(n)
+			if name != "max" {
+				schema.Minimum = &f
+			}
+			if name != "min" {
+				schema.Maximum = &f
+			}
+		}
+	}
+}
+
+// parameters describes a struct bound with ShouldBindQuery as query
+// parameters.
+func (g *schemaGenerator) parameters(t reflect.Type, in string) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	parameters := []Parameter{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+
+		// gin fills in the default, so the client may leave it out
+		schema, required := g.fieldSchema(field, "form")
+		parameters = append(parameters, Parameter{
+			Name:     name,
+			In:       in,
+			Required: required && schema.Default == nil,
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+func primitiveSchema(kind reflect.Kind) *Schema {
+	switch kind {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	default:
+		return &Schema{}
+	}
+}
+
+// nullable allows null next to the values of schema.
+func nullable(schema *Schema) *Schema {
+	switch t := schema.Type.(type) {
+	case string:
+		schema.Type = []string{t, "null"}
+		return schema
+	case nil:
+		if schema.Ref == "" {
+			// any value already includes null
+			return schema
+		}
+	}
+	return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+}
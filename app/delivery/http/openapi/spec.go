@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang-gorm/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents a single route. Request and response types are
+// described by reflecting on the values given here, so they stay in step
+// with the structs the handlers bind and the usecases return.
+type Operation struct {
+	Summary     string
+	Description string
+	Auth        bool
+
+	// Parameters are query and header parameters not covered by Query.
+	Parameters []Parameter
+
+	// Query is a struct bound with ShouldBindQuery, Body a struct bound
+	// with ShouldBindJSON. Content lists other accepted content types.
+	Query   interface{}
+	Body    interface{}
+	Content map[string]interface{}
+
+	// Response is the data of a helpers.Response, or the items of a
+	// helpers.PaginatedResponse when Paginated is set. Produces replaces
+	// the json envelope for routes that stream or send files.
+	Response  interface{}
+	Paginated bool
+	Produces  []string
+
+	// Status is the success status, 200 when empty. Errors lists the
+	// statuses besides the 400, 401 and 500 every route may answer with.
+	Status int
+	Errors []int
+}
+
+// PaginationMeta is the meta of a helpers.PaginatedResponse.
+type PaginationMeta struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+}
+
+// JSONPatchOperation is a single RFC 6902 operation.
+type JSONPatchOperation struct {
+	Op    string      `json:"op" validate:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path" validate:"required"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PageParameters are the page and limit query parameters of paginated
+// routes.
+var PageParameters = []Parameter{
+	QueryParameter("page", "page number, starting at 1", &Schema{Type: "integer", Format: "int32", Default: 1}),
+	QueryParameter("limit", "items per page", &Schema{Type: "integer", Format: "int32", Default: 10}),
+}
+
+func QueryParameter(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func HeaderParameter(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+type tagPrefix struct {
+	prefix string
+	name   string
+}
+
+// Spec collects the operations of the api and builds the document from the
+// routes gin actually serves.
+type Spec struct {
+	info       Info
+	operations map[string]Operation
+	tags       []tagPrefix
+	ignored    []string
+	generator  *schemaGenerator
+}
+
+func NewSpec(info Info) *Spec {
+	return &Spec{
+		info:       info,
+		operations: map[string]Operation{},
+		generator:  newSchemaGenerator(),
+	}
+}
+
+// Describe documents the route with the given method and gin path.
+func (s *Spec) Describe(method, path string, operation Operation) {
+	s.operations[method+" "+path] = operation
+}
+
+// Ignore leaves routes under prefix out of the document, for routes that
+// aren't json apis.
+func (s *Spec) Ignore(prefix string) {
+	s.ignored = append(s.ignored, prefix)
+}
+
+// Tag groups the routes under prefix, the longest matching prefix wins.
+func (s *Spec) Tag(prefix, name string) {
+	s.tags = append(s.tags, tagPrefix{prefix: prefix, name: name})
+}
+
+// Enum documents the allowed values of a named type wherever it is used.
+func (s *Spec) Enum(value interface{}, values ...interface{}) {
+	s.generator.enums[reflect.TypeOf(value)] = values
+}
+
+// Build documents routes and reports the routes without documentation and
+// the documentation without a route.
+func (s *Spec) Build(routes gin.RoutesInfo) (*Document, []string) {
+	document := &Document{
+		OpenAPI: "3.1.0",
+		Info:    s.info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: s.generator.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	s.generator.schemaOf(reflect.TypeOf(helpers.Response{}))
+	s.generator.schemaOf(reflect.TypeOf(helpers.PaginatedResponse{}))
+
+	problems := []string{}
+	documented := map[string]bool{}
+	tags := map[string]bool{}
+	for _, route := range routes {
+		if s.isIgnored(route.Path) {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		operation, ok := s.operations[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not documented", key))
+			continue
+		}
+		documented[key] = true
+
+		path, pathParameters := openAPIPath(route.Path)
+		item, ok := document.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			document.Paths[path] = item
+		}
+
+		object := s.operationObject(operation, pathParameters)
+		object.OperationID = operationID(route.Handler)
+		if tag := s.tagOf(route.Path); tag != "" {
+			object.Tags = []string{tag}
+			tags[tag] = true
+		}
+		(*item)[strings.ToLower(route.Method)] = object
+	}
+
+	for key := range s.operations {
+		if !documented[key] {
+			problems = append(problems, fmt.Sprintf("%s is documented but not routed", key))
+		}
+	}
+	sort.Strings(problems)
+
+	for _, tag := range s.tags {
+		if tags[tag.name] {
+			document.Tags = append(document.Tags, Tag{Name: tag.name})
+			delete(tags, tag.name)
+		}
+	}
+
+	return document, problems
+}
+
+func (s *Spec) operationObject(operation Operation, pathParameters []Parameter) *OperationObject {
+	object := &OperationObject{
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		Parameters:  append([]Parameter{}, pathParameters...),
+		Responses:   map[string]*Response{},
+	}
+
+	// request
+	object.Parameters = append(object.Parameters, operation.Parameters...)
+	if operation.Query != nil {
+		object.Parameters = append(object.Parameters, s.generator.parameters(reflect.TypeOf(operation.Query), "query")...)
+	}
+	if operation.Body != nil || len(operation.Content) > 0 {
+		object.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+		if operation.Body != nil {
+			object.RequestBody.Content[gin.MIMEJSON] = &MediaType{Schema: s.bodySchema(operation.Body)}
+		}
+		for contentType, body := range operation.Content {
+			schema := &Schema{Type: "string", ContentMediaType: contentType}
+			if contentType == gin.MIMEMultipartPOSTForm {
+				schema = s.generator.structSchema(derefType(reflect.TypeOf(body)), "form")
+			} else if body != nil {
+				schema = s.bodySchema(body)
+			}
+			object.RequestBody.Content[contentType] = &MediaType{Schema: schema}
+		}
+	}
+	if operation.Auth {
+		object.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	// responses
+	status := operation.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case status >= 300 && status < 400, status == http.StatusSwitchingProtocols:
+		// redirects and upgrades have no body
+	case len(operation.Produces) > 0:
+		success.Content = map[string]*MediaType{}
+		for _, contentType := range operation.Produces {
+			schema := &Schema{}
+			if strings.HasPrefix(contentType, "text/") {
+				schema.Type = "string"
+			}
+			success.Content[contentType] = &MediaType{Schema: schema}
+		}
+	default:
+		success.Content = map[string]*MediaType{gin.MIMEJSON: {Schema: s.envelope(operation)}}
+	}
+	object.Responses[strconv.Itoa(status)] = success
+
+	errors := append([]int{http.StatusBadRequest, http.StatusInternalServerError}, operation.Errors...)
+	if operation.Auth {
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	for _, errorStatus := range errors {
+		object.Responses[strconv.Itoa(errorStatus)] = &Response{
+			Description: http.StatusText(errorStatus),
+			Content: map[string]*MediaType{
+				gin.MIMEJSON: {Schema: &Schema{Ref: "#/components/schemas/Response"}},
+			},
+		}
+	}
+
+	return object
+}
+
+// bodySchema describes a request body, pointers are dereferenced since a
+// missing body is rejected anyway.
+func (s *Spec) bodySchema(body interface{}) *Schema {
+	return s.generator.schemaOf(derefType(reflect.TypeOf(body)))
+}
+
+// envelope wraps the response data in helpers.Response or
+// helpers.PaginatedResponse.
+func (s *Spec) envelope(operation Operation) *Schema {
+	if operation.Paginated {
+		items := &Schema{}
+		if operation.Response != nil {
+			items = s.bodySchema(operation.Response)
+		}
+		return &Schema{AllOf: []*Schema{
+			{Ref: "#/components/schemas/PaginatedResponse"},
+			{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"data": {Type: "array", Items: items},
+					"meta": s.generator.schemaOf(reflect.TypeOf(PaginationMeta{})),
+				},
+			},
+		}}
+	}
+
+	if operation.Response == nil {
+		return &Schema{Ref: "#/components/schemas/Response"}
+	}
+	return &Schema{AllOf: []*Schema{
+		{Ref: "#/components/schemas/Response"},
+		{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data": s.bodySchema(operation.Response),
+			},
+		},
+	}}
+}
+
+func (s *Spec) isIgnored(path string) bool {
+	for _, prefix := range s.ignored {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Spec) tagOf(path string) string {
+	name, length := "", -1
+	for _, tag := range s.tags {
+		if strings.HasPrefix(path, tag.prefix) && len(tag.prefix) > length {
+			name, length = tag.name, len(tag.prefix)
+		}
+	}
+	return name
+}
+
+// openAPIPath turns the :param and *param segments of a gin path into
+// {param} and returns them as path parameters.
+func openAPIPath(ginPath string) (string, []Parameter) {
+	segments := strings.Split(ginPath, "/")
+	parameters := []Parameter{}
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			parameters = append(parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// operationID derives the operation id from the handler method, so
+// golang-gorm/app/delivery/http/user.(*todoHandler).Create-fm becomes
+// todoCreate.
+func operationID(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	receiverStart := strings.LastIndex(handler, "(*")
+	receiverEnd := strings.LastIndex(handler, ").")
+	if receiverStart < 0 || receiverEnd < receiverStart {
+		return ""
+	}
+	receiver := strings.TrimSuffix(handler[receiverStart+2:receiverEnd], "Handler")
+	return receiver + handler[receiverEnd+2:]
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+package http_user
+
+import (
+	"golang-gorm/app/delivery/http/middleware"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiKeyHandler struct {
+	APIKeyUsecase usecase_user.APIKeyUsecase
+	Route         *gin.RouterGroup
+	Middleware    middleware.AuthMiddleware
+}
+
+func NewAPIKeyHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, apiKeyUsecase usecase_user.APIKeyUsecase) {
+	handler := &apiKeyHandler{
+		APIKeyUsecase: apiKeyUsecase,
+		Route:         ginEngine.Group("/user"),
+		Middleware:    middleware,
+	}
+
+	handler.handleAPIKeyRoute("/setting/api-keys")
+}
+
+func (h *apiKeyHandler) handleAPIKeyRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("", h.Middleware.AuthUser(), h.List)
+	api.POST("", h.Middleware.AuthUser(), h.Create)
+	api.DELETE("/:id", h.Middleware.AuthUser(), h.Delete)
+}
+
+func (r *apiKeyHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	query := c.Request.URL.Query()
+
+	response := r.APIKeyUsecase.GetAll(ctx, claim, query)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *apiKeyHandler) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.CreateAPIKeyRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.APIKeyUsecase.Create(ctx, claim, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *apiKeyHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	apiKeyID := c.Param("id")
+
+	response := r.APIKeyUsecase.DeleteOne(ctx, claim, apiKeyID)
+
+	c.JSON(response.Status, response)
+}
@@ -0,0 +1,678 @@
+package http_user
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	davNamespace    = "DAV:"
+	caldavNamespace = "urn:ietf:params:xml:ns:caldav"
+	csNamespace     = "http://calendarserver.org/ns/"
+
+	caldavPrefix         = "/caldav"
+	caldavCollectionName = "todos"
+	caldavContentType    = "text/calendar; charset=utf-8; component=vtodo"
+	caldavPageSize       = 100
+	caldavMaxBodySize    = 1024 * 1024
+)
+
+type caldavHandler struct {
+	AuthUsecase   usecase_user.AuthUsecase
+	APIKeyUsecase usecase_user.APIKeyUsecase
+	TodoUsecase   usecase_user.TodoUsecase
+}
+
+// NewCalDAVHandler exposes the todos of the authenticated user as a CalDAV
+// VTODO collection at /caldav/calendars/<user id>/todos/.
+func NewCalDAVHandler(ginEngine *gin.Engine, authUsecase usecase_user.AuthUsecase, apiKeyUsecase usecase_user.APIKeyUsecase, todoUsecase usecase_user.TodoUsecase) {
+	handler := &caldavHandler{
+		AuthUsecase:   authUsecase,
+		APIKeyUsecase: apiKeyUsecase,
+		TodoUsecase:   todoUsecase,
+	}
+
+	ginEngine.GET("/.well-known/caldav", handler.WellKnown)
+	ginEngine.Handle("PROPFIND", "/.well-known/caldav", handler.WellKnown)
+
+	api := ginEngine.Group(caldavPrefix, handler.Authenticate())
+	for _, method := range []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"} {
+		api.Handle(method, "/*path", handler.Serve)
+	}
+}
+
+func (h *caldavHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, caldavPrefix+"/")
+}
+
+// Authenticate accepts an api key as a bearer token or as the basic auth
+// password, most CalDAV clients can only do the latter, or the account email
+// and password.
+func (h *caldavHandler) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var response helpers.Response
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			response = h.APIKeyUsecase.Verify(ctx, token)
+		} else if email, password, ok := c.Request.BasicAuth(); !ok {
+			h.unauthorized(c)
+			return
+		} else {
+			if helpers.IsAPIKey(password) {
+				response = h.APIKeyUsecase.Verify(ctx, password)
+			}
+			// a password may happen to look like a key
+			if response.Status == 0 || response.Status == http.StatusUnauthorized {
+				response = h.AuthUsecase.VerifyCredentials(ctx, request.LoginRequest{
+					Email:    email,
+					Password: password,
+				})
+			}
+		}
+		if response.Status != http.StatusOK {
+			if response.Status == http.StatusInternalServerError {
+				h.internalError(c, fmt.Errorf("%s", response.Message))
+				c.Abort()
+				return
+			}
+			h.unauthorized(c)
+			return
+		}
+
+		user := response.Data.(*model.User)
+		c.Set("user_data", model.JWTClaimUser{UserID: user.ID, Email: user.Email})
+		c.Next()
+	}
+}
+
+func (h *caldavHandler) unauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="todo", charset="UTF-8"`)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+// caldavPath is a parsed request path below /caldav.
+type caldavPath struct {
+	kind   string // root, principal, home, collection, item
+	userID string
+	name   string
+}
+
+// parseCalDAVPath parses a path as it was sent, still escaped, so an item
+// name is unescaped exactly once.
+func parseCalDAVPath(path string) (caldavPath, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		return caldavPath{kind: "root"}, true
+	case len(parts) == 2 && parts[0] == "principals":
+		return caldavPath{kind: "principal", userID: parts[1]}, true
+	case len(parts) == 2 && parts[0] == "calendars":
+		return caldavPath{kind: "home", userID: parts[1]}, true
+	case len(parts) == 3 && parts[0] == "calendars" && parts[2] == caldavCollectionName:
+		return caldavPath{kind: "collection", userID: parts[1]}, true
+	case len(parts) == 4 && parts[0] == "calendars" && parts[2] == caldavCollectionName && strings.HasSuffix(parts[3], ".ics"):
+		name, err := url.PathUnescape(strings.TrimSuffix(parts[3], ".ics"))
+		if err != nil || name == "" {
+			return caldavPath{}, false
+		}
+		return caldavPath{kind: "item", userID: parts[1], name: name}, true
+	default:
+		return caldavPath{}, false
+	}
+}
+
+func principalHref(userID string) string {
+	return fmt.Sprintf("%s/principals/%s/", caldavPrefix, userID)
+}
+
+func homeHref(userID string) string {
+	return fmt.Sprintf("%s/calendars/%s/", caldavPrefix, userID)
+}
+
+func collectionHref(userID string) string {
+	return fmt.Sprintf("%s%s/", homeHref(userID), caldavCollectionName)
+}
+
+func itemHref(todo *model.Todo) string {
+	return collectionHref(todo.UserID) + url.PathEscape(usecase_user.TodoToICal(todo).UID) + ".ics"
+}
+
+// todoETag includes the version, restoring a todo bumps it but keeps
+// updated_at.
+func todoETag(todo *model.Todo) string {
+	return fmt.Sprintf(`"%d-%d"`, todo.UpdatedAt.UnixMilli(), todo.Version)
+}
+
+func (h *caldavHandler) Serve(c *gin.Context) {
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+
+	path, ok := parseCalDAVPath(strings.TrimPrefix(c.Request.URL.EscapedPath(), caldavPrefix))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if path.userID != "" && path.userID != claim.UserID {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	switch c.Request.Method {
+	case "OPTIONS":
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+		c.Status(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(c, claim, path)
+	case "REPORT":
+		h.report(c, claim, path)
+	case "GET", "HEAD":
+		h.get(c, claim, path)
+	case "PUT":
+		h.put(c, claim, path)
+	case "DELETE":
+		h.delete(c, claim, path)
+	default:
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// davRequest covers the PROPFIND and REPORT bodies the server understands.
+type davRequest struct {
+	XMLName xml.Name
+	AllProp *struct{} `xml:"DAV: allprop"`
+	Prop    *struct {
+		Names []davName `xml:",any"`
+	} `xml:"DAV: prop"`
+	Hrefs  []string       `xml:"DAV: href"`
+	Filter *davCompFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+type davName struct {
+	XMLName xml.Name
+}
+
+type davCompFilter struct {
+	Name    string          `xml:"name,attr"`
+	Filters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// matchesTodo reports whether a calendar-query filter can match a VTODO.
+func (f *davCompFilter) matchesTodo() bool {
+	if f == nil || len(f.Filters) == 0 {
+		return true
+	}
+	for _, filter := range f.Filters {
+		if strings.EqualFold(filter.Name, "VTODO") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDAVRequest(c *gin.Context) (*davRequest, error) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, caldavMaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &davRequest{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.AllProp = &struct{}{}
+		return req, nil
+	}
+	if err := xml.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	if req.Prop == nil {
+		req.AllProp = &struct{}{}
+	}
+
+	return req, nil
+}
+
+// requested returns the requested property names, or nil for allprop.
+func (r *davRequest) requested() []xml.Name {
+	if r.AllProp != nil || r.Prop == nil {
+		return nil
+	}
+	names := []xml.Name{}
+	for _, name := range r.Prop.Names {
+		names = append(names, name.XMLName)
+	}
+	return names
+}
+
+// davResponse is one <response> of a multistatus, props map a property name
+// to its inner xml.
+type davResponse struct {
+	href   string
+	status int
+	props  map[xml.Name]string
+}
+
+func (h *caldavHandler) propfind(c *gin.Context, claim model.JWTClaimUser, path caldavPath) {
+	req, err := parseDAVRequest(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	depth := c.GetHeader("Depth")
+
+	responses := []davResponse{}
+	switch path.kind {
+	case "root", "principal":
+		href := caldavPrefix + "/"
+		if path.kind == "principal" {
+			href = principalHref(claim.UserID)
+		}
+		responses = append(responses, davResponse{href: href, props: principalProps(claim, path.kind == "principal")})
+	case "home":
+		responses = append(responses, davResponse{href: homeHref(claim.UserID), props: homeProps(claim)})
+		if depth == "1" {
+			props, err := h.collectionProps(c.Request.Context(), claim)
+			if err != nil {
+				h.internalError(c, err)
+				return
+			}
+			responses = append(responses, davResponse{href: collectionHref(claim.UserID), props: props})
+		}
+	case "collection":
+		props, err := h.collectionProps(c.Request.Context(), claim)
+		if err != nil {
+			h.internalError(c, err)
+			return
+		}
+		responses = append(responses, davResponse{href: collectionHref(claim.UserID), props: props})
+		if depth == "1" {
+			todos, err := h.allTodos(c.Request.Context(), claim)
+			if err != nil {
+				h.internalError(c, err)
+				return
+			}
+			for _, todo := range todos {
+				responses = append(responses, davResponse{href: itemHref(todo), props: itemProps(todo, req.AllProp == nil)})
+			}
+		}
+	case "item":
+		todo, err := h.findTodo(c.Request.Context(), claim, path.name)
+		if err != nil {
+			h.internalError(c, err)
+			return
+		}
+		if todo == nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		responses = append(responses, davResponse{href: itemHref(todo), props: itemProps(todo, req.AllProp == nil)})
+	}
+
+	writeMultistatus(c, responses, req.requested())
+}
+
+func (h *caldavHandler) report(c *gin.Context, claim model.JWTClaimUser, path caldavPath) {
+	if path.kind != "collection" {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	req, err := parseDAVRequest(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	responses := []davResponse{}
+	switch req.XMLName {
+	case xml.Name{Space: caldavNamespace, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			// clients may send absolute urls
+			hrefPath := href
+			if parsed, err := url.Parse(href); err == nil {
+				hrefPath = parsed.EscapedPath()
+			}
+
+			itemPath, ok := parseCalDAVPath(strings.TrimPrefix(hrefPath, caldavPrefix))
+			if !ok || itemPath.kind != "item" || itemPath.userID != claim.UserID {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+
+			todo, err := h.findTodo(c.Request.Context(), claim, itemPath.name)
+			if err != nil {
+				h.internalError(c, err)
+				return
+			}
+			if todo == nil {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, davResponse{href: itemHref(todo), props: itemProps(todo, true)})
+		}
+	case xml.Name{Space: caldavNamespace, Local: "calendar-query"}:
+		if req.Filter.matchesTodo() {
+			todos, err := h.allTodos(c.Request.Context(), claim)
+			if err != nil {
+				h.internalError(c, err)
+				return
+			}
+			for _, todo := range todos {
+				responses = append(responses, davResponse{href: itemHref(todo), props: itemProps(todo, true)})
+			}
+		}
+	default:
+		c.Status(http.StatusNotImplemented)
+		return
+	}
+
+	writeMultistatus(c, responses, req.requested())
+}
+
+func (h *caldavHandler) get(c *gin.Context, claim model.JWTClaimUser, path caldavPath) {
+	if path.kind != "item" {
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	todo, err := h.findTodo(c.Request.Context(), claim, path.name)
+	if err != nil {
+		h.internalError(c, err)
+		return
+	}
+	if todo == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("ETag", todoETag(todo))
+	c.Header("Last-Modified", todo.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, caldavContentType, []byte(calendarData(todo)))
+}
+
+func (h *caldavHandler) put(c *gin.Context, claim model.JWTClaimUser, path caldavPath) {
+	if path.kind != "item" {
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := c.Request.Context()
+
+	// parse vtodo
+	todos, err := helpers.ParseICalTodos(io.LimitReader(c.Request.Body, caldavMaxBodySize))
+	if err != nil || len(todos) != 1 {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	vtodo := todos[0]
+
+	todo, err := h.findTodo(ctx, claim, path.name)
+	if err != nil {
+		h.internalError(c, err)
+		return
+	}
+
+	// check preconditions
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && (todo == nil || (ifMatch != "*" && ifMatch != todoETag(todo))) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+	if c.GetHeader("If-None-Match") == "*" && todo != nil {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	var response helpers.Response
+	status := http.StatusNoContent
+	if todo == nil {
+		externalID := usecase_user.CalDAVExternalIDPrefix + path.name
+		response = h.TodoUsecase.Create(ctx, claim, request.CreateTodoRequest{
+			Name:       vtodo.Summary,
+			Status:     usecase_user.TodoStatusFromICal(vtodo.Status),
+			ExternalID: &externalID,
+		})
+		status = http.StatusCreated
+	} else {
+		response = h.TodoUsecase.UpdateOne(ctx, claim, todo.ID, request.UpdateTodoRequest{
+			Name:    vtodo.Summary,
+			Status:  usecase_user.TodoStatusFromICal(vtodo.Status),
+			Version: &todo.Version,
+		})
+	}
+	if response.Status != http.StatusOK && response.Status != http.StatusCreated {
+		c.String(response.Status, response.Message)
+		return
+	}
+
+	switch saved := response.Data.(type) {
+	case *model.Todo:
+		c.Header("ETag", todoETag(saved))
+	case model.Todo:
+		c.Header("ETag", todoETag(&saved))
+	}
+	c.Status(status)
+}
+
+func (h *caldavHandler) delete(c *gin.Context, claim model.JWTClaimUser, path caldavPath) {
+	if path.kind != "item" {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	ctx := c.Request.Context()
+
+	todo, err := h.findTodo(ctx, claim, path.name)
+	if err != nil {
+		h.internalError(c, err)
+		return
+	}
+	if todo == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && ifMatch != "*" && ifMatch != todoETag(todo) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	// pin the version we checked the etag against
+	response := h.TodoUsecase.DeleteOne(ctx, claim, todo.ID, request.DeleteTodoRequest{
+		Version: &todo.Version,
+	})
+	if response.Status != http.StatusOK {
+		c.String(response.Status, response.Message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findTodo resolves a resource name: our own todos use their id, todos
+// created by a client use the name it picked.
+func (h *caldavHandler) findTodo(ctx context.Context, claim model.JWTClaimUser, name string) (*model.Todo, error) {
+	if _, err := uuid.Parse(name); err == nil {
+		response := h.TodoUsecase.GetOne(ctx, claim, name)
+		if response.Status == http.StatusInternalServerError {
+			return nil, fmt.Errorf("%s", response.Message)
+		}
+		if todo, ok := response.Data.(*model.Todo); ok {
+			return todo, nil
+		}
+	}
+
+	response := h.TodoUsecase.GetOneByExternalID(ctx, claim, usecase_user.CalDAVExternalIDPrefix+name)
+	if response.Status == http.StatusInternalServerError {
+		return nil, fmt.Errorf("%s", response.Message)
+	}
+	todo, _ := response.Data.(*model.Todo)
+	return todo, nil
+}
+
+func (h *caldavHandler) allTodos(ctx context.Context, claim model.JWTClaimUser) ([]*model.Todo, error) {
+	all := []*model.Todo{}
+	for page := 1; ; page++ {
+		response := h.TodoUsecase.GetAll(ctx, claim, url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(caldavPageSize)},
+		})
+		if response.Status != http.StatusOK {
+			return nil, fmt.Errorf("%s", response.Message)
+		}
+
+		todos, _ := response.Data.([]*model.Todo)
+		all = append(all, todos...)
+		if len(todos) < caldavPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (h *caldavHandler) internalError(c *gin.Context, err error) {
+	logrus.Error(err)
+	c.Status(http.StatusInternalServerError)
+}
+
+func principalProps(claim model.JWTClaimUser, principal bool) map[xml.Name]string {
+	resourceType := "<d:collection/>"
+	if principal {
+		resourceType += "<d:principal/>"
+	}
+	return map[xml.Name]string{
+		{Space: davNamespace, Local: "resourcetype"}:                 resourceType,
+		{Space: davNamespace, Local: "displayname"}:                  xmlEscape(claim.Email),
+		{Space: davNamespace, Local: "current-user-principal"}:       "<d:href>" + principalHref(claim.UserID) + "</d:href>",
+		{Space: davNamespace, Local: "principal-URL"}:                "<d:href>" + principalHref(claim.UserID) + "</d:href>",
+		{Space: caldavNamespace, Local: "calendar-home-set"}:         "<d:href>" + homeHref(claim.UserID) + "</d:href>",
+		{Space: caldavNamespace, Local: "calendar-user-address-set"}: "<d:href>mailto:" + xmlEscape(claim.Email) + "</d:href>",
+	}
+}
+
+func homeProps(claim model.JWTClaimUser) map[xml.Name]string {
+	return map[xml.Name]string{
+		{Space: davNamespace, Local: "resourcetype"}:           "<d:collection/>",
+		{Space: davNamespace, Local: "displayname"}:            xmlEscape(claim.Email),
+		{Space: davNamespace, Local: "current-user-principal"}: "<d:href>" + principalHref(claim.UserID) + "</d:href>",
+	}
+}
+
+func (h *caldavHandler) collectionProps(ctx context.Context, claim model.JWTClaimUser) (map[xml.Name]string, error) {
+	todos, err := h.allTodos(ctx, claim)
+	if err != nil {
+		return nil, err
+	}
+
+	// ctag changes whenever a todo is added, changed or removed
+	hash := sha256.New()
+	for _, todo := range todos {
+		fmt.Fprintf(hash, "%s:%d:%d;", todo.ID, todo.UpdatedAt.UnixMilli(), todo.Version)
+	}
+	ctag := hex.EncodeToString(hash.Sum(nil))
+
+	return map[xml.Name]string{
+		{Space: davNamespace, Local: "resourcetype"}:                        "<d:collection/><c:calendar/>",
+		{Space: davNamespace, Local: "displayname"}:                         "Todos",
+		{Space: davNamespace, Local: "current-user-principal"}:              "<d:href>" + principalHref(claim.UserID) + "</d:href>",
+		{Space: davNamespace, Local: "current-user-privilege-set"}:          "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege><d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>",
+		{Space: davNamespace, Local: "getetag"}:                             `"` + ctag + `"`,
+		{Space: caldavNamespace, Local: "supported-calendar-component-set"}: `<c:comp name="VTODO"/>`,
+		{Space: csNamespace, Local: "getctag"}:                              ctag,
+	}, nil
+}
+
+func itemProps(todo *model.Todo, withData bool) map[xml.Name]string {
+	props := map[xml.Name]string{
+		{Space: davNamespace, Local: "resourcetype"}:    "",
+		{Space: davNamespace, Local: "getetag"}:         xmlEscape(todoETag(todo)),
+		{Space: davNamespace, Local: "getcontenttype"}:  caldavContentType,
+		{Space: davNamespace, Local: "getlastmodified"}: todo.UpdatedAt.UTC().Format(http.TimeFormat),
+	}
+	if withData {
+		props[xml.Name{Space: caldavNamespace, Local: "calendar-data"}] = xmlEscape(calendarData(todo))
+	}
+	return props
+}
+
+func calendarData(todo *model.Todo) string {
+	var buf bytes.Buffer
+	helpers.WriteICalHeader(&buf)
+	helpers.WriteICalTodo(&buf, usecase_user.TodoToICal(todo))
+	helpers.WriteICalFooter(&buf)
+	return buf.String()
+}
+
+func xmlEscape(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+// writeMultistatus writes a 207 response. With requested set, only those
+// properties are returned and missing ones are reported with a 404 propstat.
+func writeMultistatus(c *gin.Context, responses []davResponse, requested []xml.Name) {
+	prefixes := map[string]string{
+		davNamespace:    "d",
+		caldavNamespace: "c",
+		csNamespace:     "cs",
+	}
+	propXML := func(name xml.Name, value string) string {
+		prefix, ok := prefixes[name.Space]
+		if !ok {
+			return fmt.Sprintf(`<%s xmlns="%s">%s</%s>`, name.Local, xmlEscape(name.Space), value, name.Local)
+		}
+		if value == "" {
+			return fmt.Sprintf("<%s:%s/>", prefix, name.Local)
+		}
+		return fmt.Sprintf("<%s:%s>%s</%s:%s>", prefix, name.Local, value, prefix, name.Local)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, response := range responses {
+		b.WriteString("<d:response><d:href>" + xmlEscape(response.href) + "</d:href>")
+		if response.status != 0 {
+			b.WriteString(fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status></d:response>", response.status, http.StatusText(response.status)))
+			continue
+		}
+
+		found, missing := "", ""
+		if requested == nil {
+			for name, value := range response.props {
+				found += propXML(name, value)
+			}
+		} else {
+			for _, name := range requested {
+				if value, ok := response.props[name]; ok {
+					found += propXML(name, value)
+				} else {
+					missing += propXML(name, "")
+				}
+			}
+		}
+		if found != "" {
+			b.WriteString("<d:propstat><d:prop>" + found + "</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>")
+		}
+		if missing != "" {
+			b.WriteString("<d:propstat><d:prop>" + missing + "</d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
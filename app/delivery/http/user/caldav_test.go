@@ -0,0 +1,435 @@
+package http_user
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	testEmail    = "alice@example.com"
+	testPassword = "secret"
+	testAPIKey   = helpers.APIKeyPrefix + "0123456789abcdef0123456789abcdef"
+)
+
+var testUser = &model.User{ID: uuid.NewString(), Email: testEmail}
+
+type fakeAuthUsecase struct {
+	usecase_user.AuthUsecase
+}
+
+func (f *fakeAuthUsecase) VerifyCredentials(ctx context.Context, payload request.LoginRequest) helpers.Response {
+	if payload.Email != testEmail || payload.Password != testPassword {
+		return helpers.Response{Message: "wrong password", Status: http.StatusBadRequest}
+	}
+	return helpers.Response{Data: testUser, Status: http.StatusOK}
+}
+
+type fakeAPIKeyUsecase struct {
+	usecase_user.APIKeyUsecase
+}
+
+func (f *fakeAPIKeyUsecase) Verify(ctx context.Context, key string) helpers.Response {
+	if key != testAPIKey {
+		return helpers.Response{Message: "invalid api key", Status: http.StatusUnauthorized}
+	}
+	return helpers.Response{Data: testUser, Status: http.StatusOK}
+}
+
+// fakeTodoUsecase keeps todos in memory and checks versions like the real
+// usecase does.
+type fakeTodoUsecase struct {
+	usecase_user.TodoUsecase
+
+	mu    sync.Mutex
+	todos map[string]*model.Todo
+	clock time.Time
+}
+
+func newFakeTodoUsecase() *fakeTodoUsecase {
+	return &fakeTodoUsecase{
+		todos: map[string]*model.Todo{},
+		clock: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// tick returns a new time for every change, so etags always change.
+func (f *fakeTodoUsecase) tick() time.Time {
+	f.clock = f.clock.Add(time.Second)
+	return f.clock
+}
+
+func (f *fakeTodoUsecase) GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	todos := []*model.Todo{}
+	if query.Get("page") == "1" {
+		for _, todo := range f.todos {
+			if todo.UserID == claim.UserID {
+				copied := *todo
+				todos = append(todos, &copied)
+			}
+		}
+	}
+	return helpers.PaginatedResponse{Data: todos, Status: http.StatusOK}
+}
+
+func (f *fakeTodoUsecase) find(claim model.JWTClaimUser, match func(*model.Todo) bool) helpers.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, todo := range f.todos {
+		if todo.UserID == claim.UserID && match(todo) {
+			copied := *todo
+			return helpers.Response{Data: &copied, Status: http.StatusOK}
+		}
+	}
+	return helpers.Response{Message: "todo not found", Status: http.StatusNotFound}
+}
+
+func (f *fakeTodoUsecase) GetOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response {
+	return f.find(claim, func(todo *model.Todo) bool { return todo.ID == todoID })
+}
+
+func (f *fakeTodoUsecase) GetOneByExternalID(ctx context.Context, claim model.JWTClaimUser, externalID string) helpers.Response {
+	return f.find(claim, func(todo *model.Todo) bool {
+		return todo.ExternalID != nil && *todo.ExternalID == externalID
+	})
+}
+
+func (f *fakeTodoUsecase) Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateTodoRequest) helpers.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.tick()
+	todo := &model.Todo{
+		ID:         uuid.NewString(),
+		UserID:     claim.UserID,
+		Name:       payload.Name,
+		Status:     payload.Status,
+		ExternalID: payload.ExternalID,
+		Version:    1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	f.todos[todo.ID] = todo
+
+	copied := *todo
+	return helpers.Response{Data: &copied, Status: http.StatusCreated}
+}
+
+func (f *fakeTodoUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.UpdateTodoRequest) helpers.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	todo, ok := f.todos[todoID]
+	if !ok || todo.UserID != claim.UserID {
+		return helpers.Response{Message: "todo not found", Status: http.StatusNotFound}
+	}
+	if payload.Version != nil && *payload.Version != todo.Version {
+		return helpers.Response{Message: "todo was modified", Status: http.StatusConflict}
+	}
+	todo.Name = payload.Name
+	todo.Status = payload.Status
+	todo.Version++
+	todo.UpdatedAt = f.tick()
+
+	copied := *todo
+	return helpers.Response{Data: &copied, Status: http.StatusOK}
+}
+
+func (f *fakeTodoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.DeleteTodoRequest) helpers.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	todo, ok := f.todos[todoID]
+	if !ok || todo.UserID != claim.UserID {
+		return helpers.Response{Message: "todo not found", Status: http.StatusNotFound}
+	}
+	if payload.Version != nil && *payload.Version != todo.Version {
+		return helpers.Response{Message: "todo was modified", Status: http.StatusConflict}
+	}
+	delete(f.todos, todoID)
+
+	return helpers.Response{Status: http.StatusOK}
+}
+
+// caldavClient sends requests like a CalDAV client would.
+type caldavClient struct {
+	t      *testing.T
+	server *httptest.Server
+	auth   func(*http.Request)
+}
+
+func newCalDAVServer(t *testing.T, todoUsecase *fakeTodoUsecase) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	NewCalDAVHandler(engine, &fakeAuthUsecase{}, &fakeAPIKeyUsecase{}, todoUsecase)
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (c *caldavClient) do(method, path string, headers map[string]string, body string) (*http.Response, string) {
+	c.t.Helper()
+
+	req, err := http.NewRequest(method, c.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return res, string(data)
+}
+
+func (c *caldavClient) expect(method, path string, headers map[string]string, body string, status int) (*http.Response, string) {
+	c.t.Helper()
+
+	res, data := c.do(method, path, headers, body)
+	if res.StatusCode != status {
+		c.t.Fatalf("%s %s: got status %d, want %d: %s", method, path, res.StatusCode, status, data)
+	}
+	return res, data
+}
+
+func basicAuth(username, password string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.SetBasicAuth(username, password)
+	}
+}
+
+func vtodo(uid, summary, status string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+		"BEGIN:VTODO\r\nUID:" + uid + "\r\nSUMMARY:" + summary + "\r\nSTATUS:" + status + "\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+}
+
+func TestCalDAVAuthentication(t *testing.T) {
+	server := newCalDAVServer(t, newFakeTodoUsecase())
+
+	tests := []struct {
+		name   string
+		auth   func(*http.Request)
+		status int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"wrong password", basicAuth(testEmail, "wrong"), http.StatusUnauthorized},
+		{"email and password", basicAuth(testEmail, testPassword), http.StatusMultiStatus},
+		{"api key as password", basicAuth("anything", testAPIKey), http.StatusMultiStatus},
+		{"unknown api key", basicAuth(testEmail, helpers.APIKeyPrefix+"ffffffffffffffffffffffff"), http.StatusUnauthorized},
+		{"api key as bearer token", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+testAPIKey)
+		}, http.StatusMultiStatus},
+		{"unknown bearer token", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+helpers.APIKeyPrefix+"ffffffffffffffffffffffff")
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &caldavClient{t: t, server: server, auth: tt.auth}
+			res, _ := client.expect("PROPFIND", "/caldav/", map[string]string{"Depth": "0"}, "", tt.status)
+			if tt.status == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestCalDAVClientSync(t *testing.T) {
+	todoUsecase := newFakeTodoUsecase()
+	server := newCalDAVServer(t, todoUsecase)
+	client := &caldavClient{t: t, server: server, auth: basicAuth("alice", testAPIKey)}
+
+	principal := "/caldav/principals/" + testUser.ID + "/"
+	home := "/caldav/calendars/" + testUser.ID + "/"
+	collection := home + "todos/"
+	item := collection + "reminder-1.ics"
+
+	// discovery
+	res, _ := client.expect("PROPFIND", "/.well-known/caldav", nil, "", http.StatusMovedPermanently)
+	if location := res.Header.Get("Location"); location != "/caldav/" {
+		t.Fatalf("well-known redirects to %q", location)
+	}
+	_, body := client.expect("PROPFIND", "/caldav/", map[string]string{"Depth": "0"},
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:current-user-principal/></d:prop></d:propfind>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "<d:href>"+principal+"</d:href>") {
+		t.Fatalf("current-user-principal missing: %s", body)
+	}
+	_, body = client.expect("PROPFIND", principal, map[string]string{"Depth": "0"},
+		`<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><c:calendar-home-set/></d:prop></d:propfind>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "<d:href>"+home+"</d:href>") {
+		t.Fatalf("calendar-home-set missing: %s", body)
+	}
+	_, body = client.expect("PROPFIND", home, map[string]string{"Depth": "1"}, "", http.StatusMultiStatus)
+	if !strings.Contains(body, "<d:href>"+collection+"</d:href>") || !strings.Contains(body, `<c:comp name="VTODO"/>`) {
+		t.Fatalf("todo collection missing: %s", body)
+	}
+
+	// create
+	res, _ = client.expect("PUT", item, map[string]string{"If-None-Match": "*"},
+		vtodo("reminder-1", "Buy milk", "NEEDS-ACTION"), http.StatusCreated)
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("PUT returned no ETag")
+	}
+	client.expect("PUT", item, map[string]string{"If-None-Match": "*"},
+		vtodo("reminder-1", "Buy milk", "NEEDS-ACTION"), http.StatusPreconditionFailed)
+
+	// read back
+	res, body = client.expect("GET", item, nil, "", http.StatusOK)
+	if res.Header.Get("ETag") != etag {
+		t.Fatalf("GET ETag %q, PUT ETag %q", res.Header.Get("ETag"), etag)
+	}
+	if !strings.Contains(body, "UID:reminder-1") || !strings.Contains(body, "SUMMARY:Buy milk") {
+		t.Fatalf("unexpected calendar data: %s", body)
+	}
+
+	_, body = client.expect("PROPFIND", collection, map[string]string{"Depth": "1"},
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/></d:prop></d:propfind>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "<d:href>"+item+"</d:href>") || !strings.Contains(body, "<d:getetag>"+xmlEscape(etag)+"</d:getetag>") {
+		t.Fatalf("collection listing misses the item: %s", body)
+	}
+
+	_, body = client.expect("REPORT", collection, map[string]string{"Depth": "1"},
+		`<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/><c:calendar-data/></d:prop>`+
+			`<d:href>`+item+`</d:href><d:href>`+collection+`missing.ics</d:href></c:calendar-multiget>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "SUMMARY:Buy milk") || !strings.Contains(body, "404 Not Found") {
+		t.Fatalf("unexpected multiget: %s", body)
+	}
+
+	_, body = client.expect("REPORT", collection, map[string]string{"Depth": "1"},
+		`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/></d:prop>`+
+			`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter></c:calendar-query>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "<d:href>"+item+"</d:href>") {
+		t.Fatalf("calendar-query misses the item: %s", body)
+	}
+	_, body = client.expect("REPORT", collection, map[string]string{"Depth": "1"},
+		`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/></d:prop>`+
+			`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter></c:filter></c:calendar-query>`, http.StatusMultiStatus)
+	if strings.Contains(body, item) {
+		t.Fatalf("VEVENT query returned a todo: %s", body)
+	}
+
+	// update
+	client.expect("PUT", item, map[string]string{"If-Match": `"1"`},
+		vtodo("reminder-1", "Buy oat milk", "COMPLETED"), http.StatusPreconditionFailed)
+	res, _ = client.expect("PUT", item, map[string]string{"If-Match": etag},
+		vtodo("reminder-1", "Buy oat milk", "COMPLETED"), http.StatusNoContent)
+	newETag := res.Header.Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Fatalf("update kept ETag %q", newETag)
+	}
+	_, body = client.expect("GET", item, nil, "", http.StatusOK)
+	if !strings.Contains(body, "SUMMARY:Buy oat milk") || !strings.Contains(body, "STATUS:COMPLETED") {
+		t.Fatalf("update not applied: %s", body)
+	}
+
+	// delete
+	client.expect("DELETE", item, map[string]string{"If-Match": etag}, "", http.StatusPreconditionFailed)
+	client.expect("DELETE", item, map[string]string{"If-Match": newETag}, "", http.StatusNoContent)
+	client.expect("GET", item, nil, "", http.StatusNotFound)
+	client.expect("DELETE", item, nil, "", http.StatusNotFound)
+}
+
+func TestCalDAVOtherUsersCollection(t *testing.T) {
+	todoUsecase := newFakeTodoUsecase()
+	server := newCalDAVServer(t, todoUsecase)
+	client := &caldavClient{t: t, server: server, auth: basicAuth(testEmail, testPassword)}
+
+	other := uuid.NewString()
+	todoUsecase.todos["other"] = &model.Todo{ID: uuid.NewString(), UserID: other, Name: "not yours", UpdatedAt: time.Now()}
+
+	client.expect("PROPFIND", fmt.Sprintf("/caldav/calendars/%s/todos/", other), map[string]string{"Depth": "1"}, "", http.StatusForbidden)
+	client.expect("PUT", fmt.Sprintf("/caldav/calendars/%s/todos/x.ics", other), nil, vtodo("x", "x", "NEEDS-ACTION"), http.StatusForbidden)
+
+	_, body := client.expect("REPORT", "/caldav/calendars/"+testUser.ID+"/todos/", map[string]string{"Depth": "1"},
+		`<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/></d:prop></c:calendar-query>`, http.StatusMultiStatus)
+	if strings.Contains(body, other) {
+		t.Fatalf("listing leaks another user's todo: %s", body)
+	}
+}
+
+func TestCalDAVRestoreChangesETag(t *testing.T) {
+	todoUsecase := newFakeTodoUsecase()
+	server := newCalDAVServer(t, todoUsecase)
+	client := &caldavClient{t: t, server: server, auth: basicAuth(testEmail, testPassword)}
+
+	item := "/caldav/calendars/" + testUser.ID + "/todos/reminder-1.ics"
+	res, _ := client.expect("PUT", item, nil, vtodo("reminder-1", "Buy milk", "NEEDS-ACTION"), http.StatusCreated)
+	etag := res.Header.Get("ETag")
+
+	// restoring from the trash bumps the version and keeps updated_at
+	for _, todo := range todoUsecase.todos {
+		todo.Version++
+	}
+
+	res, _ = client.expect("GET", item, nil, "", http.StatusOK)
+	if res.Header.Get("ETag") == etag {
+		t.Fatalf("restored todo kept ETag %q", etag)
+	}
+	client.expect("DELETE", item, map[string]string{"If-Match": etag}, "", http.StatusPreconditionFailed)
+}
+
+func TestCalDAVEscapedItemName(t *testing.T) {
+	todoUsecase := newFakeTodoUsecase()
+	server := newCalDAVServer(t, todoUsecase)
+	client := &caldavClient{t: t, server: server, auth: basicAuth(testEmail, testPassword)}
+
+	collection := "/caldav/calendars/" + testUser.ID + "/todos/"
+	client.expect("PUT", collection+"a%2541.ics", nil, vtodo("a%41", "Percent", "NEEDS-ACTION"), http.StatusCreated)
+	client.expect("PUT", collection+"aA.ics", nil, vtodo("aA", "Letter", "NEEDS-ACTION"), http.StatusCreated)
+
+	for _, todo := range todoUsecase.todos {
+		if todo.Name == "Percent" && *todo.ExternalID != usecase_user.CalDAVExternalIDPrefix+"a%41" {
+			t.Errorf("item was saved as %q", *todo.ExternalID)
+		}
+	}
+
+	_, body := client.expect("GET", collection+"a%2541.ics", nil, "", http.StatusOK)
+	if !strings.Contains(body, "SUMMARY:Percent") {
+		t.Fatalf("got the wrong todo: %s", body)
+	}
+	_, body = client.expect("REPORT", collection, map[string]string{"Depth": "1"},
+		`<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><c:calendar-data/></d:prop>`+
+			`<d:href>`+collection+`a%2541.ics</d:href></c:calendar-multiget>`, http.StatusMultiStatus)
+	if !strings.Contains(body, "SUMMARY:Percent") || strings.Contains(body, "SUMMARY:Letter") {
+		t.Fatalf("multiget got the wrong todo: %s", body)
+	}
+}
@@ -0,0 +1,65 @@
+package http_user
+
+import (
+	"golang-gorm/app/delivery/http/middleware"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fileHandler struct {
+	FileUsecase usecase_user.FileUsecase
+	Route       *gin.RouterGroup
+	Middleware  middleware.AuthMiddleware
+}
+
+func NewFileHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, fileUsecase usecase_user.FileUsecase) {
+	handler := &fileHandler{
+		FileUsecase: fileUsecase,
+		Route:       ginEngine.Group("/user"),
+		Middleware:  middleware,
+	}
+
+	handler.handleFileRoute("/files")
+}
+
+func (h *fileHandler) handleFileRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.POST("/upload-url", h.Middleware.AuthUser(), h.CreateUploadURL)
+	api.POST("/:id/complete", h.Middleware.AuthUser(), h.CompleteUpload)
+}
+
+func (r *fileHandler) CreateUploadURL(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.CreateUploadURLRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.FileUsecase.CreateUploadURL(ctx, claim, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *fileHandler) CompleteUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	fileID := c.Param("id")
+
+	response := r.FileUsecase.CompleteUpload(ctx, claim, fileID)
+
+	c.JSON(response.Status, response)
+}
@@ -0,0 +1,70 @@
+package http_user
+
+import (
+	"errors"
+	"fmt"
+	"golang-gorm/domain/request"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMultipartFieldSize caps every text field sent along with an upload.
+const maxMultipartFieldSize = 64 * 1024
+
+var errMultipartFieldTooLarge = fmt.Errorf("form field must be less than %dKB", maxMultipartFieldSize/1024)
+
+// readMultipart reads the form fields of a multipart request up to the file
+// part named fileField and returns that part unread, so the file streams from
+// the request instead of being spooled to disk by ParseMultipartForm. Fields
+// after the file aren't read, clients send them first. The part is nil when
+// the request has no such file.
+func readMultipart(c *gin.Context, fileField string) (url.Values, *request.FilePart, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return fields, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the file, handed over unread
+		if part.FormName() == fileField && part.FileName() != "" {
+			return fields, &request.FilePart{
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Body:        part,
+			}, nil
+		}
+
+		// other files are skipped by the next NextPart
+		if part.FileName() != "" {
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxMultipartFieldSize+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(value) > maxMultipartFieldSize {
+			return nil, nil, errMultipartFieldTooLarge
+		}
+		fields.Add(part.FormName(), string(value))
+	}
+}
+
+// isRequestTooLarge reports whether err comes from a request body that grew
+// past its http.MaxBytesReader limit.
+func isRequestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,381 @@
+package http_user
+
+import (
+	"golang-gorm/app/delivery/http/openapi"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	idempotencyKeyHeader = openapi.HeaderParameter("Idempotency-Key", "replays the stored response when the key was used before")
+	ifMatchHeader        = openapi.HeaderParameter("If-Match", "ETags of the versions the change may be based on, or * for any version of an existing todo")
+	ifNoneMatchHeader    = openapi.HeaderParameter("If-None-Match", "answers 304 when the ETag still matches")
+	accessTokenQuery     = openapi.QueryParameter("access_token", "the token for clients that can't set the Authorization header", &openapi.Schema{Type: "string"})
+)
+
+// DescribeRoutes documents the routes of the user http delivery.
+func DescribeRoutes(spec *openapi.Spec) {
+	spec.Enum(model.TodoStatus(""), model.TodoStatusDone, model.TodoStatusNotStarted)
+	spec.Enum(model.TodoHistoryEvent(""),
+		model.TodoHistoryEventCreated,
+		model.TodoHistoryEventRenamed,
+		model.TodoHistoryEventStatusChanged,
+		model.TodoHistoryEventMoved,
+		model.TodoHistoryEventUpdated,
+		model.TodoHistoryEventDeleted,
+		model.TodoHistoryEventRestored,
+	)
+	spec.Enum(model.WebhookDeliveryStatus(""), model.WebhookDeliveryPending, model.WebhookDeliverySucceeded, model.WebhookDeliveryFailed)
+	spec.Enum(model.FileStatus(""), model.FileStatusPending, model.FileStatusReady)
+	spec.Enum(model.ScanStatus(""), model.ScanStatusPending, model.ScanStatusClean, model.ScanStatusInfected)
+
+	// caldav speaks webdav, not json
+	spec.Ignore(caldavPrefix)
+	spec.Ignore("/.well-known/caldav")
+
+	describeAuthRoutes(spec)
+	describeTodoRoutes(spec)
+	describeSyncRoutes(spec)
+	describeSettingRoutes(spec)
+	describeFileRoutes(spec)
+	describeWebhookRoutes(spec)
+	describeAPIKeyRoutes(spec)
+	describeStreamRoutes(spec)
+}
+
+func describeAuthRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/auth", "Auth")
+
+	spec.Describe(http.MethodPost, "/user/auth/register", openapi.Operation{
+		Summary:  "Register a user",
+		Body:     request.RegisterRequest{},
+		Response: model.User{},
+		Status:   http.StatusCreated,
+	})
+	spec.Describe(http.MethodPost, "/user/auth/login", openapi.Operation{
+		Summary: "Log in and get a token",
+		Body:    request.LoginRequest{},
+		Response: struct {
+			Token string     `json:"token"`
+			User  model.User `json:"user"`
+		}{},
+	})
+	spec.Describe(http.MethodGet, "/user/auth/profile", openapi.Operation{
+		Summary:  "Get the profile of the current user",
+		Auth:     true,
+		Response: model.User{},
+	})
+}
+
+func describeTodoRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/todo", "Todo")
+
+	spec.Describe(http.MethodGet, "/user/todo", openapi.Operation{
+		Summary: "List todos",
+		Auth:    true,
+		Parameters: append(append([]openapi.Parameter{}, openapi.PageParameters...),
+			openapi.QueryParameter("status", "only todos with this status", &openapi.Schema{Ref: "#/components/schemas/TodoStatus"}),
+		),
+		Response:  model.Todo{},
+		Paginated: true,
+	})
+	spec.Describe(http.MethodGet, "/user/todo/trash", openapi.Operation{
+		Summary:    "List deleted todos",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.Todo{},
+		Paginated:  true,
+	})
+	spec.Describe(http.MethodGet, "/user/todo/export", openapi.Operation{
+		Summary:  "Export todos",
+		Auth:     true,
+		Query:    request.ExportTodoRequest{},
+		Produces: []string{"text/csv", gin.MIMEJSON, "text/calendar"},
+	})
+	spec.Describe(http.MethodPost, "/user/todo/import", openapi.Operation{
+		Summary:     "Import todos",
+		Description: "Takes the file of at most 10MB as the raw body or as the file field of a multipart form. Todos are matched by external_id, rows of a csv or json export of this account also by id.",
+		Auth:        true,
+		Parameters:  []openapi.Parameter{idempotencyKeyHeader},
+		Query:       request.ImportTodoRequest{},
+		Content: map[string]interface{}{
+			"application/octet-stream": nil,
+			gin.MIMEMultipartPOSTForm: struct {
+				File *multipart.FileHeader `form:"file" validate:"required"`
+			}{},
+		},
+		Response: map[string]interface{}{},
+		Errors:   []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	})
+	spec.Describe(http.MethodGet, "/user/todo/:id", openapi.Operation{
+		Summary:    "Get a todo",
+		Auth:       true,
+		Parameters: []openapi.Parameter{ifNoneMatchHeader},
+		Response:   model.Todo{},
+	})
+	spec.Describe(http.MethodPost, "/user/todo", openapi.Operation{
+		Summary:    "Create a todo",
+		Auth:       true,
+		Parameters: []openapi.Parameter{idempotencyKeyHeader},
+		Body:       request.CreateTodoRequest{},
+		Response:   model.Todo{},
+		Status:     http.StatusCreated,
+		Errors:     []int{http.StatusConflict, http.StatusRequestEntityTooLarge},
+	})
+	spec.Describe(http.MethodPut, "/user/todo/:id", openapi.Operation{
+		Summary:    "Update a todo",
+		Auth:       true,
+		Parameters: []openapi.Parameter{ifMatchHeader},
+		Body:       request.UpdateTodoRequest{},
+		Response:   model.Todo{},
+		Errors:     []int{http.StatusPreconditionFailed},
+	})
+	spec.Describe(http.MethodPatch, "/user/todo/:id", openapi.Operation{
+		Summary:    "Patch a todo",
+		Auth:       true,
+		Parameters: []openapi.Parameter{ifMatchHeader},
+		Content: map[string]interface{}{
+			helpers.MergePatchContentType: request.PatchTodoRequest{},
+			helpers.JSONPatchContentType:  []openapi.JSONPatchOperation{},
+		},
+		Response: model.Todo{},
+		Errors:   []int{http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge},
+	})
+	spec.Describe(http.MethodDelete, "/user/todo/:id", openapi.Operation{
+		Summary:    "Move a todo to the trash",
+		Auth:       true,
+		Parameters: []openapi.Parameter{ifMatchHeader},
+		Errors:     []int{http.StatusPreconditionFailed},
+	})
+	spec.Describe(http.MethodPatch, "/user/todo/:id/move", openapi.Operation{
+		Summary:     "Move a todo between two others",
+		Description: "before_id must come before after_id in the list, neighbors out of order are refused with 400 and nothing is changed.",
+		Auth:        true,
+		Body:        request.MoveTodoRequest{},
+		Response:    model.Todo{},
+	})
+	spec.Describe(http.MethodPost, "/user/todo/:id/restore", openapi.Operation{
+		Summary:    "Restore a todo from the trash",
+		Auth:       true,
+		Parameters: []openapi.Parameter{idempotencyKeyHeader},
+		Response:   model.Todo{},
+	})
+	spec.Describe(http.MethodDelete, "/user/todo/:id/permanent", openapi.Operation{
+		Summary:     "Delete a todo from the trash for good",
+		Description: "Only todos already in the trash can be purged, a live todo is not found.",
+		Auth:        true,
+	})
+	spec.Describe(http.MethodGet, "/user/todo/:id/history", openapi.Operation{
+		Summary:    "List the changes of a todo",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.TodoHistory{},
+		Paginated:  true,
+	})
+	spec.Describe(http.MethodPost, "/user/todo/:id/attachments", openapi.Operation{
+		Summary:     "Upload an attachment",
+		Description: "Takes the file as a multipart upload, base64 encoded in json, or the file_id of a completed presigned upload. Multipart fields must come before the file, the file is streamed as it arrives. Infected files are refused with 422, files still waiting for their malware scan have no url yet.",
+		Auth:        true,
+		Parameters:  []openapi.Parameter{idempotencyKeyHeader},
+		Body:        request.UploadAttachmentRequest{},
+		Content: map[string]interface{}{
+			gin.MIMEMultipartPOSTForm: struct {
+				Name   string                `form:"name"`
+				FileID string                `form:"file_id"`
+				File   *multipart.FileHeader `form:"file"`
+			}{},
+		},
+		Response: model.File{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusInsufficientStorage},
+	})
+	spec.Describe(http.MethodGet, "/user/todo/:id/attachments", openapi.Operation{
+		Summary:    "List the attachments of a todo",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.File{},
+		Paginated:  true,
+	})
+	spec.Describe(http.MethodGet, "/user/todo/:id/attachments/:file_id", openapi.Operation{
+		Summary:     "Download an attachment",
+		Description: "Redirects to a short-lived presigned url. Answers 409 while the file waits for its malware scan or when it is infected.",
+		Auth:        true,
+		Status:      http.StatusFound,
+		Errors:      []int{http.StatusConflict},
+	})
+	spec.Describe(http.MethodDelete, "/user/todo/:id/attachments/:file_id", openapi.Operation{
+		Summary: "Delete an attachment",
+		Auth:    true,
+	})
+}
+
+func describeSyncRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/sync", "Sync")
+
+	spec.Describe(http.MethodGet, "/user/sync", openapi.Operation{
+		Summary:  "Pull the changes since a sync token",
+		Auth:     true,
+		Query:    request.SyncPullRequest{},
+		Response: usecase_user.SyncChanges{},
+	})
+	spec.Describe(http.MethodPost, "/user/sync", openapi.Operation{
+		Summary:    "Push changes made offline",
+		Auth:       true,
+		Parameters: []openapi.Parameter{idempotencyKeyHeader},
+		Body:       request.SyncPushRequest{},
+		Response:   []usecase_user.SyncResult{},
+		Errors:     []int{http.StatusRequestEntityTooLarge},
+	})
+}
+
+func describeSettingRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/setting", "Setting")
+
+	spec.Describe(http.MethodPut, "/user/setting/update-profile", openapi.Operation{
+		Summary:     "Update the profile",
+		Description: "Takes the profile picture as a multipart upload or base64 encoded in json. Multipart fields must come before the picture, the picture is streamed as it arrives. The picture is cropped to a square without its metadata, the avatar lists 64, 256 and 512 pixel variants.",
+		Auth:        true,
+		Body:        request.UserUpdateProfileRequest{},
+		Content: map[string]interface{}{
+			gin.MIMEMultipartPOSTForm: struct {
+				Name           string                `form:"name" validate:"required"`
+				ProfilePicture *multipart.FileHeader `form:"profile_picture" validate:"required"`
+			}{},
+		},
+		Response: model.User{},
+		Errors:   []int{http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage},
+	})
+	spec.Describe(http.MethodPatch, "/user/setting/profile", openapi.Operation{
+		Summary: "Patch the profile",
+		Auth:    true,
+		Content: map[string]interface{}{
+			helpers.MergePatchContentType: request.PatchProfileRequest{},
+			helpers.JSONPatchContentType:  []openapi.JSONPatchOperation{},
+		},
+		Response: model.User{},
+		Errors:   []int{http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage},
+	})
+	spec.Describe(http.MethodGet, "/user/setting/storage", openapi.Operation{
+		Summary:     "Show the storage usage",
+		Description: "Sums up the storage used by files that aren't trashed, avatar sizes included, by category against the quota of the plan. Uploads that would exceed the quota are refused with 507, files larger than the whole quota with 413.",
+		Auth:        true,
+		Response:    model.StorageReport{},
+	})
+}
+
+func describeFileRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/files", "File")
+
+	spec.Describe(http.MethodPost, "/user/files/upload-url", openapi.Operation{
+		Summary:     "Start a presigned upload",
+		Description: "Reserves a pending file and returns the request that puts it straight into the bucket. Send the returned headers as they are, the bucket refuses content that doesn't match the sha256 checksum.",
+		Auth:        true,
+		Body:        request.CreateUploadURLRequest{},
+		Response:    usecase_user.PresignedUpload{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage},
+	})
+	spec.Describe(http.MethodPost, "/user/files/:id/complete", openapi.Operation{
+		Summary:     "Complete a presigned upload",
+		Description: "Checks the size, content type and checksum of the uploaded object, marks the file ready and scans it for malware.",
+		Auth:        true,
+		Response:    model.File{},
+		Errors:      []int{http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+}
+
+func describeWebhookRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/setting/webhooks", "Webhook")
+
+	spec.Describe(http.MethodGet, "/user/setting/webhooks", openapi.Operation{
+		Summary:    "List webhooks",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.Webhook{},
+		Paginated:  true,
+	})
+	spec.Describe(http.MethodPost, "/user/setting/webhooks", openapi.Operation{
+		Summary:     "Create a webhook",
+		Description: "The signing secret is only returned here.",
+		Auth:        true,
+		Body:        request.CreateWebhookRequest{},
+		Response:    usecase_user.WebhookWithSecret{},
+		Status:      http.StatusCreated,
+	})
+	spec.Describe(http.MethodGet, "/user/setting/webhooks/:id", openapi.Operation{
+		Summary:  "Get a webhook",
+		Auth:     true,
+		Response: model.Webhook{},
+	})
+	spec.Describe(http.MethodPut, "/user/setting/webhooks/:id", openapi.Operation{
+		Summary:  "Update a webhook",
+		Auth:     true,
+		Body:     request.UpdateWebhookRequest{},
+		Response: model.Webhook{},
+	})
+	spec.Describe(http.MethodDelete, "/user/setting/webhooks/:id", openapi.Operation{
+		Summary: "Delete a webhook",
+		Auth:    true,
+	})
+	spec.Describe(http.MethodPost, "/user/setting/webhooks/:id/test", openapi.Operation{
+		Summary:  "Send a test event",
+		Auth:     true,
+		Response: model.WebhookDelivery{},
+	})
+	spec.Describe(http.MethodGet, "/user/setting/webhooks/:id/deliveries", openapi.Operation{
+		Summary:    "List the deliveries of a webhook",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.WebhookDelivery{},
+		Paginated:  true,
+	})
+}
+
+func describeAPIKeyRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/setting/api-keys", "API Key")
+
+	spec.Describe(http.MethodGet, "/user/setting/api-keys", openapi.Operation{
+		Summary:    "List api keys",
+		Auth:       true,
+		Parameters: openapi.PageParameters,
+		Response:   model.APIKey{},
+		Paginated:  true,
+	})
+	spec.Describe(http.MethodPost, "/user/setting/api-keys", openapi.Operation{
+		Summary:     "Create an api key",
+		Description: "The key is only returned here. CalDAV clients use it as the password, or as a bearer token.",
+		Auth:        true,
+		Body:        request.CreateAPIKeyRequest{},
+		Response:    usecase_user.APIKeyWithKey{},
+		Status:      http.StatusCreated,
+	})
+	spec.Describe(http.MethodDelete, "/user/setting/api-keys/:id", openapi.Operation{
+		Summary: "Delete an api key",
+		Auth:    true,
+	})
+}
+
+func describeStreamRoutes(spec *openapi.Spec) {
+	spec.Tag("/user/stream", "Stream")
+
+	spec.Describe(http.MethodGet, "/user/stream", openapi.Operation{
+		Summary:     "Stream todo events",
+		Description: "Server-Sent Events named after the event type.",
+		Auth:        true,
+		Parameters:  []openapi.Parameter{accessTokenQuery},
+		Produces:    []string{"text/event-stream"},
+	})
+	spec.Describe(http.MethodGet, "/user/stream/ws", openapi.Operation{
+		Summary:     "Stream todo events over a WebSocket",
+		Description: "Sends the same events as the Server-Sent Events stream as json messages. Browsers can only connect from this server's origin or one listed in STREAM_ALLOWED_ORIGINS.",
+		Auth:        true,
+		Parameters:  []openapi.Parameter{accessTokenQuery},
+		Status:      http.StatusSwitchingProtocols,
+	})
+}
@@ -1,16 +1,27 @@
 package http_user
 
 import (
+	"errors"
+	"fmt"
 	"golang-gorm/app/delivery/http/middleware"
 	usecase_user "golang-gorm/app/usecase/user"
 	"golang-gorm/domain/model"
 	"golang-gorm/domain/request"
 	"golang-gorm/helpers"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// a 10MB picture grows by a third as a base64 data uri
+const maxProfileSize = 16 * 1024 * 1024
+
+// maxPatchSize leaves room for a base64 profile picture in a profile patch.
+const maxPatchSize = 16 * 1024 * 1024
+
 type settingHandler struct {
 	SettingUsecase usecase_user.SettingUsecase
 	Route          *gin.RouterGroup
@@ -31,6 +42,8 @@ func (h *settingHandler) handleSettingRoute(path string) {
 	api := h.Route.Group(path)
 
 	api.PUT("/update-profile", h.Middleware.AuthUser(), h.UpdateProfile)
+	api.PATCH("/profile", h.Middleware.AuthUser(), h.PatchProfile)
+	api.GET("/storage", h.Middleware.AuthUser(), h.GetStorage)
 }
 
 func (r *settingHandler) UpdateProfile(c *gin.Context) {
@@ -38,10 +51,29 @@ func (r *settingHandler) UpdateProfile(c *gin.Context) {
 
 	claim := c.MustGet("user_data").(model.JWTClaimUser)
 	payload := request.UserUpdateProfileRequest{}
-	if err := c.ShouldBindJSON(&payload); err != nil {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxProfileSize)
+
+	// the picture of a multipart request is streamed to the usecase
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		var fields url.Values
+		fields, payload.Upload, err = readMultipart(c, "profile_picture")
+		payload.Name = fields.Get("name")
+	} else {
+		err = c.ShouldBind(&payload)
+	}
+	if isRequestTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, helpers.Response{
+			Data:    nil,
+			Message: fmt.Sprintf("profile must be less than %dMB", maxProfileSize/1024/1024),
+			Status:  http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, helpers.Response{
 			Data:    nil,
-			Message: "invalid json data",
+			Message: "invalid profile data",
 			Status:  http.StatusBadRequest,
 		})
 		return
@@ -51,3 +83,44 @@ func (r *settingHandler) UpdateProfile(c *gin.Context) {
 
 	c.JSON(response.Status, response)
 }
+
+func (r *settingHandler) PatchProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize)
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, helpers.Response{
+				Data:    nil,
+				Message: fmt.Sprintf("patch must be less than %dMB", maxPatchSize/1024/1024),
+				Status:  http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid patch data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.SettingUsecase.PatchProfile(ctx, claim, request.PatchRequest{
+		ContentType: c.ContentType(),
+		Patch:       patch,
+	})
+
+	c.JSON(response.Status, response)
+}
+
+func (r *settingHandler) GetStorage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	response := r.SettingUsecase.GetStorage(ctx, claim)
+
+	c.JSON(response.Status, response)
+}
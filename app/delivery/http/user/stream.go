@@ -0,0 +1,189 @@
+package http_user
+
+import (
+	"context"
+	"encoding/json"
+	"golang-gorm/app/delivery/http/middleware"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+const (
+	// streamHeartbeat keeps idle connections open through proxies
+	streamHeartbeat = 25 * time.Second
+	streamPongWait  = 2 * streamHeartbeat
+	streamWriteWait = 10 * time.Second
+)
+
+type streamHandler struct {
+	StreamUsecase usecase_user.StreamUsecase
+	Route         *gin.RouterGroup
+	Middleware    middleware.AuthMiddleware
+	Upgrader      websocket.Upgrader
+}
+
+func NewStreamHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, streamUsecase usecase_user.StreamUsecase) {
+	handler := &streamHandler{
+		StreamUsecase: streamUsecase,
+		Route:         ginEngine.Group("/user"),
+		Middleware:    middleware,
+		Upgrader: websocket.Upgrader{
+			// a page on another origin that got hold of a token could pass
+			// it in the query string, only let trusted pages connect
+			CheckOrigin: streamOriginChecker(viper.GetString("STREAM_ALLOWED_ORIGINS")),
+		},
+	}
+
+	handler.handleStreamRoute("/stream")
+}
+
+// streamOriginChecker allows WebSocket connections from clients that send no
+// Origin, which browsers always do, from this server's own origin and from
+// the comma separated allowed origins, "*" allowing any.
+func streamOriginChecker(allowed string) func(r *http.Request) bool {
+	origins := map[string]bool{}
+	for _, origin := range strings.Split(allowed, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+		}
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || origins["*"] || origins[strings.ToLower(origin)] {
+			return true
+		}
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+}
+
+func (h *streamHandler) handleStreamRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("", h.Middleware.AuthUserOrQueryToken(), h.Events)
+	api.GET("/ws", h.Middleware.AuthUserOrQueryToken(), h.WebSocket)
+}
+
+// Events streams todo events as Server-Sent Events, named after the event type.
+func (r *streamHandler) Events(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	events, err := r.StreamUsecase.Subscribe(ctx, claim)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case payload, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(streamEventType(payload), string(payload))
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// WebSocket streams todo events as JSON text messages. Messages sent by the
+// client are ignored.
+func (r *streamHandler) WebSocket(c *gin.Context) {
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+
+	conn, err := r.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader already wrote the error response
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	events, err := r.StreamUsecase.Subscribe(ctx, claim)
+	if err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()),
+			time.Now().Add(streamWriteWait))
+		return
+	}
+
+	// read until the client goes away, answering pings and tracking pongs
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func streamEventType(payload []byte) string {
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil || event.Type == "" {
+		return "message"
+	}
+	return event.Type
+}
@@ -1,40 +1,81 @@
 package http_user
 
 import (
+	"errors"
+	"fmt"
 	"golang-gorm/app/delivery/http/middleware"
 	usecase_user "golang-gorm/app/usecase/user"
 	"golang-gorm/domain/model"
 	"golang-gorm/domain/request"
 	"golang-gorm/helpers"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxImportSize = 10 * 1024 * 1024
+
+const maxTodoPatchSize = 1024 * 1024
+
+// request body limits of the idempotent routes, an attachment gets some room
+// for the multipart envelope around it
+const (
+	maxTodoBodySize          = 1024 * 1024
+	maxSyncPushSize          = 8 * 1024 * 1024
+	maxAttachmentRequestSize = 26 * 1024 * 1024
 )
 
 type todoHandler struct {
 	TodoUsecase usecase_user.TodoUsecase
 	Route       *gin.RouterGroup
 	Middleware  middleware.AuthMiddleware
+	Idempotency middleware.IdempotencyMiddleware
 }
 
-func NewTodoHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, todoUsecase usecase_user.TodoUsecase) {
+func NewTodoHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, idempotency middleware.IdempotencyMiddleware, todoUsecase usecase_user.TodoUsecase) {
 	handler := &todoHandler{
 		TodoUsecase: todoUsecase,
 		Route:       ginEngine.Group("/user"),
 		Middleware:  middleware,
+		Idempotency: idempotency,
 	}
 
 	handler.handleTodoRoute("/todo")
+	handler.handleSyncRoute("/sync")
 }
 
 func (h *todoHandler) handleTodoRoute(path string) {
 	api := h.Route.Group(path)
 
 	api.GET("", h.Middleware.AuthUser(), h.List)
+	api.GET("/trash", h.Middleware.AuthUser(), h.Trash)
+	api.GET("/export", h.Middleware.AuthUser(), h.Export)
+	api.POST("/import", h.Middleware.AuthUser(), h.Idempotency.Idempotent(maxImportSize), h.Import)
 	api.GET("/:id", h.Middleware.AuthUser(), h.GetByID)
-	api.POST("", h.Middleware.AuthUser(), h.Create)
+	api.POST("", h.Middleware.AuthUser(), h.Idempotency.Idempotent(maxTodoBodySize), h.Create)
 	api.PUT("/:id", h.Middleware.AuthUser(), h.Update)
+	api.PATCH("/:id", h.Middleware.AuthUser(), h.Patch)
 	api.DELETE("/:id", h.Middleware.AuthUser(), h.Delete)
+	api.PATCH("/:id/move", h.Middleware.AuthUser(), h.Move)
+	api.POST("/:id/restore", h.Middleware.AuthUser(), h.Idempotency.Idempotent(maxTodoBodySize), h.Restore)
+	api.DELETE("/:id/permanent", h.Middleware.AuthUser(), h.Purge)
+	api.GET("/:id/history", h.Middleware.AuthUser(), h.History)
+
+	api.POST("/:id/attachments", h.Middleware.AuthUser(), h.Idempotency.Idempotent(maxAttachmentRequestSize), h.UploadAttachment)
+	api.GET("/:id/attachments", h.Middleware.AuthUser(), h.ListAttachments)
+	api.GET("/:id/attachments/:file_id", h.Middleware.AuthUser(), h.DownloadAttachment)
+	api.DELETE("/:id/attachments/:file_id", h.Middleware.AuthUser(), h.DeleteAttachment)
+}
+
+func (h *todoHandler) handleSyncRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("", h.Middleware.AuthUser(), h.SyncPull)
+	api.POST("", h.Middleware.AuthUser(), h.Idempotency.Idempotent(maxSyncPushSize), h.SyncPush)
 }
 
 func (r *todoHandler) List(c *gin.Context) {
@@ -55,6 +96,14 @@ func (r *todoHandler) GetByID(c *gin.Context) {
 	todoID := c.Param("id")
 
 	response := r.TodoUsecase.GetOne(ctx, claim, todoID)
+	if todo, ok := response.Data.(*model.Todo); ok {
+		etag := helpers.ETag(todo.Version)
+		c.Header("ETag", etag)
+		if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && helpers.ETagMatches(ifNoneMatch, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
 
 	c.JSON(response.Status, response)
 }
@@ -74,6 +123,9 @@ func (r *todoHandler) Create(c *gin.Context) {
 	}
 
 	response := r.TodoUsecase.Create(ctx, claim, payload)
+	if todo, ok := response.Data.(model.Todo); ok {
+		c.Header("ETag", helpers.ETag(todo.Version))
+	}
 
 	c.JSON(response.Status, response)
 }
@@ -93,7 +145,75 @@ func (r *todoHandler) Update(c *gin.Context) {
 		return
 	}
 
+	// If-Match takes precedence over the version in the body
+	if header := c.GetHeader("If-Match"); header != "" {
+		ifMatch, ok := helpers.ParseIfMatch(header)
+		if !ok {
+			c.JSON(http.StatusPreconditionFailed, helpers.Response{
+				Data:    nil,
+				Message: "If-Match does not match the current version",
+				Status:  http.StatusPreconditionFailed,
+			})
+			return
+		}
+		payload.Version = nil
+		payload.IfMatch = ifMatch
+	}
+
 	response := r.TodoUsecase.UpdateOne(ctx, claim, todoID, payload)
+	if todo, ok := response.Data.(*model.Todo); ok {
+		c.Header("ETag", helpers.ETag(todo.Version))
+	}
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTodoPatchSize)
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, helpers.Response{
+				Data:    nil,
+				Message: fmt.Sprintf("patch must be less than %dMB", maxTodoPatchSize/1024/1024),
+				Status:  http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid patch data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	payload := request.PatchRequest{
+		ContentType: c.ContentType(),
+		Patch:       patch,
+	}
+	if header := c.GetHeader("If-Match"); header != "" {
+		ifMatch, ok := helpers.ParseIfMatch(header)
+		if !ok {
+			c.JSON(http.StatusPreconditionFailed, helpers.Response{
+				Data:    nil,
+				Message: "If-Match does not match the current version",
+				Status:  http.StatusPreconditionFailed,
+			})
+			return
+		}
+		payload.IfMatch = ifMatch
+	}
+
+	response := r.TodoUsecase.PatchOne(ctx, claim, todoID, payload)
+	if todo, ok := response.Data.(*model.Todo); ok {
+		c.Header("ETag", helpers.ETag(todo.Version))
+	}
 
 	c.JSON(response.Status, response)
 }
@@ -104,7 +224,293 @@ func (r *todoHandler) Delete(c *gin.Context) {
 	claim := c.MustGet("user_data").(model.JWTClaimUser)
 	todoID := c.Param("id")
 
-	response := r.TodoUsecase.DeleteOne(ctx, claim, todoID)
+	payload := request.DeleteTodoRequest{}
+	if header := c.GetHeader("If-Match"); header != "" {
+		ifMatch, ok := helpers.ParseIfMatch(header)
+		if !ok {
+			c.JSON(http.StatusPreconditionFailed, helpers.Response{
+				Data:    nil,
+				Message: "If-Match does not match the current version",
+				Status:  http.StatusPreconditionFailed,
+			})
+			return
+		}
+		payload.IfMatch = ifMatch
+	}
+
+	response := r.TodoUsecase.DeleteOne(ctx, claim, todoID, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.ExportTodoRequest{}
+	if err := c.ShouldBindQuery(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid query",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.TodoUsecase.Export(ctx, claim, payload)
+	if response.Status != http.StatusOK {
+		c.JSON(response.Status, response)
+		return
+	}
+
+	export := response.Data.(*usecase_user.TodoExport)
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+	c.Status(http.StatusOK)
+	if err := export.Write(ctx, c.Writer); err != nil {
+		// headers are already sent, the client sees a truncated file
+		logrus.Error(err)
+	}
+}
+
+func (r *todoHandler) Import(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.ImportTodoRequest{}
+	if err := c.ShouldBindQuery(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid query",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	// file from multipart form or the raw request body, either way the
+	// request is refused once it grows past the limit
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		_, file, err := readMultipart(c, "file")
+		if isRequestTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, helpers.Response{
+				Data:    nil,
+				Message: fmt.Sprintf("import must be less than %dMB", maxImportSize/1024/1024),
+				Status:  http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		if err != nil || file == nil {
+			c.JSON(http.StatusBadRequest, helpers.Response{
+				Data:    nil,
+				Message: "file is required",
+				Status:  http.StatusBadRequest,
+			})
+			return
+		}
+		body = file.Body
+	}
+
+	response := r.TodoUsecase.Import(ctx, claim, payload, body)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Move(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	payload := request.MoveTodoRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.TodoUsecase.Move(ctx, claim, todoID, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Trash(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	query := c.Request.URL.Query()
+
+	response := r.TodoUsecase.GetTrash(ctx, claim, query)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+
+	response := r.TodoUsecase.RestoreOne(ctx, claim, todoID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) Purge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+
+	response := r.TodoUsecase.PurgeOne(ctx, claim, todoID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) History(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	query := c.Request.URL.Query()
+
+	response := r.TodoUsecase.GetHistory(ctx, claim, todoID, query)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) UploadAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	payload := request.UploadAttachmentRequest{}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentRequestSize)
+
+	// the file of a multipart request is streamed to the usecase
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		var fields url.Values
+		fields, payload.Upload, err = readMultipart(c, "file")
+		payload.Name = fields.Get("name")
+		payload.FileID = fields.Get("file_id")
+	} else {
+		err = c.ShouldBind(&payload)
+	}
+	if isRequestTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, helpers.Response{
+			Data:    nil,
+			Message: fmt.Sprintf("attachment must be less than %dMB", maxAttachmentRequestSize/1024/1024),
+			Status:  http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid request data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.TodoUsecase.UploadAttachment(ctx, claim, todoID, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) ListAttachments(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	query := c.Request.URL.Query()
+
+	response := r.TodoUsecase.GetAttachments(ctx, claim, todoID, query)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) DownloadAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	fileID := c.Param("file_id")
+
+	response := r.TodoUsecase.GetAttachment(ctx, claim, todoID, fileID)
+	if response.Status != http.StatusOK {
+		c.JSON(response.Status, response)
+		return
+	}
+
+	// files not cleared by the malware scan have no url
+	file := response.Data.(*model.File)
+	if file.Url == "" {
+		message := "file is waiting for its malware scan"
+		if file.ScanStatus == model.ScanStatusInfected {
+			message = "file is infected"
+		}
+		c.JSON(http.StatusConflict, helpers.Response{
+			Data:    nil,
+			Message: message,
+			Status:  http.StatusConflict,
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, file.Url)
+}
+
+func (r *todoHandler) DeleteAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	todoID := c.Param("id")
+	fileID := c.Param("file_id")
+
+	response := r.TodoUsecase.DeleteAttachment(ctx, claim, todoID, fileID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) SyncPull(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.SyncPullRequest{}
+	if err := c.ShouldBindQuery(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid query",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.TodoUsecase.SyncPull(ctx, claim, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *todoHandler) SyncPush(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.SyncPushRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.TodoUsecase.SyncPush(ctx, claim, payload)
 
 	c.JSON(response.Status, response)
 }
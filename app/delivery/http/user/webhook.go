@@ -0,0 +1,135 @@
+package http_user
+
+import (
+	"golang-gorm/app/delivery/http/middleware"
+	usecase_user "golang-gorm/app/usecase/user"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type webhookHandler struct {
+	WebhookUsecase usecase_user.WebhookUsecase
+	Route          *gin.RouterGroup
+	Middleware     middleware.AuthMiddleware
+}
+
+func NewWebhookHandler(ginEngine *gin.Engine, middleware middleware.AuthMiddleware, webhookUsecase usecase_user.WebhookUsecase) {
+	handler := &webhookHandler{
+		WebhookUsecase: webhookUsecase,
+		Route:          ginEngine.Group("/user"),
+		Middleware:     middleware,
+	}
+
+	handler.handleWebhookRoute("/setting/webhooks")
+}
+
+func (h *webhookHandler) handleWebhookRoute(path string) {
+	api := h.Route.Group(path)
+
+	api.GET("", h.Middleware.AuthUser(), h.List)
+	api.POST("", h.Middleware.AuthUser(), h.Create)
+	api.GET("/:id", h.Middleware.AuthUser(), h.GetByID)
+	api.PUT("/:id", h.Middleware.AuthUser(), h.Update)
+	api.DELETE("/:id", h.Middleware.AuthUser(), h.Delete)
+	api.POST("/:id/test", h.Middleware.AuthUser(), h.SendTest)
+	api.GET("/:id/deliveries", h.Middleware.AuthUser(), h.Deliveries)
+}
+
+func (r *webhookHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	query := c.Request.URL.Query()
+
+	response := r.WebhookUsecase.GetAll(ctx, claim, query)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) GetByID(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	webhookID := c.Param("id")
+
+	response := r.WebhookUsecase.GetOne(ctx, claim, webhookID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	payload := request.CreateWebhookRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.WebhookUsecase.Create(ctx, claim, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) Update(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	webhookID := c.Param("id")
+	payload := request.UpdateWebhookRequest{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, helpers.Response{
+			Data:    nil,
+			Message: "invalid json data",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := r.WebhookUsecase.UpdateOne(ctx, claim, webhookID, payload)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	webhookID := c.Param("id")
+
+	response := r.WebhookUsecase.DeleteOne(ctx, claim, webhookID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) SendTest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	webhookID := c.Param("id")
+
+	response := r.WebhookUsecase.SendTest(ctx, claim, webhookID)
+
+	c.JSON(response.Status, response)
+}
+
+func (r *webhookHandler) Deliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claim := c.MustGet("user_data").(model.JWTClaimUser)
+	webhookID := c.Param("id")
+	query := c.Request.URL.Query()
+
+	response := r.WebhookUsecase.GetDeliveries(ctx, claim, webhookID, query)
+
+	c.JSON(response.Status, response)
+}
@@ -0,0 +1,64 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang-gorm/domain/model"
+)
+
+// Handler reacts to a domain event. The event is already committed, so a
+// returned error only makes the outbox relay retry it later.
+type Handler func(ctx context.Context, event model.TodoEvent) error
+
+// Bus dispatches domain events relayed from the outbox to every subscribed
+// handler, synchronously and in subscription order. Handlers that do slow
+// work should hand it off, e.g. to a background job. Events are delivered at
+// least once, so handlers have to tolerate duplicates.
+type Bus interface {
+	Subscribe(name string, handler Handler)
+	Publish(ctx context.Context, event model.TodoEvent) error
+}
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+type bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewBus() Bus {
+	return &bus{}
+}
+
+func (b *bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions = append(b.subscriptions, subscription{
+		name:    name,
+		handler: handler,
+	})
+}
+
+// Publish runs every handler, even after one of them fails, and returns
+// their errors joined.
+func (b *bus) Publish(ctx context.Context, event model.TodoEvent) error {
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscriptions {
+		if err := s.handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PendingFileScanner scans files that missed their scan at upload.
+type PendingFileScanner interface {
+	ScanPendingFiles(ctx context.Context, before time.Time) (int, error)
+}
+
+type fileScanJob struct {
+	scanner PendingFileScanner
+	delay   time.Duration
+}
+
+// NewFileScanJob scans files still unscanned delay after their upload, which
+// leaves uploads in progress to their own scan.
+func NewFileScanJob(scanner PendingFileScanner, delay time.Duration) Job {
+	return &fileScanJob{
+		scanner: scanner,
+		delay:   delay,
+	}
+}
+
+func (j *fileScanJob) Name() string {
+	return "file_scan"
+}
+
+func (j *fileScanJob) Run(ctx context.Context) error {
+	before := time.Now().Add(-j.delay)
+
+	// keep going until nothing is left, files the scanner fails on stay
+	// pending for the next run
+	for {
+		scanned, err := j.scanner.ScanPendingFiles(ctx, before)
+		if err != nil {
+			return err
+		}
+		if scanned > 0 {
+			logrus.WithField("job", j.Name()).Infof("scanned %d files", scanned)
+		}
+		if scanned == 0 || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FileSweeper removes files no entity points at and objects without a file.
+type FileSweeper interface {
+	SweepFiles(ctx context.Context, createdBefore time.Time, deletedBefore time.Time, dryRun bool) (*model.FileSweepReport, error)
+}
+
+type fileSweepJob struct {
+	sweeper     FileSweeper
+	gracePeriod time.Duration
+	retention   time.Duration
+	dryRun      bool
+}
+
+// NewFileSweepJob trashes files unreferenced for longer than gracePeriod,
+// purges them retention later and deletes objects left without a file. With
+// dryRun it only logs what it would delete.
+func NewFileSweepJob(sweeper FileSweeper, gracePeriod time.Duration, retention time.Duration, dryRun bool) Job {
+	return &fileSweepJob{
+		sweeper:     sweeper,
+		gracePeriod: gracePeriod,
+		retention:   retention,
+		dryRun:      dryRun,
+	}
+}
+
+func (j *fileSweepJob) Name() string {
+	return "file_sweep"
+}
+
+func (j *fileSweepJob) Run(ctx context.Context) error {
+	now := time.Now()
+	report, err := j.sweeper.SweepFiles(ctx, now.Add(-j.gracePeriod), now.Add(-j.retention), j.dryRun)
+	if err != nil {
+		return err
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"job":     j.Name(),
+		"dry_run": report.DryRun,
+	})
+
+	// a dry run lists everything so it can be checked before enabling
+	if report.DryRun {
+		for _, id := range report.UnreferencedFiles {
+			log.WithField("file_id", id).Info("would trash unreferenced file")
+		}
+		for _, id := range report.PurgedFiles {
+			log.WithField("file_id", id).Info("would purge trashed file")
+		}
+		for _, key := range report.UnusedBlobs {
+			log.WithField("object", key).Info("would delete unused blob")
+		}
+		for _, key := range report.OrphanedObjects {
+			log.WithField("object", key).Info("would delete object without a file")
+		}
+	}
+	for _, key := range report.FailedObjects {
+		log.WithField("object", key).Warn("object could not be deleted")
+	}
+
+	if len(report.UnreferencedFiles)+len(report.PurgedFiles)+len(report.UnusedBlobs)+len(report.OrphanedObjects)+len(report.FailedObjects) > 0 {
+		log.Infof("trashed %d unreferenced files, purged %d files, deleted %d unused blobs and %d orphaned objects, %d failed, %d bytes reclaimed",
+			len(report.UnreferencedFiles), len(report.PurgedFiles), len(report.UnusedBlobs), len(report.OrphanedObjects), len(report.FailedObjects), report.ReclaimedBytes)
+	}
+
+	return nil
+}
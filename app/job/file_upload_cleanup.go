@@ -0,0 +1,50 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PendingUploadCleaner removes presigned uploads that were never completed.
+type PendingUploadCleaner interface {
+	CleanupPendingUploads(ctx context.Context, before time.Time) (int, error)
+}
+
+type fileUploadCleanupJob struct {
+	cleaner PendingUploadCleaner
+	maxAge  time.Duration
+}
+
+// NewFileUploadCleanupJob deletes pending uploads older than maxAge, along
+// with whatever the client put in the bucket.
+func NewFileUploadCleanupJob(cleaner PendingUploadCleaner, maxAge time.Duration) Job {
+	return &fileUploadCleanupJob{
+		cleaner: cleaner,
+		maxAge:  maxAge,
+	}
+}
+
+func (j *fileUploadCleanupJob) Name() string {
+	return "file_upload_cleanup"
+}
+
+func (j *fileUploadCleanupJob) Run(ctx context.Context) error {
+	before := time.Now().Add(-j.maxAge)
+
+	// keep going until nothing is left, uploads that fail to delete stay
+	// behind for the next run
+	for {
+		swept, err := j.cleaner.CleanupPendingUploads(ctx, before)
+		if err != nil {
+			return err
+		}
+		if swept > 0 {
+			logrus.WithField("job", j.Name()).Infof("deleted %d pending uploads", swept)
+		}
+		if swept == 0 || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
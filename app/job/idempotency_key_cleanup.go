@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type idempotencyKeyCleanupJob struct {
+	idempotencyKeyRepository postgresrepo.IdempotencyKeyRepository
+}
+
+// NewIdempotencyKeyCleanupJob deletes idempotency keys past their TTL.
+func NewIdempotencyKeyCleanupJob(idempotencyKeyRepository postgresrepo.IdempotencyKeyRepository) Job {
+	return &idempotencyKeyCleanupJob{
+		idempotencyKeyRepository: idempotencyKeyRepository,
+	}
+}
+
+func (j *idempotencyKeyCleanupJob) Name() string {
+	return "idempotency_key_cleanup"
+}
+
+func (j *idempotencyKeyCleanupJob) Run(ctx context.Context) error {
+	deleted, err := j.idempotencyKeyRepository.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logrus.WithField("job", j.Name()).Infof("deleted %d expired idempotency keys", deleted)
+	}
+
+	return nil
+}
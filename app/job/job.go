@@ -0,0 +1,33 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a unit of background work run periodically by RunEvery.
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// RunEvery runs the job once per interval until ctx is cancelled. Errors are
+// logged and the job is retried on the next tick.
+func RunEvery(ctx context.Context, interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil {
+			logrus.WithField("job", job.Name()).Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type outboxCleanupJob struct {
+	outboxRepository postgresrepo.OutboxRepository
+	retention        time.Duration
+}
+
+// NewOutboxCleanupJob deletes outbox events published longer than retention ago.
+func NewOutboxCleanupJob(outboxRepository postgresrepo.OutboxRepository, retention time.Duration) Job {
+	return &outboxCleanupJob{
+		outboxRepository: outboxRepository,
+		retention:        retention,
+	}
+}
+
+func (j *outboxCleanupJob) Name() string {
+	return "outbox_cleanup"
+}
+
+func (j *outboxCleanupJob) Run(ctx context.Context) error {
+	deleted, err := j.outboxRepository.DeletePublishedBefore(ctx, time.Now().Add(-j.retention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logrus.WithField("job", j.Name()).Infof("deleted %d published outbox events", deleted)
+	}
+
+	return nil
+}
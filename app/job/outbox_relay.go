@@ -0,0 +1,135 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-gorm/app/outbox"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outboxBatchSize   = 100
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = 5 * time.Minute
+)
+
+type outboxRelayJob struct {
+	outboxRepository postgresrepo.OutboxRepository
+	sinks            []outbox.Sink
+	maxAttempts      int
+}
+
+// NewOutboxRelayJob publishes outbox events to every sink, at least once and
+// in order per aggregate. An event that still fails after maxAttempts is
+// marked failed and no longer holds back the events after it.
+func NewOutboxRelayJob(outboxRepository postgresrepo.OutboxRepository, sinks []outbox.Sink, maxAttempts int) Job {
+	return &outboxRelayJob{
+		outboxRepository: outboxRepository,
+		sinks:            sinks,
+		maxAttempts:      maxAttempts,
+	}
+}
+
+func (j *outboxRelayJob) Name() string {
+	return "outbox_relay"
+}
+
+func (j *outboxRelayJob) Run(ctx context.Context) error {
+	unlock, locked, err := j.outboxRepository.TryLock(ctx)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		// another replica is relaying
+		return nil
+	}
+	defer unlock()
+
+	for {
+		events, err := j.outboxRepository.FetchDue(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		// once an event of an aggregate fails, the rest of it waits for the retry
+		blocked := map[string]bool{}
+		for _, event := range events {
+			aggregate := string(event.AggregateType) + ":" + event.AggregateID
+			if blocked[aggregate] {
+				continue
+			}
+			if err := j.publish(ctx, event); err != nil {
+				blocked[aggregate] = true
+				logrus.WithFields(logrus.Fields{
+					"job":      j.Name(),
+					"event_id": event.EventID,
+					"attempts": event.Attempts,
+				}).Warn(err)
+			}
+		}
+
+		if len(events) < outboxBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// publish hands the event to the sinks that haven't received it yet and
+// records the outcome.
+func (j *outboxRelayJob) publish(ctx context.Context, event *model.OutboxEvent) error {
+	var errs []error
+	for _, sink := range j.sinks {
+		if event.Delivered(sink.Name()) {
+			continue
+		}
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		event.DeliveredSinks = append(event.DeliveredSinks, sink.Name())
+	}
+	publishErr := errors.Join(errs...)
+
+	now := time.Now()
+	if publishErr == nil {
+		event.PublishedAt = &now
+		event.LastError = nil
+	} else {
+		message := publishErr.Error()
+		event.Attempts++
+		event.LastError = &message
+		if event.Attempts >= j.maxAttempts {
+			event.FailedAt = &now
+			logrus.WithFields(logrus.Fields{
+				"job":      j.Name(),
+				"event_id": event.EventID,
+			}).Error("giving up on outbox event: ", message)
+		} else {
+			event.NextAttemptAt = now.Add(outboxBackoff(event.Attempts))
+		}
+	}
+
+	// the context may be cancelled by now, the outcome is still worth keeping
+	if err := j.outboxRepository.Update(context.WithoutCancel(ctx), event); err != nil {
+		return err
+	}
+
+	return publishErr
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
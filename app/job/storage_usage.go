@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StorageUsageRecomputer rebuilds the storage usage of users from their files.
+type StorageUsageRecomputer interface {
+	RecomputeStorageUsage(ctx context.Context) (int, error)
+}
+
+type storageUsageJob struct {
+	recomputer StorageUsageRecomputer
+}
+
+// NewStorageUsageJob recomputes the storage usage of every user, so drift
+// from a bug or a manual change to the files table doesn't last.
+func NewStorageUsageJob(recomputer StorageUsageRecomputer) Job {
+	return &storageUsageJob{
+		recomputer: recomputer,
+	}
+}
+
+func (j *storageUsageJob) Name() string {
+	return "storage_usage"
+}
+
+func (j *storageUsageJob) Run(ctx context.Context) error {
+	drifted, err := j.recomputer.RecomputeStorageUsage(ctx)
+	if err != nil {
+		return err
+	}
+	if drifted > 0 {
+		logrus.WithField("job", j.Name()).Warnf("fixed the storage usage of %d users", drifted)
+	}
+	return nil
+}
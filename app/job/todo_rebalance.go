@@ -0,0 +1,42 @@
+package job
+
+import (
+	"context"
+	postgresrepo "golang-gorm/app/repository/postgres"
+
+	"github.com/sirupsen/logrus"
+)
+
+type todoRebalanceJob struct {
+	todoRepository postgresrepo.TodoRepository
+	maxLength      int
+}
+
+// NewTodoRebalanceJob rewrites the positions of every user that has a
+// position longer than maxLength.
+func NewTodoRebalanceJob(todoRepository postgresrepo.TodoRepository, maxLength int) Job {
+	return &todoRebalanceJob{
+		todoRepository: todoRepository,
+		maxLength:      maxLength,
+	}
+}
+
+func (j *todoRebalanceJob) Name() string {
+	return "todo_rebalance"
+}
+
+func (j *todoRebalanceJob) Run(ctx context.Context) error {
+	userIDs, err := j.todoRepository.FetchUserIDsWithLongPositions(ctx, j.maxLength)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := j.todoRepository.RebalancePositions(ctx, userID); err != nil {
+			return err
+		}
+		logrus.WithField("job", j.Name()).Infof("rebalanced todo positions of user %s", userID)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type todoRetentionJob struct {
+	todoRepository postgresrepo.TodoRepository
+	retention      time.Duration
+}
+
+// NewTodoRetentionJob hard-deletes todos that stayed in the trash longer than retention.
+func NewTodoRetentionJob(todoRepository postgresrepo.TodoRepository, retention time.Duration) Job {
+	return &todoRetentionJob{
+		todoRepository: todoRepository,
+		retention:      retention,
+	}
+}
+
+func (j *todoRetentionJob) Name() string {
+	return "todo_retention"
+}
+
+func (j *todoRetentionJob) Run(ctx context.Context) error {
+	purged, err := j.todoRepository.PurgeDeletedBefore(ctx, time.Now().Add(-j.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logrus.WithField("job", j.Name()).Infof("purged %d todos from trash", purged)
+	}
+
+	return nil
+}
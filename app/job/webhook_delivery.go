@@ -0,0 +1,43 @@
+package job
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookDeliverer sends the webhook deliveries that are due.
+type WebhookDeliverer interface {
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+type webhookDeliveryJob struct {
+	deliverer WebhookDeliverer
+}
+
+// NewWebhookDeliveryJob sends queued webhook deliveries and retries failed ones.
+func NewWebhookDeliveryJob(deliverer WebhookDeliverer) Job {
+	return &webhookDeliveryJob{
+		deliverer: deliverer,
+	}
+}
+
+func (j *webhookDeliveryJob) Name() string {
+	return "webhook_delivery"
+}
+
+func (j *webhookDeliveryJob) Run(ctx context.Context) error {
+	// keep going until nothing is due
+	for {
+		attempted, err := j.deliverer.DeliverDue(ctx)
+		if err != nil {
+			return err
+		}
+		if attempted > 0 {
+			logrus.WithField("job", j.Name()).Debugf("attempted %d webhook deliveries", attempted)
+		}
+		if attempted == 0 || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
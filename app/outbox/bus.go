@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"context"
+
+	"golang-gorm/app/event"
+	"golang-gorm/domain/model"
+)
+
+type busSink struct {
+	bus event.Bus
+}
+
+// NewBusSink publishes todo events to the in-process event bus, which feeds
+// the user streams.
+func NewBusSink(bus event.Bus) Sink {
+	return &busSink{bus: bus}
+}
+
+func (s *busSink) Name() string {
+	return "bus"
+}
+
+func (s *busSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	todoEvent, ok, err := decodeTodoEvent(event)
+	if err != nil || !ok {
+		return err
+	}
+	return s.bus.Publish(ctx, todoEvent)
+}
@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang-gorm/domain/model"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+type kafkaSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaSink produces events to topic through a Kafka REST proxy at
+// restURL. Records are keyed by aggregate id, so all events of a todo land
+// on the same partition and keep their order.
+func NewKafkaSink(restURL, topic string) Sink {
+	return &kafkaSink{
+		endpoint: strings.TrimRight(restURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *kafkaSink) Name() string {
+	return "kafka"
+}
+
+func (s *kafkaSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: event.AggregateID, Value: event.Payload}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy responded with status %d: %s", resp.StatusCode, respBody)
+	}
+
+	// the proxy answers 200 even when a record was rejected
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &produced); err != nil {
+		return err
+	}
+	for _, offset := range produced.Offsets {
+		if offset.Error != nil {
+			return fmt.Errorf("kafka rejected record: %s", *offset.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"context"
+
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+type logSink struct {
+	name string
+}
+
+// NewLogSink logs events instead of publishing them. It stands in for a
+// broker that isn't configured, so local setups run the same relay without
+// a NATS or Kafka server.
+func NewLogSink(name string) Sink {
+	return &logSink{name: name}
+}
+
+func (s *logSink) Name() string {
+	return s.name
+}
+
+func (s *logSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	logrus.WithFields(logrus.Fields{
+		"sink":         s.name,
+		"event_id":     event.EventID,
+		"event_type":   event.EventType,
+		"aggregate_id": event.AggregateID,
+	}).Debug(string(event.Payload))
+	return nil
+}
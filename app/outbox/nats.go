@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"golang-gorm/domain/model"
+
+	"github.com/nats-io/nats.go"
+)
+
+type natsSink struct {
+	conn          *nats.Conn
+	subjectPrefix string
+}
+
+// NewNATSSink publishes events to "<subjectPrefix>.<event type>", e.g.
+// "app.todo.created". Core NATS doesn't persist messages, capture the
+// subjects with a JetStream stream for durable consumers. The event id is
+// sent as Nats-Msg-Id so JetStream drops redelivered events.
+func NewNATSSink(url, subjectPrefix string) (Sink, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("golang-gorm outbox"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsSink{
+		conn:          conn,
+		subjectPrefix: subjectPrefix,
+	}, nil
+}
+
+func (s *natsSink) Name() string {
+	return "nats"
+}
+
+func (s *natsSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	msg := nats.NewMsg(s.subjectPrefix + "." + event.EventType)
+	msg.Data = event.Payload
+	msg.Header.Set(nats.MsgIdHdr, event.EventID)
+	msg.Header.Set("Aggregate-Type", string(event.AggregateType))
+	msg.Header.Set("Aggregate-Id", event.AggregateID)
+
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+
+	// make sure the server got it before the event counts as published
+	return s.conn.FlushWithContext(ctx)
+}
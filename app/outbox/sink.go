@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"golang-gorm/domain/model"
+)
+
+// Sink receives events relayed from the outbox. Publish must only return nil
+// once the event is handed over for good, the relay retries the event
+// otherwise. Events may be published more than once, consumers dedupe them
+// by their event id.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// decodeTodoEvent returns the todo event carried by event, ok is false for
+// events of other aggregates.
+func decodeTodoEvent(event *model.OutboxEvent) (todoEvent model.TodoEvent, ok bool, err error) {
+	if event.AggregateType != model.OutboxAggregateTodo {
+		return todoEvent, false, nil
+	}
+	if err := json.Unmarshal(event.Payload, &todoEvent); err != nil {
+		return todoEvent, false, err
+	}
+	return todoEvent, true, nil
+}
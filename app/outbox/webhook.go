@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+
+	"golang-gorm/app/event"
+	"golang-gorm/domain/model"
+)
+
+type webhookSink struct {
+	handler event.Handler
+}
+
+// NewWebhookSink hands todo events to handler, which queues the deliveries
+// of the user's webhooks. It is a sink of its own rather than a bus
+// subscriber so a failing stream doesn't make webhooks queue twice.
+func NewWebhookSink(handler event.Handler) Sink {
+	return &webhookSink{handler: handler}
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *webhookSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	todoEvent, ok, err := decodeTodoEvent(event)
+	if err != nil || !ok {
+		return err
+	}
+	return s.handler(ctx, todoEvent)
+}
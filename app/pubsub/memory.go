@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// subscriberBuffer is how many messages a subscriber may fall behind before
+// messages are dropped for it.
+const subscriberBuffer = 64
+
+type memoryPubSub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+// NewMemoryPubSub fans messages out within the current process only.
+func NewMemoryPubSub() PubSub {
+	return &memoryPubSub{
+		subscribers: map[string]map[chan []byte]struct{}{},
+	}
+}
+
+func (p *memoryPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for ch := range p.subscribers[topic] {
+		select {
+		case ch <- payload:
+		default:
+			logrus.WithField("topic", topic).Warn("pubsub subscriber is too slow, message dropped")
+		}
+	}
+	return nil
+}
+
+func (p *memoryPubSub) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []byte, subscriberBuffer)
+
+	p.mu.Lock()
+	if p.subscribers[topic] == nil {
+		p.subscribers[topic] = map[chan []byte]struct{}{}
+	}
+	p.subscribers[topic][ch] = struct{}{}
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		p.mu.Lock()
+		delete(p.subscribers[topic], ch)
+		if len(p.subscribers[topic]) == 0 {
+			delete(p.subscribers, topic)
+		}
+		p.mu.Unlock()
+
+		close(ch)
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,115 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	postgresChannel = "app_events"
+
+	// postgres rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7999
+)
+
+type postgresEnvelope struct {
+	Topic   string          `json:"t"`
+	Payload json.RawMessage `json:"p"`
+}
+
+type postgresPubSub struct {
+	db    *gorm.DB
+	dsn   string
+	local PubSub
+}
+
+// NewPostgresPubSub publishes with NOTIFY so every replica listening on the
+// same database receives the message, and fans received messages out to
+// local subscribers. The listener reconnects on its own until ctx is done,
+// messages sent while it is disconnected are lost. Payloads must be JSON.
+func NewPostgresPubSub(ctx context.Context, db *gorm.DB, dsn string) PubSub {
+	p := &postgresPubSub{
+		db:    db,
+		dsn:   dsn,
+		local: NewMemoryPubSub(),
+	}
+	go p.listen(ctx)
+	return p
+}
+
+func (p *postgresPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	envelope, err := json.Marshal(postgresEnvelope{
+		Topic:   topic,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	if len(envelope) > maxNotifyPayload {
+		return fmt.Errorf("pubsub payload of %d bytes is too large for NOTIFY", len(envelope))
+	}
+
+	return p.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", postgresChannel, string(envelope)).Error
+}
+
+func (p *postgresPubSub) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	return p.local.Subscribe(ctx, topic)
+}
+
+func (p *postgresPubSub) listen(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := p.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		logrus.WithField("channel", postgresChannel).Error("pubsub listener stopped: ", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (p *postgresPubSub) listenOnce(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var envelope postgresEnvelope
+		if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+			logrus.WithField("channel", postgresChannel).Error("invalid pubsub payload: ", err)
+			continue
+		}
+		if err := p.local.Publish(ctx, envelope.Topic, envelope.Payload); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package pubsub
+
+import (
+	"context"
+)
+
+// PubSub delivers messages published on a topic to every current subscriber
+// of that topic. Delivery is best effort: messages published while nobody is
+// subscribed, or while a subscriber is too slow to keep up, are dropped.
+type PubSub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe returns a channel receiving messages on topic until ctx is
+	// cancelled, after which the channel is closed.
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// UserTopic is the topic carrying events for a single user.
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
@@ -0,0 +1,50 @@
+package blobrepo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultPresignExpiry is used when S3_EXPIRES_TIME isn't set.
+const defaultPresignExpiry = 15 * time.Minute
+
+// ErrObjectNotFound is returned when reading an object that doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// BlobStore keeps file contents under object keys. Objects are private,
+// clients read and write them through presigned requests.
+type BlobStore interface {
+	UploadFile(ctx context.Context, objectName string, body io.Reader, mimeType string) error
+	OpenFile(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error)
+	ReadFileHead(ctx context.Context, objectName string, length int64) ([]byte, error)
+	PresignUpload(ctx context.Context, objectName string, mimeType string, size int64, sha256 string) (*PresignedRequest, error)
+	PresignDownload(ctx context.Context, objectName string) (*PresignedRequest, error)
+	StatFile(ctx context.Context, objectName string) (*ObjectInfo, error)
+	DeleteFile(ctx context.Context, objectName string) error
+	CopyFile(ctx context.Context, sourceName string, objectName string) error
+	ListFiles(ctx context.Context, prefix string, startAfter string, limit int) ([]ObjectInfo, error)
+}
+
+// PresignedRequest is a request the client sends straight to the store. The
+// signed headers must be sent as they are.
+type PresignedRequest struct {
+	URL       string      `json:"url"`
+	Method    string      `json:"method"`
+	Header    http.Header `json:"header"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// ObjectInfo is what the store reports about a stored object. Listings
+// leave ContentType empty, not every store returns it there. Sha256 is the
+// hex checksum the store verified the content against, only stat fills it
+// in and it is empty when the store has none.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	Sha256       string
+	LastModified time.Time
+}
@@ -0,0 +1,338 @@
+package blobrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// conformanceStores are the stores the suite runs against. S3 only runs
+// when a bucket is configured through the S3_* environment variables, the
+// objects it writes there are removed again.
+var conformanceStores = []struct {
+	name string
+	new  func(t *testing.T) BlobStore
+}{
+	{"memory", func(t *testing.T) BlobStore {
+		return NewMemoryBlobStore(testSigner())
+	}},
+	{"local", func(t *testing.T) BlobStore {
+		store, err := NewLocalBlobStore(t.TempDir(), testSigner())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}},
+	{"s3", func(t *testing.T) BlobStore {
+		viper.AutomaticEnv()
+		if viper.GetString("S3_BUCKET_NAME") == "" {
+			t.Skip("S3_BUCKET_NAME is not set")
+		}
+		store, err := NewS3BlobStore(10 * time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}},
+}
+
+// conformanceChecks are the behaviours every BlobStore must share.
+var conformanceChecks = []struct {
+	name string
+	run  func(ctx context.Context, store BlobStore, objectName string) error
+}{
+	{"missing object", checkMissingObject},
+	{"upload and read back", checkRoundTrip},
+	{"read head", checkReadHead},
+	{"overwrite", checkOverwrite},
+	{"failed upload keeps previous object", checkFailedUpload},
+	{"presign", checkPresign},
+	{"delete", checkDelete},
+	{"list", checkList},
+	{"copy", checkCopy},
+}
+
+func TestBlobStoreConformance(t *testing.T) {
+	for _, store := range conformanceStores {
+		t.Run(store.name, func(t *testing.T) {
+			blobStore := store.new(t)
+			prefix := "conformance/" + uuid.New().String()
+
+			for i, check := range conformanceChecks {
+				t.Run(check.name, func(t *testing.T) {
+					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+					defer cancel()
+
+					objectName := fmt.Sprintf("%s/%d.txt", prefix, i)
+					err := check.run(ctx, blobStore, objectName)
+
+					// leave nothing behind, even when the check failed halfway
+					if err := blobStore.DeleteFile(ctx, objectName); err != nil {
+						t.Errorf("cleanup: %v", err)
+					}
+					if err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func testSigner() *URLSigner {
+	return NewURLSigner("http://localhost/blobs", []byte("secret"), time.Minute)
+}
+
+func checkMissingObject(ctx context.Context, store BlobStore, objectName string) error {
+	info, err := store.StatFile(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if info != nil {
+		return errors.New("stat of a missing object is not nil")
+	}
+
+	_, _, err = store.OpenFile(ctx, objectName)
+	if !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("open of a missing object returned %v instead of ErrObjectNotFound", err)
+	}
+
+	if err := store.DeleteFile(ctx, objectName); err != nil {
+		return fmt.Errorf("delete of a missing object: %w", err)
+	}
+	return nil
+}
+
+func checkRoundTrip(ctx context.Context, store BlobStore, objectName string) error {
+	return uploadAndExpect(ctx, store, objectName, "hello blob store", "text/plain")
+}
+
+func checkReadHead(ctx context.Context, store BlobStore, objectName string) error {
+	if _, err := store.ReadFileHead(ctx, objectName, 4); !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("head of a missing object returned %v instead of ErrObjectNotFound", err)
+	}
+
+	if err := uploadAndExpect(ctx, store, objectName, "hello blob store", "text/plain"); err != nil {
+		return err
+	}
+	head, err := store.ReadFileHead(ctx, objectName, 5)
+	if err != nil {
+		return fmt.Errorf("head: %w", err)
+	}
+	if string(head) != "hello" {
+		return fmt.Errorf("head is %q, want %q", head, "hello")
+	}
+
+	// a head longer than the object is the whole object
+	head, err = store.ReadFileHead(ctx, objectName, 512)
+	if err != nil {
+		return fmt.Errorf("head: %w", err)
+	}
+	if string(head) != "hello blob store" {
+		return fmt.Errorf("head is %q, want %q", head, "hello blob store")
+	}
+	return nil
+}
+
+func checkOverwrite(ctx context.Context, store BlobStore, objectName string) error {
+	if err := uploadAndExpect(ctx, store, objectName, "first version", "text/plain"); err != nil {
+		return err
+	}
+	return uploadAndExpect(ctx, store, objectName, `{"second":"version, longer than the first"}`, "application/json")
+}
+
+func checkFailedUpload(ctx context.Context, store BlobStore, objectName string) error {
+	if err := uploadAndExpect(ctx, store, objectName, "kept", "text/plain"); err != nil {
+		return err
+	}
+
+	failing := io.MultiReader(strings.NewReader("partial"), errorReader{})
+	if err := store.UploadFile(ctx, objectName, failing, "text/plain"); err == nil {
+		return errors.New("upload from a failing reader succeeded")
+	}
+
+	return expectObject(ctx, store, objectName, "kept", "text/plain")
+}
+
+func checkPresign(ctx context.Context, store BlobStore, objectName string) error {
+	sum := sha256.Sum256([]byte("hello"))
+	upload, err := store.PresignUpload(ctx, objectName, "text/plain", 5, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return fmt.Errorf("presign upload: %w", err)
+	}
+	if err := expectPresigned(upload, http.MethodPut); err != nil {
+		return fmt.Errorf("presign upload: %w", err)
+	}
+
+	download, err := store.PresignDownload(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("presign download: %w", err)
+	}
+	if err := expectPresigned(download, http.MethodGet); err != nil {
+		return fmt.Errorf("presign download: %w", err)
+	}
+	if upload.URL == download.URL {
+		return errors.New("upload and download share a url")
+	}
+	return nil
+}
+
+func checkDelete(ctx context.Context, store BlobStore, objectName string) error {
+	if err := uploadAndExpect(ctx, store, objectName, "deleted soon", "text/plain"); err != nil {
+		return err
+	}
+	if err := store.DeleteFile(ctx, objectName); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	info, err := store.StatFile(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if info != nil {
+		return errors.New("object still exists after delete")
+	}
+	return nil
+}
+
+func checkList(ctx context.Context, store BlobStore, objectName string) error {
+	// the prefix keeps other objects out of the listing
+	prefix := objectName + ".list/"
+	names := []string{prefix + "a", prefix + "b/c", prefix + "d"}
+	defer func() {
+		for _, name := range names {
+			store.DeleteFile(ctx, name)
+		}
+	}()
+	for _, name := range names {
+		if err := store.UploadFile(ctx, name, strings.NewReader(name), "text/plain"); err != nil {
+			return fmt.Errorf("upload: %w", err)
+		}
+	}
+
+	first, err := store.ListFiles(ctx, prefix, "", 2)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(first) != 2 || first[0].Key != names[0] || first[1].Key != names[1] {
+		return fmt.Errorf("first page is %v, want %v", objectKeys(first), names[:2])
+	}
+	if first[0].Size != int64(len(names[0])) || first[0].LastModified.IsZero() {
+		return fmt.Errorf("listed %+v without size or modification time", first[0])
+	}
+
+	rest, err := store.ListFiles(ctx, prefix, first[1].Key, 2)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(rest) != 1 || rest[0].Key != names[2] {
+		return fmt.Errorf("second page is %v, want %v", objectKeys(rest), names[2:])
+	}
+	return nil
+}
+
+func checkCopy(ctx context.Context, store BlobStore, objectName string) error {
+	copyName := objectName + ".copy"
+	defer store.DeleteFile(ctx, copyName)
+
+	if err := store.CopyFile(ctx, objectName, copyName); !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("copy of a missing object returned %v instead of ErrObjectNotFound", err)
+	}
+
+	if err := uploadAndExpect(ctx, store, objectName, "copied", "text/csv"); err != nil {
+		return err
+	}
+	if err := store.CopyFile(ctx, objectName, copyName); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	if err := expectObject(ctx, store, copyName, "copied", "text/csv"); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	return expectObject(ctx, store, objectName, "copied", "text/csv")
+}
+
+func objectKeys(objects []ObjectInfo) []string {
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key
+	}
+	return keys
+}
+
+func uploadAndExpect(ctx context.Context, store BlobStore, objectName string, content string, contentType string) error {
+	if err := store.UploadFile(ctx, objectName, strings.NewReader(content), contentType); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	return expectObject(ctx, store, objectName, content, contentType)
+}
+
+func expectObject(ctx context.Context, store BlobStore, objectName string, content string, contentType string) error {
+	info, err := store.StatFile(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if err := expectInfo(info, content, contentType); err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	body, info, err := store.OpenFile(ctx, objectName)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer body.Close()
+	if err := expectInfo(info, content, contentType); err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if !bytes.Equal(data, []byte(content)) {
+		return fmt.Errorf("read %q, want %q", data, content)
+	}
+	return nil
+}
+
+func expectInfo(info *ObjectInfo, content string, contentType string) error {
+	if info == nil {
+		return errors.New("object not found")
+	}
+	if info.Size != int64(len(content)) {
+		return fmt.Errorf("size is %d, want %d", info.Size, len(content))
+	}
+	if info.ContentType != contentType {
+		return fmt.Errorf("content type is %q, want %q", info.ContentType, contentType)
+	}
+	return nil
+}
+
+func expectPresigned(request *PresignedRequest, method string) error {
+	if request == nil || request.URL == "" {
+		return errors.New("no url")
+	}
+	if request.Method != method {
+		return fmt.Errorf("method is %s, want %s", request.Method, method)
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return errors.New("already expired")
+	}
+	return nil
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
@@ -0,0 +1,283 @@
+package blobrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// uploadPrefix names the temporary files of uploads in progress.
+const uploadPrefix = ".upload-"
+
+// metadataSuffix names the file next to each object that keeps its content
+// type and checksum, the filesystem has nowhere else to put them.
+const metadataSuffix = ".meta.json"
+
+var errObjectNameInvalid = errors.New("object name is invalid")
+
+type localMetadata struct {
+	ContentType string `json:"content_type"`
+	Sha256      string `json:"sha256,omitempty"`
+}
+
+type localBlobStore struct {
+	dir    string
+	signer *URLSigner
+}
+
+// NewLocalBlobStore keeps objects as files under dir and serves them through
+// signer.
+func NewLocalBlobStore(dir string, signer *URLSigner) (BlobStore, error) {
+	if dir == "" {
+		return nil, errors.New("local blob store directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create local blob store directory: %w", err)
+	}
+
+	return &localBlobStore{
+		dir:    dir,
+		signer: signer,
+	}, nil
+}
+
+// objectPath maps objectName into dir, names that would escape it are
+// rejected.
+func (r *localBlobStore) objectPath(objectName string) (string, error) {
+	cleaned := path.Clean("/" + objectName)
+	if objectName == "" || cleaned != "/"+objectName || strings.HasSuffix(objectName, metadataSuffix) {
+		return "", errObjectNameInvalid
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(cleaned)), nil
+}
+
+// UploadFile writes to a temporary file first, so readers never see a partly
+// written object.
+func (r *localBlobStore) UploadFile(ctx context.Context, objectName string, body io.Reader, mimeType string) error {
+	objectPath, err := r.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o750); err != nil {
+		return err
+	}
+
+	// write content
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), uploadPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(tmp, io.TeeReader(body, hash))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// write metadata
+	metadata, err := json.Marshal(localMetadata{
+		ContentType: mimeType,
+		Sha256:      hex.EncodeToString(hash.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(objectPath+metadataSuffix, metadata, 0o640); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+func (r *localBlobStore) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := r.StatFile(ctx, objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil {
+		return nil, nil, ErrObjectNotFound
+	}
+
+	objectPath, err := r.objectPath(objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(objectPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
+
+func (r *localBlobStore) ReadFileHead(ctx context.Context, objectName string, length int64) ([]byte, error) {
+	file, _, err := r.OpenFile(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, length))
+}
+
+func (r *localBlobStore) PresignUpload(ctx context.Context, objectName string, mimeType string, size int64, sha256 string) (*PresignedRequest, error) {
+	if _, err := r.objectPath(objectName); err != nil {
+		return nil, err
+	}
+
+	return r.signer.presign(SignedObject{
+		Method:      http.MethodPut,
+		ObjectName:  objectName,
+		ContentType: mimeType,
+		Size:        size,
+		Sha256:      sha256,
+	}), nil
+}
+
+func (r *localBlobStore) PresignDownload(ctx context.Context, objectName string) (*PresignedRequest, error) {
+	if _, err := r.objectPath(objectName); err != nil {
+		return nil, err
+	}
+
+	return r.signer.presign(SignedObject{
+		Method:     http.MethodGet,
+		ObjectName: objectName,
+	}), nil
+}
+
+func (r *localBlobStore) StatFile(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	objectPath, err := r.objectPath(objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	metadata := localMetadata{}
+	raw, err := os.ReadFile(objectPath + metadataSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         stat.Size(),
+		ContentType:  metadata.ContentType,
+		Sha256:       metadata.Sha256,
+		LastModified: stat.ModTime(),
+	}, nil
+}
+
+func (r *localBlobStore) DeleteFile(ctx context.Context, objectName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	objectPath, err := r.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{objectPath, objectPath + metadataSuffix} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyFile goes through UploadFile, so the copy appears at once as well.
+func (r *localBlobStore) CopyFile(ctx context.Context, sourceName string, objectName string) error {
+	body, info, err := r.OpenFile(ctx, sourceName)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return r.UploadFile(ctx, objectName, body, info.ContentType)
+}
+
+// ListFiles walks the whole directory on every page, which is fine for the
+// development stores this is meant for.
+func (r *localBlobStore) ListFiles(ctx context.Context, prefix string, startAfter string, limit int) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	err := filepath.WalkDir(r.dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(name, metadataSuffix) || strings.HasPrefix(entry.Name(), uploadPrefix) {
+			return nil
+		}
+
+		relative, err := filepath.Rel(r.dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) || key <= startAfter {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// walk order is per directory, keys are compared as a whole
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	if len(objects) > limit {
+		objects = objects[:limit]
+	}
+	return objects, nil
+}
@@ -0,0 +1,182 @@
+package blobrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data         []byte
+	contentType  string
+	sha256       string
+	lastModified time.Time
+}
+
+type memoryBlobStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	signer  *URLSigner
+}
+
+// NewMemoryBlobStore keeps objects in process memory and serves them through
+// signer. Objects are lost on restart, so it is meant for development and
+// tests.
+func NewMemoryBlobStore(signer *URLSigner) BlobStore {
+	return &memoryBlobStore{
+		objects: map[string]memoryObject{},
+		signer:  signer,
+	}
+}
+
+func (r *memoryBlobStore) UploadFile(ctx context.Context, objectName string, body io.Reader, mimeType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	r.objects[objectName] = memoryObject{data: data, contentType: mimeType, sha256: hex.EncodeToString(sum[:]), lastModified: time.Now()}
+	return nil
+}
+
+func (r *memoryBlobStore) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	object, ok := r.objects[objectName]
+	if !ok {
+		return nil, nil, ErrObjectNotFound
+	}
+
+	// data is never modified in place, uploads replace the whole object
+	return io.NopCloser(bytes.NewReader(object.data)), object.info(objectName), nil
+}
+
+func (r *memoryBlobStore) ReadFileHead(ctx context.Context, objectName string, length int64) ([]byte, error) {
+	file, _, err := r.OpenFile(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(io.LimitReader(file, length))
+}
+
+func (r *memoryBlobStore) PresignUpload(ctx context.Context, objectName string, mimeType string, size int64, sha256 string) (*PresignedRequest, error) {
+	return r.signer.presign(SignedObject{
+		Method:      http.MethodPut,
+		ObjectName:  objectName,
+		ContentType: mimeType,
+		Size:        size,
+		Sha256:      sha256,
+	}), nil
+}
+
+func (r *memoryBlobStore) PresignDownload(ctx context.Context, objectName string) (*PresignedRequest, error) {
+	return r.signer.presign(SignedObject{
+		Method:     http.MethodGet,
+		ObjectName: objectName,
+	}), nil
+}
+
+func (r *memoryBlobStore) StatFile(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	object, ok := r.objects[objectName]
+	if !ok {
+		return nil, nil
+	}
+
+	info := object.info(objectName)
+	info.Sha256 = object.sha256
+	return info, nil
+}
+
+func (r *memoryBlobStore) DeleteFile(ctx context.Context, objectName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.objects, objectName)
+	return nil
+}
+
+// CopyFile shares the data of the source, objects are never modified in
+// place.
+func (r *memoryBlobStore) CopyFile(ctx context.Context, sourceName string, objectName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	object, ok := r.objects[sourceName]
+	if !ok {
+		return ErrObjectNotFound
+	}
+	object.lastModified = time.Now()
+	r.objects[objectName] = object
+	return nil
+}
+
+func (r *memoryBlobStore) ListFiles(ctx context.Context, prefix string, startAfter string, limit int) ([]ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []string{}
+	for key := range r.objects {
+		if strings.HasPrefix(key, prefix) && key > startAfter {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	objects := make([]ObjectInfo, len(keys))
+	for i, key := range keys {
+		objects[i] = *r.objects[key].info(key)
+		objects[i].ContentType = ""
+	}
+	return objects, nil
+}
+
+func (o memoryObject) info(objectName string) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         int64(len(o.data)),
+		ContentType:  o.contentType,
+		LastModified: o.lastModified,
+	}
+}
@@ -0,0 +1,271 @@
+package blobrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/viper"
+)
+
+// uploadPartSize is the size of each part of a multipart upload, smaller
+// bodies are sent with a single PutObject.
+const uploadPartSize = 8 * 1024 * 1024
+
+type s3BlobStore struct {
+	bucketName    string
+	client        *s3.Client
+	uploader      *manager.Uploader
+	presigner     *s3.PresignClient
+	presignExpiry time.Duration
+}
+
+// NewS3BlobStore keeps objects in an S3 compatible bucket.
+func NewS3BlobStore(contextTimeout time.Duration) (BlobStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	defer cancel()
+
+	bucketName := viper.GetString("S3_BUCKET_NAME")
+	if bucketName == "" {
+		return nil, errors.New("S3_BUCKET_NAME is not set")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(credentials.StaticCredentialsProvider{
+			Value: aws.Credentials{
+				AccessKeyID:     viper.GetString("S3_ACCESS_KEY"),
+				SecretAccessKey: viper.GetString("S3_SECRET_KEY"),
+				SessionToken:    "",
+			},
+		}),
+		config.WithRegion(viper.GetString("S3_REGION")),
+		config.WithBaseEndpoint(viper.GetString("S3_ENDPOINT")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load s3 config: %w", err)
+	}
+
+	presignExpiry := time.Duration(viper.GetInt("S3_EXPIRES_TIME")) * time.Second
+	if presignExpiry <= 0 {
+		presignExpiry = defaultPresignExpiry
+	}
+
+	client := s3.NewFromConfig(cfg)
+	return &s3BlobStore{
+		client:        client,
+		bucketName:    bucketName,
+		presigner:     s3.NewPresignClient(client),
+		presignExpiry: presignExpiry,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = uploadPartSize
+			u.Concurrency = 2
+		}),
+	}, nil
+}
+
+// UploadFile streams body to the bucket, large bodies go up as a multipart
+// upload that is aborted when reading body fails. Objects are private, they
+// are read through presigned urls.
+func (r *s3BlobStore) UploadFile(ctx context.Context, objectName string, body io.Reader, mimeType string) error {
+	// upload to bucket s3
+	_, err := r.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.bucketName),
+		Key:         aws.String(objectName),
+		Body:        body,
+		ContentType: aws.String(mimeType),
+	})
+	return err
+}
+
+// OpenFile streams objectName from the bucket.
+func (r *s3BlobStore) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	output, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, err
+	}
+
+	return output.Body, &ObjectInfo{
+		Key:          objectName,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}, nil
+}
+
+// ReadFileHead reads the first length bytes of objectName with a ranged GET,
+// fewer when the object is shorter.
+func (r *s3BlobStore) ReadFileHead(ctx context.Context, objectName string, length int64) ([]byte, error) {
+	output, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectName),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", length-1)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(io.LimitReader(output.Body, length))
+}
+
+// PresignUpload signs a PUT of exactly size bytes of mimeType to objectName.
+// The checksum is signed along, so S3 refuses a body with any other content.
+func (r *s3BlobStore) PresignUpload(ctx context.Context, objectName string, mimeType string, size int64, sha256 string) (*PresignedRequest, error) {
+	sum, err := hex.DecodeString(sha256)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sha256 checksum: %w", err)
+	}
+
+	request, err := r.presigner.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:            aws.String(r.bucketName),
+		Key:               aws.String(objectName),
+		ContentType:       aws.String(mimeType),
+		ContentLength:     aws.Int64(size),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		ChecksumSHA256:    aws.String(base64.StdEncoding.EncodeToString(sum)),
+	}, s3.WithPresignExpires(r.presignExpiry))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.presigned(request), nil
+}
+
+// PresignDownload signs a GET of objectName.
+func (r *s3BlobStore) PresignDownload(ctx context.Context, objectName string) (*PresignedRequest, error) {
+	request, err := r.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectName),
+	}, s3.WithPresignExpires(r.presignExpiry))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.presigned(request), nil
+}
+
+func (r *s3BlobStore) presigned(request *v4.PresignedHTTPRequest) *PresignedRequest {
+	// host is implied by the url
+	header := request.SignedHeader.Clone()
+	header.Del("Host")
+
+	return &PresignedRequest{
+		URL:       request.URL,
+		Method:    request.Method,
+		Header:    header,
+		ExpiresAt: time.Now().Add(r.presignExpiry),
+	}
+}
+
+// StatFile returns the size, content type and SHA-256 checksum of
+// objectName, nil when the object doesn't exist. Only objects put with a
+// SHA-256 checksum in a single part have one.
+func (r *s3BlobStore) StatFile(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	output, err := r.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(r.bucketName),
+		Key:          aws.String(objectName),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		Sha256:       checksumHex(aws.ToString(output.ChecksumSHA256)),
+		LastModified: aws.ToTime(output.LastModified),
+	}, nil
+}
+
+// checksumHex turns the base64 checksum S3 reports into hex. Multipart
+// objects have a checksum of their part checksums, suffixed with the part
+// count, which isn't the checksum of the content.
+func checksumHex(checksum string) string {
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(sum) != sha256.Size {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+// DeleteFile removes objectName, deleting a missing object is not an error.
+func (r *s3BlobStore) DeleteFile(ctx context.Context, objectName string) error {
+	_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(objectName),
+	})
+	return err
+}
+
+// CopyFile copies within the bucket, the content type is kept.
+func (r *s3BlobStore) CopyFile(ctx context.Context, sourceName string, objectName string) error {
+	_, err := r.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(r.bucketName),
+		CopySource: aws.String(r.bucketName + "/" + url.PathEscape(sourceName)),
+		Key:        aws.String(objectName),
+	})
+	if err != nil {
+		// copy errors aren't modeled, only their code tells
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey" {
+			return ErrObjectNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListFiles returns up to limit objects under prefix with keys after
+// startAfter, in key order.
+func (r *s3BlobStore) ListFiles(ctx context.Context, prefix string, startAfter string, limit int) ([]ObjectInfo, error) {
+	output, err := r.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:     aws.String(r.bucketName),
+		Prefix:     aws.String(prefix),
+		StartAfter: aws.String(startAfter),
+		MaxKeys:    aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]ObjectInfo, len(output.Contents))
+	for i, object := range output.Contents {
+		objects[i] = ObjectInfo{
+			Key:          aws.ToString(object.Key),
+			Size:         aws.ToInt64(object.Size),
+			LastModified: aws.ToTime(object.LastModified),
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,93 @@
+package memoryrepo
+
+import (
+	"context"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/domain/model"
+	"sync"
+	"time"
+)
+
+type idempotencyKeyRepository struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyKey
+}
+
+// NewIdempotencyKeyRepository keeps idempotency keys in process memory. Keys
+// are lost on restart and not shared between instances, so it is meant for
+// development and single instance deployments.
+func NewIdempotencyKeyRepository() postgresrepo.IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{
+		records: map[string]model.IdempotencyKey{},
+	}
+}
+
+func idempotencyRecordID(record *model.IdempotencyKey) string {
+	return record.UserID + "\x00" + record.Key
+}
+
+func (r *idempotencyKeyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyRecordID(record)
+	if existing, ok := r.records[id]; ok && !existing.ExpiresAt.Before(time.Now()) {
+		return &existing, nil
+	}
+
+	record.CreatedAt = time.Now()
+	r.records[id] = *record
+	return nil, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, record *model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyRecordID(record)
+	if _, ok := r.records[id]; ok {
+		r.records[id] = *record
+	}
+	return nil
+}
+
+func (r *idempotencyKeyRepository) Release(ctx context.Context, record *model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyRecordID(record)
+	if existing, ok := r.records[id]; ok && existing.IsPending() {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, record := range r.records {
+		if record.ExpiresAt.Before(before) {
+			delete(r.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,117 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits how often using a key writes last_used_at.
+const apiKeyTouchInterval = time.Minute
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+type APIKeyRepository interface {
+	FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.APIKey, error)
+	Count(ctx context.Context, filters map[string]interface{}) (int64, error)
+	FindOne(ctx context.Context, filters map[string]interface{}) (*model.APIKey, error)
+	Create(ctx context.Context, apiKey *model.APIKey) error
+	DeleteOne(ctx context.Context, apiKey *model.APIKey) error
+	Touch(ctx context.Context, apiKey *model.APIKey) error
+}
+
+func (r *apiKeyRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	query = helpers.CommonFilter(query, filters)
+
+	// filters
+	if userID, ok := filters["user_id"].(string); ok {
+		query = query.Where("user_id = ?", userID)
+	}
+	if keyHash, ok := filters["key_hash"].(string); ok {
+		query = query.Where("key_hash = ?", keyHash)
+	}
+
+	return query
+}
+
+func (r *apiKeyRepository) FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.APIKey, error) {
+	var apiKeys []*model.APIKey
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&apiKeys).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepository) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	var count int64
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).Model(&model.APIKey{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindOne returns the key together with its user.
+func (r *apiKeyRepository) FindOne(ctx context.Context, filters map[string]interface{}) (*model.APIKey, error) {
+	var apiKey model.APIKey
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Preload("User", "deleted_at IS NULL").
+		First(&apiKey).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("User").Create(apiKey).Error
+}
+
+func (r *apiKeyRepository) DeleteOne(ctx context.Context, apiKey *model.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(apiKey).
+		UpdateColumn("deleted_at", time.Now()).Error
+}
+
+// Touch records that the key was used, at most once per apiKeyTouchInterval.
+func (r *apiKeyRepository) Touch(ctx context.Context, apiKey *model.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&model.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-apiKeyTouchInterval)).
+		UpdateColumn("last_used_at", now).Error
+}
@@ -2,13 +2,20 @@ package postgresrepo
 
 import (
 	"context"
+	"errors"
 	"golang-gorm/domain/model"
 	"golang-gorm/helpers"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrStorageQuotaExceeded is returned when a new file doesn't fit in the
+// quota of its user.
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
 type fileRepository struct {
 	db *gorm.DB
 }
@@ -21,14 +28,41 @@ type FileRepository interface {
 	FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.File, error)
 	Count(ctx context.Context, filters map[string]interface{}) (int64, error)
 	FindOne(ctx context.Context, filters map[string]interface{}) (*model.File, error)
-	Create(ctx context.Context, file *model.File) error
+	Create(ctx context.Context, file *model.File, quota int64) error
 	Update(ctx context.Context, file *model.File) error
+	DeleteOne(ctx context.Context, file *model.File) error
+	FetchByTodoIDs(ctx context.Context, userID string, todoIDs []string) (map[string][]*model.File, error)
+	FetchPending(ctx context.Context, before time.Time, limit int) ([]*model.File, error)
+	FetchUnreferenced(ctx context.Context, before time.Time, afterID string, limit int) ([]*model.File, error)
+	FetchDeleted(ctx context.Context, before time.Time, afterID string, limit int) ([]*model.File, error)
+	FindObjectKeys(ctx context.Context, objectKeys []string) (map[string]bool, error)
+	Purge(ctx context.Context, file *model.File) error
+	FetchUnscanned(ctx context.Context, before time.Time, limit int) ([]*model.File, error)
+	AttachBlob(ctx context.Context, file *model.File, previousKey string) error
+	Quarantine(ctx context.Context, file *model.File, previousKey string) error
+	FindBlobKeys(ctx context.Context, objectKeys []string) (map[string]bool, error)
+	FetchUnusedBlobs(ctx context.Context, before time.Time, afterKey string, limit int) ([]*model.FileBlob, error)
+	DeleteUnusedBlob(ctx context.Context, objectKey string, deleteObject func(ctx context.Context) error) (bool, error)
+	FetchUsage(ctx context.Context, userID string) ([]*model.StorageUsage, error)
+	RecomputeUsage(ctx context.Context, userID string) (bool, error)
 }
 
 func (r *fileRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
 	query = helpers.CommonFilter(query, filters)
 
 	// filters
+	if userID, ok := filters["user_id"].(string); ok {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status, ok := filters["status"].(model.FileStatus); ok {
+		query = query.Where("status = ?", status)
+	}
+	if scanStatus, ok := filters["scan_status"].(model.ScanStatus); ok {
+		query = query.Where("scan_status = ?", scanStatus)
+	}
+	if todoID, ok := filters["todo_id"].(string); ok {
+		query = query.Where("id IN (?)", r.db.Model(&model.TodoAttachment{}).Select("file_id").Where("todo_id = ?", todoID))
+	}
 
 	return query
 }
@@ -73,11 +107,50 @@ func (r *fileRepository) FindOne(ctx context.Context, filters map[string]interfa
 	return &file, nil
 }
 
-func (r *fileRepository) Create(ctx context.Context, file *model.File) error {
+// Create saves file and its variants, takes a reference on the shared blob of
+// each one with a content hash and adds them to the storage usage of the
+// user. It fails with ErrStorageQuotaExceeded when the usage would grow past
+// quota bytes, there is no limit when quota is 0.
+func (r *fileRepository) Create(ctx context.Context, file *model.File, quota int64) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Create(file).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if file.UserID != nil {
+			if err := lockUsage(tx, *file.UserID); err != nil {
+				return err
+			}
+
+			if quota > 0 {
+				var used int64
+				err := tx.Model(&model.StorageUsage{}).
+					Where("user_id = ?", *file.UserID).
+					Select("COALESCE(SUM(bytes), 0)").
+					Scan(&used).Error
+				if err != nil {
+					return err
+				}
+				if used+storedSize(file) > quota {
+					return ErrStorageQuotaExceeded
+				}
+			}
+		}
+
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+
+		for _, blob := range fileBlobs(file) {
+			if err := acquireBlob(tx, blob); err != nil {
+				return err
+			}
+		}
+
+		if file.UserID == nil {
+			return nil
+		}
+		return addUsage(tx, *file.UserID, file.MimeType, storedSize(file), 1)
+	})
 }
 
 func (r *fileRepository) Update(ctx context.Context, file *model.File) error {
@@ -86,3 +159,507 @@ func (r *fileRepository) Update(ctx context.Context, file *model.File) error {
 	}
 	return r.db.WithContext(ctx).Save(file).Error
 }
+
+// DeleteOne moves file to the trash and takes it off the storage usage of its
+// user, trashing it twice changes nothing.
+func (r *fileRepository) DeleteOne(ctx context.Context, file *model.File) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return trashFile(tx, file.ID)
+	})
+}
+
+// trashFile soft-deletes a file that isn't trashed yet and takes it off the
+// storage usage of its user.
+func trashFile(tx *gorm.DB, fileID string) error {
+	live, err := lockLiveFile(tx, fileID)
+	if err != nil || live == nil {
+		return err
+	}
+
+	err = tx.Model(&model.File{}).Where("id = ?", fileID).UpdateColumn("deleted_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return removeUsage(tx, live)
+}
+
+// FetchByTodoIDs returns the attachments of the user's todos in one query,
+// keyed by todo id.
+func (r *fileRepository) FetchByTodoIDs(ctx context.Context, userID string, todoIDs []string) (map[string][]*model.File, error) {
+	var rows []struct {
+		model.File
+		TodoID string
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("files").
+		Select("files.*, todo_attachments.todo_id").
+		Joins("JOIN todo_attachments ON todo_attachments.file_id = files.id").
+		Joins("JOIN todos ON todos.id = todo_attachments.todo_id").
+		Where("todo_attachments.todo_id IN ? AND todos.user_id = ? AND files.deleted_at IS NULL", todoIDs, userID).
+		Order("files.created_at ASC").
+		Scan(&rows).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	files := make(map[string][]*model.File, len(todoIDs))
+	for i := range rows {
+		files[rows[i].TodoID] = append(files[rows[i].TodoID], &rows[i].File)
+	}
+
+	return files, nil
+}
+
+// FetchPending returns presigned uploads created before the given time that
+// were never completed, oldest first.
+func (r *fileRepository) FetchPending(ctx context.Context, before time.Time, limit int) ([]*model.File, error) {
+	var files []*model.File
+
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ? AND deleted_at IS NULL", model.FileStatusPending, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&files).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// FetchUnreferenced returns ready files created before the given time that
+// are neither an avatar nor an attachment, ordered by id after afterID.
+// Attachments of trashed todos still count until the todo is purged.
+func (r *fileRepository) FetchUnreferenced(ctx context.Context, before time.Time, afterID string, limit int) ([]*model.File, error) {
+	var files []*model.File
+
+	query := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ? AND deleted_at IS NULL", model.FileStatusReady, before).
+		Where("NOT EXISTS (?)", r.db.Model(&model.User{}).Select("1").Where("users.avatar_id = files.id")).
+		Where("NOT EXISTS (?)", r.db.Model(&model.TodoAttachment{}).Select("1").Where("todo_attachments.file_id = files.id"))
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+
+	err := query.Order("id ASC").Limit(limit).Find(&files).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// FetchDeleted returns files soft-deleted before the given time along with
+// their variants, ordered by id after afterID.
+func (r *fileRepository) FetchDeleted(ctx context.Context, before time.Time, afterID string, limit int) ([]*model.File, error) {
+	var files []*model.File
+
+	query := r.db.WithContext(ctx).
+		Preload("Variants").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+
+	err := query.Order("id ASC").Limit(limit).Find(&files).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// FindObjectKeys reports which of the object keys belong to a file, a variant
+// or a shared blob, trashed files and unused blobs included.
+func (r *fileRepository) FindObjectKeys(ctx context.Context, objectKeys []string) (map[string]bool, error) {
+	var found []string
+
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT object_key FROM files WHERE object_key IN ?
+			UNION SELECT object_key FROM file_variants WHERE object_key IN ?
+			UNION SELECT object_key FROM file_blobs WHERE object_key IN ?`, objectKeys, objectKeys, objectKeys).
+		Scan(&found).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(found))
+	for _, objectKey := range found {
+		known[objectKey] = true
+	}
+
+	return known, nil
+}
+
+// Purge removes the row of a file for good, its variants cascade. The
+// references of file and its loaded variants on shared blobs are released,
+// and a file that wasn't trashed first is taken off the storage usage.
+func (r *fileRepository) Purge(ctx context.Context, file *model.File) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		live, err := lockLiveFile(tx, file.ID)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Delete(&model.File{}, "id = ?", file.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if live != nil {
+			if err := removeUsage(tx, live); err != nil {
+				return err
+			}
+		}
+
+		// keys that aren't a shared blob release nothing
+		if err := releaseBlob(tx, file.ObjectKey); err != nil {
+			return err
+		}
+		for _, variant := range file.Variants {
+			if err := releaseBlob(tx, variant.ObjectKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchUnscanned returns ready files created before the given time that
+// still wait for a malware scan, oldest first.
+func (r *fileRepository) FetchUnscanned(ctx context.Context, before time.Time, limit int) ([]*model.File, error) {
+	var files []*model.File
+
+	err := r.db.WithContext(ctx).
+		Where("scan_status = ? AND status = ? AND created_at < ? AND deleted_at IS NULL", model.ScanStatusPending, model.FileStatusReady, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&files).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// AttachBlob points file at the shared blob named by its object key, taking a
+// reference on it, and releases the object it pointed at before.
+func (r *fileRepository) AttachBlob(ctx context.Context, file *model.File, previousKey string) error {
+	return r.moveObject(ctx, file, previousKey, true)
+}
+
+// Quarantine saves file with the object it was moved to and releases the
+// shared blob it pointed at before.
+func (r *fileRepository) Quarantine(ctx context.Context, file *model.File, previousKey string) error {
+	return r.moveObject(ctx, file, previousKey, false)
+}
+
+func (r *fileRepository) moveObject(ctx context.Context, file *model.File, previousKey string, shared bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Variants").Save(file).Error; err != nil {
+			return err
+		}
+		if shared {
+			if err := acquireBlob(tx, fileBlob(file.ObjectKey, file.Sha256, file.Size, file.MimeType)); err != nil {
+				return err
+			}
+		}
+		return releaseBlob(tx, previousKey)
+	})
+}
+
+// FindBlobKeys reports which of the object keys are shared blobs.
+func (r *fileRepository) FindBlobKeys(ctx context.Context, objectKeys []string) (map[string]bool, error) {
+	var found []string
+
+	err := r.db.WithContext(ctx).
+		Model(&model.FileBlob{}).
+		Where("object_key IN ?", objectKeys).
+		Pluck("object_key", &found).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	blobs := make(map[string]bool, len(found))
+	for _, objectKey := range found {
+		blobs[objectKey] = true
+	}
+
+	return blobs, nil
+}
+
+// FetchUnusedBlobs returns blobs nothing points at since before the given
+// time, ordered by key after afterKey.
+func (r *fileRepository) FetchUnusedBlobs(ctx context.Context, before time.Time, afterKey string, limit int) ([]*model.FileBlob, error) {
+	var blobs []*model.FileBlob
+
+	query := r.db.WithContext(ctx).Where("ref_count <= 0 AND updated_at < ?", before)
+	if afterKey != "" {
+		query = query.Where("object_key > ?", afterKey)
+	}
+
+	err := query.Order("object_key ASC").Limit(limit).Find(&blobs).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// DeleteUnusedBlob deletes a blob that is still unused, calling deleteObject
+// while its row is locked. An upload taking a reference meanwhile waits for
+// the row to go and then stores the object again, so it never ends up
+// pointing at a deleted object. It reports whether the blob was deleted.
+func (r *fileRepository) DeleteUnusedBlob(ctx context.Context, objectKey string, deleteObject func(ctx context.Context) error) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var blob model.FileBlob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("object_key = ?", objectKey).
+			First(&blob).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if blob.RefCount > 0 {
+			return nil
+		}
+
+		if err := deleteObject(ctx); err != nil {
+			return err
+		}
+		deleted = true
+		return tx.Delete(&blob).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// fileBlobs are the shared blobs of file and its variants, those with a
+// content hash.
+func fileBlobs(file *model.File) []*model.FileBlob {
+	blobs := []*model.FileBlob{}
+	if file.Sha256 != "" {
+		blobs = append(blobs, fileBlob(file.ObjectKey, file.Sha256, file.Size, file.MimeType))
+	}
+	for _, variant := range file.Variants {
+		if variant.Sha256 != "" {
+			blobs = append(blobs, fileBlob(variant.ObjectKey, variant.Sha256, variant.Size, variant.MimeType))
+		}
+	}
+	return blobs
+}
+
+func fileBlob(objectKey string, sha256 string, size int64, mimeType string) *model.FileBlob {
+	return &model.FileBlob{
+		ObjectKey: objectKey,
+		Sha256:    sha256,
+		Size:      size,
+		MimeType:  mimeType,
+	}
+}
+
+// acquireBlob registers blob with a single reference, or adds one when it is
+// registered already.
+func acquireBlob(tx *gorm.DB, blob *model.FileBlob) error {
+	blob.RefCount = 1
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "object_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"ref_count":  gorm.Expr("file_blobs.ref_count + 1"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(blob).Error
+}
+
+func releaseBlob(tx *gorm.DB, objectKey string) error {
+	return tx.Model(&model.FileBlob{}).
+		Where("object_key = ?", objectKey).
+		Updates(map[string]interface{}{
+			"ref_count":  gorm.Expr("ref_count - 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// FetchUsage returns the storage usage of the user by mimetype.
+func (r *fileRepository) FetchUsage(ctx context.Context, userID string) ([]*model.StorageUsage, error) {
+	var usages []*model.StorageUsage
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND files > 0", userID).
+		Order("mime_type ASC").
+		Find(&usages).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return usages, nil
+}
+
+// RecomputeUsage rebuilds the storage usage of the user from their live
+// files and reports whether it had drifted.
+func (r *fileRepository) RecomputeUsage(ctx context.Context, userID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	drifted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUsage(tx, userID); err != nil {
+			return err
+		}
+
+		var stored []*model.StorageUsage
+		err := tx.Where("user_id = ? AND (files <> 0 OR bytes <> 0)", userID).Find(&stored).Error
+		if err != nil {
+			return err
+		}
+
+		var computed []*model.StorageUsage
+		err = tx.Model(&model.File{}).
+			Select("user_id, mime_type, SUM(size + COALESCE((?), 0)) AS bytes, COUNT(*) AS files",
+				tx.Model(&model.FileVariant{}).Select("SUM(size)").Where("file_variants.file_id = files.id")).
+			Where("user_id = ? AND deleted_at IS NULL", userID).
+			Group("user_id, mime_type").
+			Scan(&computed).Error
+		if err != nil {
+			return err
+		}
+
+		drifted = !sameUsage(stored, computed)
+		if !drifted {
+			return nil
+		}
+
+		err = tx.Where("user_id = ?", userID).Delete(&model.StorageUsage{}).Error
+		if err != nil || len(computed) == 0 {
+			return err
+		}
+		return tx.Create(&computed).Error
+	})
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+
+	return drifted, nil
+}
+
+// lockUsage serializes changes to the storage usage of a user until the
+// transaction ends.
+func lockUsage(tx *gorm.DB, userID string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "storage_usage:"+userID).Error
+}
+
+// lockLiveFile locks the usage of the owner of a file that isn't trashed,
+// then the file itself. It returns the owner, mimetype and stored size of the
+// file, or nil when it is trashed or gone.
+func lockLiveFile(tx *gorm.DB, fileID string) (*model.StorageUsage, error) {
+	var file model.File
+	err := tx.Select("user_id").Where("id = ? AND deleted_at IS NULL", fileID).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if file.UserID != nil {
+		if err := lockUsage(tx, *file.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Variants").
+		Where("id = ? AND deleted_at IS NULL", fileID).
+		First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || file.UserID == nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.StorageUsage{
+		UserID:   *file.UserID,
+		MimeType: file.MimeType,
+		Bytes:    storedSize(&file),
+		Files:    1,
+	}, nil
+}
+
+// storedSize is the size of file and its variants.
+func storedSize(file *model.File) int64 {
+	size := file.Size
+	for _, variant := range file.Variants {
+		size += variant.Size
+	}
+	return size
+}
+
+func addUsage(tx *gorm.DB, userID string, mimeType string, bytes int64, files int64) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "mime_type"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"bytes":      gorm.Expr("storage_usages.bytes + ?", bytes),
+			"files":      gorm.Expr("storage_usages.files + ?", files),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&model.StorageUsage{
+		UserID:   userID,
+		MimeType: mimeType,
+		Bytes:    bytes,
+		Files:    files,
+	}).Error
+}
+
+func removeUsage(tx *gorm.DB, usage *model.StorageUsage) error {
+	return addUsage(tx, usage.UserID, usage.MimeType, -usage.Bytes, -usage.Files)
+}
+
+func sameUsage(a []*model.StorageUsage, b []*model.StorageUsage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byMimeType := make(map[string]*model.StorageUsage, len(a))
+	for _, usage := range a {
+		byMimeType[usage.MimeType] = usage
+	}
+	for _, usage := range b {
+		other, ok := byMimeType[usage.MimeType]
+		if !ok || other.Bytes != usage.Bytes || other.Files != usage.Files {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+type IdempotencyKeyRepository interface {
+	Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, error)
+	Complete(ctx context.Context, record *model.IdempotencyKey) error
+	Release(ctx context.Context, record *model.IdempotencyKey) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Reserve stores record as pending unless a live record already holds the
+// same user and key, in which case that record is returned instead.
+func (r *idempotencyKeyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var existing *model.IdempotencyKey
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// an expired record frees the key
+		err := tx.Where("user_id = ? AND key = ? AND expires_at < ?", record.UserID, record.Key, time.Now()).
+			Delete(&model.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		existing = &model.IdempotencyKey{}
+		return tx.Where("user_id = ? AND key = ?", record.UserID, record.Key).First(existing).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, record *model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(record).
+		Select("status", "content_type", "body").
+		Updates(record).Error
+}
+
+func (r *idempotencyKeyRepository) Release(ctx context.Context, record *model.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ? AND status = 0", record.UserID, record.Key).
+		Delete(&model.IdempotencyKey{}).Error
+}
+
+func (r *idempotencyKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
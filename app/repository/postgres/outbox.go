@@ -0,0 +1,123 @@
+package postgresrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+type OutboxRepository interface {
+	TryLock(ctx context.Context) (unlock func(), locked bool, err error)
+	FetchDue(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	Update(ctx context.Context, event *model.OutboxEvent) error
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// createOutboxEvent stores an event in the outbox as part of tx, so it is
+// only relayed when the change that raised it commits.
+func createOutboxEvent(tx *gorm.DB, eventID string, aggregateType model.OutboxAggregate, aggregateID, userID, eventType string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&model.OutboxEvent{
+		EventID:        eventID,
+		AggregateType:  aggregateType,
+		AggregateID:    aggregateID,
+		UserID:         userID,
+		EventType:      eventType,
+		Payload:        payload,
+		NextAttemptAt:  time.Now(),
+		DeliveredSinks: model.StringList{},
+	}).Error
+}
+
+// TryLock takes a session level advisory lock so only one relay publishes at
+// a time, which is what keeps events of an aggregate in order across
+// replicas. The lock is held on a dedicated connection until unlock is
+// called, or until the connection dies with the process.
+func (r *outboxRepository) TryLock(ctx context.Context) (func(), bool, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext('outbox_relay'))").Scan(&locked)
+	if err != nil || !locked {
+		conn.Close()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext('outbox_relay'))"); err != nil {
+			logrus.Error(err)
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+// FetchDue returns unpublished events that are due in the order they were
+// written. An event waiting for a retry holds back the later events of its
+// aggregate, so they are never published ahead of it.
+func (r *outboxRepository) FetchDue(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`SELECT * FROM outbox_events o
+		WHERE o.published_at IS NULL AND o.failed_at IS NULL AND o.next_attempt_at <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox_events e
+			WHERE e.aggregate_type = o.aggregate_type AND e.aggregate_id = o.aggregate_id
+			AND e.id < o.id AND e.published_at IS NULL AND e.failed_at IS NULL AND e.next_attempt_at > ?
+		)
+		ORDER BY o.id
+		LIMIT ?`, now, now, limit).
+		Scan(&events).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *outboxRepository) Update(ctx context.Context, event *model.OutboxEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+// DeletePublishedBefore removes published events, events that failed for
+// good are kept for inspection.
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	result := r.db.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", before).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
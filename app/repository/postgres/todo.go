@@ -2,14 +2,27 @@ package postgresrepo
 
 import (
 	"context"
+	"errors"
 	"golang-gorm/domain/model"
 	"golang-gorm/helpers"
+	"sort"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrTodoVersionConflict = errors.New("todo was modified by another request")
+
+// ErrTodoIDTaken is returned by Create when a todo, of any user, already has
+// the id picked by the client.
+var ErrTodoIDTaken = errors.New("todo id is taken")
+
+// todoPurgeBatchSize is how many expired todos are purged per transaction.
+const todoPurgeBatchSize = 100
+
 type todoRepository struct {
 	db *gorm.DB
 }
@@ -25,6 +38,15 @@ type TodoRepository interface {
 	Create(ctx context.Context, todo *model.Todo) error
 	UpdateOne(ctx context.Context, todo *model.Todo) error
 	DeleteOne(ctx context.Context, todo *model.Todo) error
+	RestoreOne(ctx context.Context, todo *model.Todo) error
+	PurgeOne(ctx context.Context, todo *model.Todo) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	FindAdjacent(ctx context.Context, filters map[string]interface{}, position string, reverse bool) (*model.Todo, error)
+	FetchUserIDsWithLongPositions(ctx context.Context, maxLength int) ([]string, error)
+	RebalancePositions(ctx context.Context, userID string) error
+	Import(ctx context.Context, creates []*model.Todo, updates []*model.Todo) error
+	FetchChanges(ctx context.Context, userID string, sinceSeq int64, limit int) ([]*model.Todo, error)
+	FindSyncState(ctx context.Context, userID string) (*model.SyncState, error)
 }
 
 func (r *todoRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
@@ -34,6 +56,27 @@ func (r *todoRepository) queryFilter(query *gorm.DB, filters map[string]interfac
 	if userID, ok := filters["user_id"].(string); ok {
 		query = query.Where("user_id = ?", userID)
 	}
+	if externalID, ok := filters["external_id"].(string); ok {
+		query = query.Where("external_id = ?", externalID)
+	}
+	if externalIDs, ok := filters["external_ids"].([]string); ok {
+		query = query.Where("external_id IN ?", externalIDs)
+	}
+	if ids, ok := filters["ids"].([]string); ok {
+		query = query.Where("id IN ?", ids)
+	}
+	if notID, ok := filters["not_id"].(string); ok {
+		query = query.Where("id <> ?", notID)
+	}
+	if idRange, ok := filters["id_between"].([2]string); ok {
+		query = query.Where("id > ? AND id < ?", idRange[0], idRange[1])
+	}
+	if position, ok := filters["position"].(string); ok {
+		query = query.Where("position = ?", position)
+	}
+	if status, ok := filters["status"].(model.TodoStatus); ok {
+		query = query.Where("status = ?", status)
+	}
 
 	return query
 }
@@ -42,6 +85,7 @@ func (r *todoRepository) FetchList(ctx context.Context, offset, limit int, filte
 	var todos []*model.Todo
 
 	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Order("position ASC, id ASC").
 		Offset(offset).
 		Limit(limit).
 		Find(&todos).Error
@@ -82,19 +126,425 @@ func (r *todoRepository) Create(ctx context.Context, todo *model.Todo) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Create(todo).Error
+	if todo.Version == 0 {
+		todo.Version = 1
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := r.reserveSyncSeq(tx, todo.UserID, 1)
+		if err != nil {
+			return err
+		}
+		todo.SyncSeq = seq
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoNothing: true,
+		}).Create(todo)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTodoIDTaken
+		}
+
+		if err := r.createHistory(tx, todo, model.TodoHistoryEventCreated, nil); err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, todo, model.TodoEventCreated)
+	})
 }
 
+// UpdateOne writes the columns of todo that differ from the stored row if its
+// version is still the one stored, and bumps the version. Nothing is written
+// when no column changed. ErrTodoVersionConflict is returned when another
+// request updated it first.
 func (r *todoRepository) UpdateOne(ctx context.Context, todo *model.Todo) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Save(todo).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// lock current row to diff against
+		var current model.Todo
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", todo.ID).
+			First(&current).Error
+		if err != nil {
+			return err
+		}
+		if current.Version != todo.Version {
+			return ErrTodoVersionConflict
+		}
+
+		changes := helpers.DiffModel(current, todo)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		// only write changed columns, the version check keeps the update atomic
+		columns := make([]string, 0, len(changes)+2)
+		for column := range changes {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		columns = append(columns, "version", "sync_seq", "updated_at")
+
+		seq, err := r.reserveSyncSeq(tx, todo.UserID, 1)
+		if err != nil {
+			return err
+		}
+		todo.SyncSeq = seq
+
+		expectedVersion := todo.Version
+		todo.Version = expectedVersion + 1
+		result := tx.Model(todo).
+			Where("version = ?", expectedVersion).
+			Select(columns).
+			Updates(todo)
+		if result.Error == nil && result.RowsAffected == 0 {
+			result.Error = ErrTodoVersionConflict
+		}
+		if result.Error != nil {
+			todo.Version = expectedVersion
+			return result.Error
+		}
+
+		if err := r.createHistory(tx, todo, model.TodoHistoryEventFromChanges(changes), changes); err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, todo, model.TodoEventUpdated)
+	})
 }
 
 func (r *todoRepository) DeleteOne(ctx context.Context, todo *model.Todo) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Model(todo).UpdateColumn("deleted_at", time.Now()).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.updateVersioned(tx, todo, "deleted_at", time.Now()); err != nil {
+			return err
+		}
+
+		if err := r.createHistory(tx, todo, model.TodoHistoryEventDeleted, nil); err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, todo, model.TodoEventDeleted)
+	})
+}
+
+func (r *todoRepository) RestoreOne(ctx context.Context, todo *model.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.updateVersioned(tx, todo, "deleted_at", nil); err != nil {
+			return err
+		}
+
+		if err := r.createHistory(tx, todo, model.TodoHistoryEventRestored, nil); err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, todo, model.TodoEventRestored)
+	})
+}
+
+// updateVersioned updates a single column without touching updated_at, with
+// the same version check as UpdateOne.
+func (r *todoRepository) updateVersioned(tx *gorm.DB, todo *model.Todo, column string, value interface{}) error {
+	seq, err := r.reserveSyncSeq(tx, todo.UserID, 1)
+	if err != nil {
+		return err
+	}
+
+	result := tx.Model(&model.Todo{}).
+		Where("id = ? AND version = ?", todo.ID, todo.Version).
+		UpdateColumns(map[string]interface{}{
+			column:     value,
+			"version":  gorm.Expr("version + 1"),
+			"sync_seq": seq,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTodoVersionConflict
+	}
+
+	todo.Version++
+	todo.SyncSeq = seq
+	return nil
+}
+
+// reserveSyncSeq bumps the user's change sequence by n and returns the last
+// reserved value. The sync state row stays locked until the transaction ends,
+// so sequences become visible in the order they were handed out.
+func (r *todoRepository) reserveSyncSeq(tx *gorm.DB, userID string, n int) (int64, error) {
+	var seq int64
+	err := tx.Raw(`INSERT INTO sync_states (user_id, seq) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET seq = sync_states.seq + EXCLUDED.seq
+		RETURNING seq`, userID, n).
+		Scan(&seq).Error
+	return seq, err
+}
+
+// PurgeOne removes todo for good. Its attachments no other todo links go to
+// the trash with it.
+func (r *todoRepository) PurgeOne(ctx context.Context, todo *model.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.purge(tx, []*model.Todo{todo})
+	})
+}
+
+// PurgeDeletedBefore removes todos trashed before the given time for good, in
+// batches, the same way as PurgeOne. It returns how many were purged.
+func (r *todoRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		var todos []*model.Todo
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+				Order("deleted_at ASC").
+				Limit(todoPurgeBatchSize).
+				Find(&todos).Error
+			if err != nil || len(todos) == 0 {
+				return err
+			}
+
+			return r.purge(tx, todos)
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		purged += int64(len(todos))
+		if len(todos) < todoPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purge deletes todos, trashes the attachments only they link, makes clients
+// that haven't seen their tombstones resync and writes a purged event for
+// each.
+func (r *todoRepository) purge(tx *gorm.DB, todos []*model.Todo) error {
+	todoIDs := make([]string, len(todos))
+	for i, todo := range todos {
+		todoIDs[i] = todo.ID
+	}
+
+	// trash attachments before the links cascade away
+	var fileIDs []string
+	err := tx.Raw(`SELECT DISTINCT file_id FROM todo_attachments
+		WHERE todo_id IN ? AND NOT EXISTS (
+			SELECT 1 FROM todo_attachments AS other
+			WHERE other.file_id = todo_attachments.file_id AND other.todo_id NOT IN ?
+		)`, todoIDs, todoIDs).
+		Scan(&fileIDs).Error
+	if err != nil {
+		return err
+	}
+	for _, fileID := range fileIDs {
+		if err := trashFile(tx, fileID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Delete(&model.Todo{}, "id IN ?", todoIDs).Error; err != nil {
+		return err
+	}
+
+	for _, todo := range todos {
+		err := tx.Model(&model.SyncState{}).
+			Where("user_id = ?", todo.UserID).
+			UpdateColumn("purged_seq", gorm.Expr("GREATEST(purged_seq, ?)", todo.SyncSeq)).Error
+		if err != nil {
+			return err
+		}
+
+		if err := r.createEvent(tx, todo, model.TodoEventPurged); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindAdjacent returns the todo right after position in list order, or right
+// before it when reverse is true. An empty position starts from the edge of the list.
+func (r *todoRepository) FindAdjacent(ctx context.Context, filters map[string]interface{}, position string, reverse bool) (*model.Todo, error) {
+	var todo model.Todo
+
+	query := r.queryFilter(r.db.WithContext(ctx), filters)
+	if reverse {
+		if position != "" {
+			query = query.Where("position < ?", position)
+		}
+		query = query.Order("position DESC, id DESC")
+	} else {
+		if position != "" {
+			query = query.Where("position > ?", position)
+		}
+		query = query.Order("position ASC, id ASC")
+	}
+
+	err := query.Take(&todo).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &todo, nil
+}
+
+func (r *todoRepository) FetchUserIDsWithLongPositions(ctx context.Context, maxLength int) ([]string, error) {
+	var userIDs []string
+
+	err := r.db.WithContext(ctx).
+		Model(&model.Todo{}).
+		Distinct("user_id").
+		Where("length(position) > ?", maxLength).
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// RebalancePositions rewrites every position of the user with evenly spaced
+// ranks, keeping the current order. Trashed todos are included so restoring
+// them keeps their place.
+func (r *todoRepository) RebalancePositions(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		err := tx.Model(&model.Todo{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			Order("position ASC, id ASC").
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// every moved todo gets its own sequence so sync pages never split one
+		lastSeq, err := r.reserveSyncSeq(tx, userID, len(ids))
+		if err != nil {
+			return err
+		}
+		firstSeq := lastSeq - int64(len(ids)) + 1
+
+		// a moved todo is a changed todo, so its ETags change and stale
+		// copies fail the version check
+		now := time.Now()
+		for i, position := range helpers.RankSequence(len(ids)) {
+			err := tx.Model(&model.Todo{}).
+				Where("id = ?", ids[i]).
+				UpdateColumns(map[string]interface{}{
+					"position":   position,
+					"sync_seq":   firstSeq + int64(i),
+					"version":    gorm.Expr("version + 1"),
+					"updated_at": now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Import creates and updates todos in a single transaction so a failed
+// import leaves nothing behind.
+func (r *todoRepository) Import(ctx context.Context, creates []*model.Todo, updates []*model.Todo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepository := &todoRepository{db: tx}
+		for _, todo := range creates {
+			if err := txRepository.Create(ctx, todo); err != nil {
+				return err
+			}
+		}
+		for _, todo := range updates {
+			if err := txRepository.UpdateOne(ctx, todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchChanges returns the user's todos changed after sinceSeq in sequence
+// order, trashed todos included as tombstones.
+func (r *todoRepository) FetchChanges(ctx context.Context, userID string, sinceSeq int64, limit int) ([]*model.Todo, error) {
+	var todos []*model.Todo
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND sync_seq > ?", userID, sinceSeq).
+		Order("sync_seq ASC, id ASC").
+		Limit(limit).
+		Find(&todos).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// FindSyncState returns the user's change sequence, a user without todos
+// gets a zero state.
+func (r *todoRepository) FindSyncState(ctx context.Context, userID string) (*model.SyncState, error) {
+	state := model.SyncState{UserID: userID}
+
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Take(&state).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (r *todoRepository) createHistory(tx *gorm.DB, todo *model.Todo, event model.TodoHistoryEvent, changes model.Changes) error {
+	return tx.Create(&model.TodoHistory{
+		ID:      uuid.New().String(),
+		TodoID:  todo.ID,
+		UserID:  todo.UserID,
+		Event:   event,
+		Changes: changes,
+	}).Error
+}
+
+// createEvent writes the todo event to the outbox, the relay publishes it
+// once the transaction commits.
+func (r *todoRepository) createEvent(tx *gorm.DB, todo *model.Todo, eventType model.TodoEventType) error {
+	event := model.TodoEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     todo.UserID,
+		Todo:       *todo,
+		OccurredAt: time.Now(),
+	}
+	return createOutboxEvent(tx, event.ID, model.OutboxAggregateTodo, todo.ID, todo.UserID, string(eventType), event)
 }
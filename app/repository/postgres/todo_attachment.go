@@ -0,0 +1,64 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+
+	"gorm.io/gorm"
+)
+
+type todoAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewTodoAttachmentRepository(db *gorm.DB) TodoAttachmentRepository {
+	return &todoAttachmentRepository{db: db}
+}
+
+type TodoAttachmentRepository interface {
+	FindOne(ctx context.Context, filters map[string]interface{}) (*model.TodoAttachment, error)
+	Create(ctx context.Context, attachment *model.TodoAttachment) error
+	DeleteOne(ctx context.Context, attachment *model.TodoAttachment) error
+}
+
+func (r *todoAttachmentRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	// filters
+	if todoID, ok := filters["todo_id"].(string); ok {
+		query = query.Where("todo_id = ?", todoID)
+	}
+	if fileID, ok := filters["file_id"].(string); ok {
+		query = query.Where("file_id = ?", fileID)
+	}
+
+	return query
+}
+
+func (r *todoAttachmentRepository) FindOne(ctx context.Context, filters map[string]interface{}) (*model.TodoAttachment, error) {
+	var attachment model.TodoAttachment
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).First(&attachment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (r *todoAttachmentRepository) Create(ctx context.Context, attachment *model.TodoAttachment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *todoAttachmentRepository) DeleteOne(ctx context.Context, attachment *model.TodoAttachment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Where("todo_id = ? AND file_id = ?", attachment.TodoID, attachment.FileID).
+		Delete(&model.TodoAttachment{}).Error
+}
@@ -0,0 +1,61 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type todoHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewTodoHistoryRepository(db *gorm.DB) TodoHistoryRepository {
+	return &todoHistoryRepository{db: db}
+}
+
+type TodoHistoryRepository interface {
+	FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.TodoHistory, error)
+	Count(ctx context.Context, filters map[string]interface{}) (int64, error)
+}
+
+func (r *todoHistoryRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	// filters
+	if todoID, ok := filters["todo_id"].(string); ok {
+		query = query.Where("todo_id = ?", todoID)
+	}
+	if userID, ok := filters["user_id"].(string); ok {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	return query
+}
+
+func (r *todoHistoryRepository) FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.TodoHistory, error) {
+	var histories []*model.TodoHistory
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&histories).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (r *todoHistoryRepository) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	var count int64
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).Model(&model.TodoHistory{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
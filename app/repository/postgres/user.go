@@ -2,10 +2,12 @@ package postgresrepo
 
 import (
 	"context"
+	"time"
 
 	"golang-gorm/domain/model"
 	"golang-gorm/helpers"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -22,6 +24,7 @@ type UserRepository interface {
 	FindOne(ctx context.Context, filters map[string]interface{}) (*model.User, error)
 	Create(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, user *model.User) error
+	UpdateColumns(ctx context.Context, user *model.User, columns ...string) error
 }
 
 func (r *userRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
@@ -39,7 +42,8 @@ func (r *userRepository) FetchList(ctx context.Context, offset, limit int, filte
 	var users []*model.User
 
 	err := r.queryFilter(r.db.WithContext(ctx), filters).
-		Preload(string(model.UserRelationFile)).
+		Preload(string(model.UserRelationFileVariants)).
+		Order("id ASC").
 		Offset(offset).
 		Limit(limit).
 		Find(&users).Error
@@ -54,7 +58,7 @@ func (r *userRepository) FindOne(ctx context.Context, filters map[string]interfa
 	var user model.User
 	query := r.queryFilter(r.db.WithContext(ctx), filters)
 
-	err := query.Preload(string(model.UserRelationFile)).First(&user).Error
+	err := query.Preload(string(model.UserRelationFileVariants)).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -69,12 +73,54 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Create(user).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, user, model.UserEventRegistered)
+	})
 }
 
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Save(user).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, user, model.UserEventUpdated)
+	})
+}
+
+// UpdateColumns writes only the given columns of user, plus updated_at.
+func (r *userRepository) UpdateColumns(ctx context.Context, user *model.User, columns ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).
+			Select(append(columns, "updated_at")).
+			Updates(user).Error
+		if err != nil {
+			return err
+		}
+
+		return r.createEvent(tx, user, model.UserEventUpdated)
+	})
+}
+
+// createEvent writes the user event to the outbox, the relay publishes it
+// once the transaction commits.
+func (r *userRepository) createEvent(tx *gorm.DB, user *model.User, eventType model.UserEventType) error {
+	event := model.UserEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     user.ID,
+		User:       *user,
+		OccurredAt: time.Now(),
+	}
+	return createOutboxEvent(tx, event.ID, model.OutboxAggregateUser, user.ID, user.ID, string(eventType), event)
 }
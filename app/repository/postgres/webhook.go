@@ -0,0 +1,149 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+type WebhookRepository interface {
+	FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.Webhook, error)
+	Count(ctx context.Context, filters map[string]interface{}) (int64, error)
+	FindOne(ctx context.Context, filters map[string]interface{}) (*model.Webhook, error)
+	Create(ctx context.Context, webhook *model.Webhook) error
+	Update(ctx context.Context, webhook *model.Webhook) error
+	DeleteOne(ctx context.Context, webhook *model.Webhook) error
+	RecordSuccess(ctx context.Context, webhookID string) error
+	RecordFailure(ctx context.Context, webhookID string, disableAfter int) (*model.Webhook, error)
+}
+
+func (r *webhookRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	query = helpers.CommonFilter(query, filters)
+
+	// filters
+	if userID, ok := filters["user_id"].(string); ok {
+		query = query.Where("user_id = ?", userID)
+	}
+	if isActive, ok := filters["is_active"].(bool); ok {
+		query = query.Where("is_active = ?", isActive)
+	}
+	if eventType, ok := filters["event_type"].(string); ok {
+		query = query.Where("(event_types @> ? OR event_types @> ?)",
+			model.StringList{eventType}, model.StringList{model.WebhookEventAll})
+	}
+
+	return query
+}
+
+func (r *webhookRepository) FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&webhooks).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	var count int64
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).Model(&model.Webhook{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *webhookRepository) FindOne(ctx context.Context, filters map[string]interface{}) (*model.Webhook, error) {
+	var webhook model.Webhook
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).First(&webhook).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) Update(ctx context.Context, webhook *model.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *webhookRepository) DeleteOne(ctx context.Context, webhook *model.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(webhook).
+		UpdateColumn("deleted_at", time.Now()).Error
+}
+
+// RecordSuccess resets the consecutive failure count of the webhook.
+func (r *webhookRepository) RecordSuccess(ctx context.Context, webhookID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(&model.Webhook{}).
+		Where("id = ? AND failure_count <> 0", webhookID).
+		UpdateColumn("failure_count", 0).Error
+}
+
+// RecordFailure counts a failed delivery and disables the webhook once
+// disableAfter deliveries in a row have failed. The updated webhook is
+// returned so the caller can tell whether it was disabled.
+func (r *webhookRepository) RecordFailure(ctx context.Context, webhookID string, disableAfter int) (*model.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var webhook model.Webhook
+	err := r.db.WithContext(ctx).
+		Model(&webhook).
+		Clauses(clause.Returning{}).
+		Where("id = ?", webhookID).
+		UpdateColumns(map[string]interface{}{
+			"failure_count": gorm.Expr("failure_count + 1"),
+			"is_active":     gorm.Expr("is_active AND failure_count + 1 < ?", disableAfter),
+			"disabled_at":   gorm.Expr("CASE WHEN is_active AND failure_count + 1 >= ? THEN now() ELSE disabled_at END", disableAfter),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
@@ -0,0 +1,117 @@
+package postgresrepo
+
+import (
+	"context"
+	"golang-gorm/domain/model"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+type WebhookDeliveryRepository interface {
+	FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.WebhookDelivery, error)
+	Count(ctx context.Context, filters map[string]interface{}) (int64, error)
+	Create(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	Update(ctx context.Context, delivery *model.WebhookDelivery) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+}
+
+func (r *webhookDeliveryRepository) queryFilter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	// filters
+	if webhookID, ok := filters["webhook_id"].(string); ok {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if status, ok := filters["status"].(model.WebhookDeliveryStatus); ok {
+		query = query.Where("status = ?", status)
+	}
+
+	return query
+}
+
+func (r *webhookDeliveryRepository) FetchList(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	var count int64
+
+	err := r.queryFilter(r.db.WithContext(ctx), filters).Model(&model.WebhookDelivery{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	// events are relayed at least once, a delivery already queued for the
+	// event is kept as is
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "webhook_id"}, {Name: "event_id"}},
+			DoNothing: true,
+		}).
+		Create(deliveries).Error
+}
+
+func (r *webhookDeliveryRepository) Update(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+// ClaimDue picks pending deliveries whose next attempt is due and pushes
+// their next attempt out by lease, so concurrent workers don't pick the same
+// delivery. A worker that dies mid attempt leaves it to be retried after the lease.
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var deliveries []*model.WebhookDelivery
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), model.WebhookDeliveryPending, now, limit).
+		Scan(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
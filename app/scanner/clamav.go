@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamavChunkSize is the size of each INSTREAM chunk, well below the
+// StreamMaxLength clamd allows by default.
+const clamavChunkSize = 64 * 1024
+
+type clamavScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner scans with clamd listening on network ("tcp" or "unix")
+// at address. Each scan uses its own connection and the INSTREAM command,
+// so clamd doesn't need access to the files. timeout bounds a whole scan.
+func NewClamAVScanner(network string, address string, timeout time.Duration) (FileScanner, error) {
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("clamd network %q is not tcp or unix", network)
+	}
+	if address == "" {
+		return nil, errors.New("clamd address is not set")
+	}
+
+	return &clamavScanner{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}, nil
+}
+
+func (s *clamavScanner) Enabled() bool {
+	return true
+}
+
+// Scan streams body to clamd in length prefixed chunks, ended by an empty
+// chunk, and parses the single reply line.
+func (s *clamavScanner) Scan(ctx context.Context, body io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// clamd stops reading once the stream is too large and answers right
+	// away, so the reply is read even when sending failed
+	sendErr := s.send(conn, body)
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if sendErr != nil {
+			return nil, fmt.Errorf("send to clamd: %w", sendErr)
+		}
+		return nil, fmt.Errorf("read clamd reply: %w", err)
+	}
+
+	result, err := parseClamAVReply(reply)
+	if err != nil {
+		return nil, err
+	}
+	if sendErr != nil && !result.Infected {
+		return nil, fmt.Errorf("send to clamd: %w", sendErr)
+	}
+	return result, nil
+}
+
+func (s *clamavScanner) send(conn net.Conn, body io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 4+clamavChunkSize)
+	for {
+		n, readErr := io.ReadFull(body, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk[:4], uint32(n))
+			if _, err := conn.Write(chunk[:4+n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamAVReply reads "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR".
+func parseClamAVReply(reply string) (*Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case verdict == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{
+			Infected:  true,
+			Signature: strings.TrimSuffix(verdict, " FOUND"),
+		}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("clamd replied %q", reply)
+	}
+}
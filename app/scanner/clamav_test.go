@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd answers INSTREAM scans like clamd on a local port. It reads the
+// length prefixed chunks until the empty one and replies with reply, or
+// never replies when reply is empty. Once more than maxStream bytes arrived,
+// 0 for no limit, it replies with the size limit error right away and
+// discards the rest of the stream. Every streamed content is sent on the
+// returned channel.
+func fakeClamd(t *testing.T, reply string, maxStream int) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				received <- serveClamd(conn, reply, maxStream)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func serveClamd(conn net.Conn, reply string, maxStream int) []byte {
+	command := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return nil
+	}
+
+	content := []byte{}
+	size := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return content
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(conn, chunk); err != nil {
+			return content
+		}
+		content = append(content, chunk...)
+
+		if maxStream > 0 && len(content) > maxStream {
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			io.Copy(io.Discard, conn)
+			return content
+		}
+	}
+
+	if reply == "" {
+		io.Copy(io.Discard, conn)
+		return content
+	}
+	conn.Write([]byte(reply + "\x00"))
+	return content
+}
+
+func TestClamAVScan(t *testing.T) {
+	// more than two chunks, the last one short
+	body := bytes.Repeat([]byte("scan me "), clamavChunkSize/3)
+
+	tests := []struct {
+		name      string
+		reply     string
+		infected  bool
+		signature string
+		err       string
+	}{
+		{"clean", "stream: OK", false, "", ""},
+		{"infected", "stream: Eicar-Test-Signature FOUND", true, "Eicar-Test-Signature", ""},
+		{"error", "stream: Can't allocate memory ERROR", false, "", "Can't allocate memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, received := fakeClamd(t, tt.reply, 0)
+			scanner, err := NewClamAVScanner("tcp", address, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := scanner.Scan(context.Background(), bytes.NewReader(body))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Infected != tt.infected || result.Signature != tt.signature {
+					t.Errorf("got %+v, want infected %v with %q", result, tt.infected, tt.signature)
+				}
+			}
+
+			if content := <-received; !bytes.Equal(content, body) {
+				t.Errorf("clamd received %d bytes, want the %d sent", len(content), len(body))
+			}
+		})
+	}
+}
+
+func TestClamAVScanSizeLimit(t *testing.T) {
+	address, received := fakeClamd(t, "stream: OK", clamavChunkSize)
+	scanner, err := NewClamAVScanner("tcp", address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the reply comes after the second chunk, while more are being sent
+	body := bytes.Repeat([]byte{'x'}, 8*clamavChunkSize)
+	_, err = scanner.Scan(context.Background(), bytes.NewReader(body))
+	if err == nil || !strings.Contains(err.Error(), "size limit exceeded") {
+		t.Fatalf("got error %v, want the size limit", err)
+	}
+
+	if content := <-received; len(content) >= len(body) {
+		t.Errorf("clamd read all %d bytes before replying", len(content))
+	}
+}
+
+func TestClamAVScanTimeout(t *testing.T) {
+	address, _ := fakeClamd(t, "", 0)
+	scanner, err := NewClamAVScanner("tcp", address, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = scanner.Scan(context.Background(), strings.NewReader("never answered"))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got error %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("scan took %v despite its timeout", elapsed)
+	}
+}
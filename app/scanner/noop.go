@@ -0,0 +1,21 @@
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+type noopScanner struct{}
+
+// NewNoopScanner reports every file as clean without reading it.
+func NewNoopScanner() FileScanner {
+	return noopScanner{}
+}
+
+func (noopScanner) Scan(ctx context.Context, body io.Reader) (*Result, error) {
+	return &Result{}, nil
+}
+
+func (noopScanner) Enabled() bool {
+	return false
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FileScanner looks for malware in file contents.
+type FileScanner interface {
+	// Scan reads body and reports whether it is infected. An error means
+	// the content could not be scanned, not that it is infected.
+	Scan(ctx context.Context, body io.Reader) (*Result, error)
+	// Enabled is false for scanners that don't actually scan. Files are
+	// served without waiting for a scan then.
+	Enabled() bool
+}
+
+// Result is the verdict of a scan, Signature names what was found.
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// eicar is the standard antivirus test file, every scanner reports it and
+// it is harmless.
+var eicar = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+
+// Check scans a clean file and the EICAR test file and fails unless the
+// scanner tells them apart.
+func Check(ctx context.Context, s FileScanner) error {
+	result, err := s.Scan(ctx, bytes.NewReader([]byte("clean file")))
+	if err != nil {
+		return fmt.Errorf("scan clean file: %w", err)
+	}
+	if result.Infected {
+		return fmt.Errorf("clean file reported as %s", result.Signature)
+	}
+
+	result, err = s.Scan(ctx, bytes.NewReader(eicar))
+	if err != nil {
+		return fmt.Errorf("scan EICAR test file: %w", err)
+	}
+	if !result.Infected {
+		return errors.New("EICAR test file reported as clean")
+	}
+	return nil
+}
@@ -1,18 +1,27 @@
 package usecase
 
 import (
+	"golang-gorm/app/pubsub"
+	blobrepo "golang-gorm/app/repository/blob"
 	postgresrepo "golang-gorm/app/repository/postgres"
-	s3repo "golang-gorm/app/repository/s3"
+	"golang-gorm/app/scanner"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
 type UsecaseDependency struct {
-	Validate       *validator.Validate
-	Timeout        time.Duration
-	S3Repository   s3repo.S3Repo
-	UserRepository postgresrepo.UserRepository
-	TodoRepository postgresrepo.TodoRepository
-	FileRepository postgresrepo.FileRepository
+	Validate                  *validator.Validate
+	Timeout                   time.Duration
+	BlobStore                 blobrepo.BlobStore
+	FileScanner               scanner.FileScanner
+	UserRepository            postgresrepo.UserRepository
+	TodoRepository            postgresrepo.TodoRepository
+	FileRepository            postgresrepo.FileRepository
+	TodoAttachmentRepository  postgresrepo.TodoAttachmentRepository
+	TodoHistoryRepository     postgresrepo.TodoHistoryRepository
+	WebhookRepository         postgresrepo.WebhookRepository
+	WebhookDeliveryRepository postgresrepo.WebhookDeliveryRepository
+	APIKeyRepository          postgresrepo.APIKeyRepository
+	PubSub                    pubsub.PubSub
 }
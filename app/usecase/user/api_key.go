@@ -0,0 +1,252 @@
+package usecase_user
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const maxAPIKeysPerUser = 20
+
+type apiKeyUsecase struct {
+	apiKeyRepository postgresrepo.APIKeyRepository
+	contextTimeout   time.Duration
+	validate         *validator.Validate
+}
+
+func NewAPIKeyUsecase(d usecase.UsecaseDependency) APIKeyUsecase {
+	return &apiKeyUsecase{
+		apiKeyRepository: d.APIKeyRepository,
+		contextTimeout:   d.Timeout,
+		validate:         d.Validate,
+	}
+}
+
+type APIKeyUsecase interface {
+	GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse
+	Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateAPIKeyRequest) helpers.Response
+	DeleteOne(ctx context.Context, claim model.JWTClaimUser, apiKeyID string) helpers.Response
+	Verify(ctx context.Context, key string) helpers.Response
+}
+
+// APIKeyWithKey is only returned on creation, the key is not shown again.
+type APIKeyWithKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+func (u *apiKeyUsecase) GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"user_id": claim.UserID,
+	}
+
+	// count first
+	totalData, err := u.apiKeyRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count api key",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	apiKeys, err := u.apiKeyRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch api key",
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    apiKeys,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+func (u *apiKeyUsecase) Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateAPIKeyRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// check api key limit
+	total, err := u.apiKeyRepository.Count(ctx, map[string]interface{}{
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if total >= maxAPIKeysPerUser {
+		return helpers.Response{
+			Data:    nil,
+			Message: "api key limit reached",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	key, err := helpers.GenerateAPIKey()
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// save api key
+	apiKey := model.APIKey{
+		ID:      uuid.New().String(),
+		UserID:  claim.UserID,
+		Name:    payload.Name,
+		Prefix:  helpers.APIKeyDisplayPrefix(key),
+		KeyHash: helpers.HashAPIKey(key),
+	}
+	err = u.apiKeyRepository.Create(ctx, &apiKey)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data: APIKeyWithKey{
+			APIKey: &apiKey,
+			Key:    key,
+		},
+		Message: "success",
+		Status:  http.StatusCreated,
+	}
+}
+
+func (u *apiKeyUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, apiKeyID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check api key exist
+	apiKey, err := u.apiKeyRepository.FindOne(ctx, map[string]interface{}{
+		"id":      apiKeyID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if apiKey == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "api key not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// delete api key
+	err = u.apiKeyRepository.DeleteOne(ctx, apiKey)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    nil,
+		Message: "api key successfully deleted",
+		Status:  http.StatusOK,
+	}
+}
+
+// Verify looks up an api key and returns the user it belongs to. Keys are
+// random, so unlike passwords a plain hash is enough to store them.
+func (u *apiKeyUsecase) Verify(ctx context.Context, key string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	if !helpers.IsAPIKey(key) {
+		return helpers.Response{
+			Data:    nil,
+			Message: "invalid api key",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	apiKey, err := u.apiKeyRepository.FindOne(ctx, map[string]interface{}{
+		"key_hash": helpers.HashAPIKey(key),
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if apiKey == nil || apiKey.User == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "invalid api key",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	// last use is informational, don't fail the request over it
+	if err := u.apiKeyRepository.Touch(ctx, apiKey); err != nil {
+		logrus.Warnf("touch api key %s: %v", apiKey.ID, err)
+	}
+
+	return helpers.Response{
+		Data:    apiKey.User,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"time"
 
+	blobrepo "golang-gorm/app/repository/blob"
 	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/scanner"
 	"golang-gorm/app/usecase"
 	"golang-gorm/domain/model"
 	"golang-gorm/domain/request"
@@ -19,6 +21,9 @@ import (
 
 type authUsecase struct {
 	userRepository postgresrepo.UserRepository
+	blobStore      blobrepo.BlobStore
+	fileScanner    scanner.FileScanner
+	credentials    *credentialCache
 	contextTimeout time.Duration
 	validate       *validator.Validate
 }
@@ -26,6 +31,9 @@ type authUsecase struct {
 func NewAuthUsecase(d usecase.UsecaseDependency) AuthUsecase {
 	return &authUsecase{
 		userRepository: d.UserRepository,
+		blobStore:      d.BlobStore,
+		fileScanner:    d.FileScanner,
+		credentials:    newCredentialCache(credentialCacheTTL, credentialCacheSize),
 		contextTimeout: d.Timeout,
 		validate:       d.Validate,
 	}
@@ -35,6 +43,7 @@ type AuthUsecase interface {
 	Register(ctx context.Context, payload request.RegisterRequest) helpers.Response
 	Login(ctx context.Context, payload request.LoginRequest) helpers.Response
 	GetProfile(ctx context.Context, claim model.JWTClaimUser) helpers.Response
+	VerifyCredentials(ctx context.Context, payload request.LoginRequest) helpers.Response
 }
 
 func (u *authUsecase) Register(ctx context.Context, payload request.RegisterRequest) helpers.Response {
@@ -63,6 +72,7 @@ func (u *authUsecase) Register(ctx context.Context, payload request.RegisterRequ
 			Data:    nil,
 			Message: "email already exist",
 			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrAlreadyExists,
 		}
 	}
 
@@ -109,34 +119,12 @@ func (u *authUsecase) Login(ctx context.Context, payload request.LoginRequest) h
 		return validationResponse
 	}
 
-	// check user exist
-	user, err := u.userRepository.FindOne(ctx, map[string]interface{}{
-		"email": payload.Email,
-	})
-	if err != nil {
-		return helpers.Response{
-			Data:    nil,
-			Message: err.Error(),
-			Status:  http.StatusInternalServerError,
-		}
-	}
-	if user == nil {
-		return helpers.Response{
-			Data:    nil,
-			Message: "email not found",
-			Status:  http.StatusBadRequest,
-		}
-	}
-
-	// check password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
-	if err != nil {
-		return helpers.Response{
-			Data:    nil,
-			Message: "wrong password",
-			Status:  http.StatusBadRequest,
-		}
+	// check credentials
+	response := u.VerifyCredentials(ctx, payload)
+	if response.Status != http.StatusOK {
+		return response
 	}
+	user := response.Data.(*model.User)
 
 	// generate token
 	token, err := helpers.GenerateJWTTokenUser(model.JWTClaimUser{
@@ -158,6 +146,16 @@ func (u *authUsecase) Login(ctx context.Context, payload request.LoginRequest) h
 		}
 	}
 
+	// sign avatar url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, user.Avatar)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
 	return helpers.Response{
 		Data: map[string]interface{}{
 			"token": token,
@@ -188,7 +186,64 @@ func (u *authUsecase) GetProfile(ctx context.Context, claim model.JWTClaimUser)
 			Data:    nil,
 			Message: "user not found",
 			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// sign avatar url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, user.Avatar)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    user,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+// VerifyCredentials checks an email and password pair and returns the user,
+// used by login and by clients authenticating with basic auth.
+func (u *authUsecase) VerifyCredentials(ctx context.Context, payload request.LoginRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check user exist
+	user, err := u.userRepository.FindOne(ctx, map[string]interface{}{
+		"email": payload.Email,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if user == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "email not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// check password, bcrypt is skipped for pairs verified moments ago
+	if !u.credentials.verified(payload.Email, payload.Password, user.Password) {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: "wrong password",
+				Status:  http.StatusBadRequest,
+			}
 		}
+		u.credentials.add(payload.Email, payload.Password, user.Password)
 	}
 
 	return helpers.Response{
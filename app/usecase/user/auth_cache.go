@@ -0,0 +1,93 @@
+package usecase_user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	credentialCacheTTL  = 5 * time.Minute
+	credentialCacheSize = 1024
+)
+
+// credentialCache remembers recently verified email and password pairs, so
+// clients using basic auth on every request don't pay for bcrypt each time.
+// An entry only matches while the user's stored password hash is the one it
+// was verified against, so changing the password drops it.
+type credentialCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]credentialCacheEntry
+}
+
+type credentialCacheEntry struct {
+	passwordHash string
+	expiresAt    time.Time
+}
+
+func newCredentialCache(ttl time.Duration, size int) *credentialCache {
+	return &credentialCache{
+		ttl:     ttl,
+		size:    size,
+		entries: map[string]credentialCacheEntry{},
+	}
+}
+
+func credentialCacheKey(email, password string) string {
+	sum := sha256.Sum256([]byte(email + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// verified reports whether email and password were verified against
+// passwordHash within the ttl.
+func (c *credentialCache) verified(email, password, passwordHash string) bool {
+	key := credentialCacheKey(email, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if entry.passwordHash != passwordHash || time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// add remembers a verified pair. When the cache is full, expired entries
+// are dropped first, then the ones closest to expiring.
+func (c *credentialCache) add(email, password, passwordHash string) {
+	key := credentialCacheKey(email, password)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		for len(c.entries) >= c.size {
+			oldest := ""
+			for k, entry := range c.entries {
+				if oldest == "" || entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+
+	c.entries[key] = credentialCacheEntry{
+		passwordHash: passwordHash,
+		expiresAt:    now.Add(c.ttl),
+	}
+}
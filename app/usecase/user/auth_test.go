@@ -0,0 +1,106 @@
+package usecase_user
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeUserRepository struct {
+	postgresrepo.UserRepository
+
+	user *model.User
+}
+
+func (f *fakeUserRepository) FindOne(ctx context.Context, filters map[string]interface{}) (*model.User, error) {
+	if email, ok := filters["email"].(string); ok && email == f.user.Email {
+		copied := *f.user
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(hash)
+}
+
+func TestVerifyCredentialsForgetsChangedPassword(t *testing.T) {
+	users := &fakeUserRepository{user: &model.User{
+		ID:       "user",
+		Email:    "alice@example.com",
+		Password: hashPassword(t, "old"),
+	}}
+	u := NewAuthUsecase(usecase.UsecaseDependency{
+		UserRepository: users,
+		Validate:       validator.New(),
+		Timeout:        time.Second,
+	})
+
+	verify := func(password string) int {
+		return u.VerifyCredentials(context.Background(), request.LoginRequest{
+			Email:    "alice@example.com",
+			Password: password,
+		}).Status
+	}
+
+	if status := verify("old"); status != http.StatusOK {
+		t.Fatalf("old password: got %d", status)
+	}
+	// now cached
+	if status := verify("old"); status != http.StatusOK {
+		t.Fatalf("cached old password: got %d", status)
+	}
+
+	users.user.Password = hashPassword(t, "new")
+
+	if status := verify("old"); status == http.StatusOK {
+		t.Fatal("old password still accepted after the change")
+	}
+	if status := verify("new"); status != http.StatusOK {
+		t.Fatalf("new password: got %d", status)
+	}
+}
+
+func TestCredentialCacheIsBounded(t *testing.T) {
+	cache := newCredentialCache(time.Minute, 3)
+
+	for i := 0; i < 10; i++ {
+		cache.add(fmt.Sprintf("user%d@example.com", i), "password", "hash")
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(cache.entries) != 3 {
+		t.Fatalf("cache holds %d entries, want 3", len(cache.entries))
+	}
+	if cache.verified("user0@example.com", "password", "hash") {
+		t.Error("oldest entry was kept")
+	}
+	if !cache.verified("user9@example.com", "password", "hash") {
+		t.Error("newest entry was evicted")
+	}
+}
+
+func TestCredentialCacheExpires(t *testing.T) {
+	cache := newCredentialCache(-time.Second, 3)
+
+	cache.add("alice@example.com", "password", "hash")
+	if cache.verified("alice@example.com", "password", "hash") {
+		t.Error("expired entry still matches")
+	}
+}
@@ -0,0 +1,434 @@
+package usecase_user
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	blobrepo "golang-gorm/app/repository/blob"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/scanner"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var (
+	errMimeTypeNotAllowed   = errors.New("mimetype not allowed")
+	errStorageQuotaExceeded = errors.New("storage quota exceeded")
+	errFileExceedsQuota     = errors.New("file is larger than the storage quota")
+	errFileTooLarge         = errors.New("file too large")
+	errFileEmpty            = errors.New("file is empty")
+	errUploadMissing        = errors.New("file has not been uploaded yet")
+	errUploadMismatch       = errors.New("uploaded file does not match")
+)
+
+// fileUpload describes a single file going through the upload pipeline.
+// MimeType is what the client claimed, the stored type is sniffed from Body.
+type fileUpload struct {
+	UserID     string
+	Folder     string
+	Name       string
+	MimeType   string
+	Body       io.Reader
+	Size       int64  // -1 when the client didn't tell
+	Sha256     string // claimed hex checksum of a presigned upload
+	Categories []string
+	MaxSize    int64
+}
+
+// fileUploader is the shared upload pipeline used by every usecase that
+// stores files: validation, quota check, upload to the blob store, the files
+// row and the malware scan.
+type fileUploader struct {
+	fileRepository postgresrepo.FileRepository
+	userRepository postgresrepo.UserRepository
+	blobStore      blobrepo.BlobStore
+	fileScanner    scanner.FileScanner
+	storageQuotas  storageQuotas
+	dedupGlobally  bool
+}
+
+// newFileUploader panics when a repository is missing, so a usecase wired
+// without one fails at startup instead of on its first upload.
+func newFileUploader(fileRepository postgresrepo.FileRepository, userRepository postgresrepo.UserRepository, blobStore blobrepo.BlobStore, fileScanner scanner.FileScanner) *fileUploader {
+	if fileRepository == nil || userRepository == nil {
+		panic("file uploader needs a file and a user repository")
+	}
+
+	return &fileUploader{
+		fileRepository: fileRepository,
+		userRepository: userRepository,
+		blobStore:      blobStore,
+		fileScanner:    fileScanner,
+		storageQuotas:  newStorageQuotas(),
+		dedupGlobally:  viper.GetString("FILE_DEDUP_SCOPE") == "global",
+	}
+}
+
+func (f *fileUploader) upload(ctx context.Context, in fileUpload) (*model.File, error) {
+	// validate declared size before reading anything
+	if in.MaxSize > 0 && in.Size > in.MaxSize {
+		return nil, f.tooLarge(in.MaxSize)
+	}
+
+	// check storage quota
+	used, quota, err := f.checkQuota(ctx, in.UserID, in.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// sniff mimetype from the content
+	head := make([]byte, helpers.SniffLength)
+	n, err := io.ReadFull(in.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errFileEmpty
+	}
+	head = head[:n]
+	mimeType := helpers.SniffMimeType(head, in.MimeType)
+
+	// set objectName
+	newFile, err := f.newFile(in, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	// stream to the blob store under the name of this file, size and quota
+	// are enforced and the content hashed while reading
+	body := &limitedReader{
+		reader:    io.MultiReader(bytes.NewReader(head), in.Body),
+		remaining: quota - used,
+		err:       errStorageQuotaExceeded,
+	}
+	if in.MaxSize > 0 && in.MaxSize < body.remaining {
+		body.remaining = in.MaxSize
+		body.err = f.tooLarge(in.MaxSize)
+	}
+	hash := sha256.New()
+	err = f.blobStore.UploadFile(ctx, newFile.ObjectKey, io.TeeReader(body, hash), mimeType)
+	if body.exceeded {
+		return nil, body.err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// move to the object named by the content, unless it is stored already
+	uploadName := newFile.ObjectKey
+	defer f.deleteObjects(ctx, []string{uploadName})
+
+	newFile.Sha256 = hex.EncodeToString(hash.Sum(nil))
+	newFile.ObjectKey = f.blobKey(in.UserID, newFile.Sha256)
+	err = f.ensureBlob(ctx, newFile.ObjectKey, uploadName)
+	if err != nil {
+		return nil, err
+	}
+
+	// save to database, which takes a reference on the blob and counts the
+	// file towards the quota
+	newFile.Size = body.read
+	newFile.Status = model.FileStatusReady
+
+	err = f.fileRepository.Create(ctx, newFile, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	// the unused blob may have been swept before the reference was taken
+	err = f.ensureBlob(ctx, newFile.ObjectKey, uploadName)
+	if err != nil {
+		return nil, err
+	}
+
+	// scan for malware
+	err = f.scanUploaded(ctx, newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile, nil
+}
+
+// reserve creates a pending files row and presigns the upload of its object,
+// the client then puts the file straight into the bucket and calls complete.
+// Nothing is read here, so the claimed mimetype and size are checked instead
+// and the store holds the upload to the claimed checksum.
+func (f *fileUploader) reserve(ctx context.Context, in fileUpload) (*model.File, *blobrepo.PresignedRequest, error) {
+	// validate declared size
+	if in.Size <= 0 {
+		return nil, nil, errFileEmpty
+	}
+	if in.MaxSize > 0 && in.Size > in.MaxSize {
+		return nil, nil, f.tooLarge(in.MaxSize)
+	}
+
+	// check storage quota, pending uploads count towards it until swept
+	_, quota, err := f.checkQuota(ctx, in.UserID, in.Size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// set objectName
+	newFile, err := f.newFile(in, in.MimeType)
+	if err != nil {
+		return nil, nil, err
+	}
+	newFile.Size = in.Size
+	newFile.Status = model.FileStatusPending
+
+	// presign upload
+	upload, err := f.blobStore.PresignUpload(ctx, newFile.ObjectKey, newFile.MimeType, newFile.Size, in.Sha256)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// save to database
+	err = f.fileRepository.Create(ctx, newFile, quota)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newFile, upload, nil
+}
+
+// complete checks that the object of a pending file is in the bucket with
+// the reserved size and mimetype, both claimed and sniffed from its content,
+// marks the file ready and scans it. The object isn't downloaded, its hash is
+// the checksum the store verified the upload against.
+func (f *fileUploader) complete(ctx context.Context, file *model.File) error {
+	if file.Status != model.FileStatusPending {
+		return f.scanUploaded(ctx, file)
+	}
+
+	// check object uploaded
+	info, err := f.blobStore.StatFile(ctx, file.ObjectKey)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return errUploadMissing
+	}
+	if info.Size != file.Size {
+		return fmt.Errorf("%w, expected %d bytes but got %d", errUploadMismatch, file.Size, info.Size)
+	}
+	if info.ContentType != file.MimeType {
+		return fmt.Errorf("%w, expected %s but got %s", errUploadMismatch, file.MimeType, info.ContentType)
+	}
+
+	// sniff mimetype from the first bytes, the content type of the object is
+	// only what the client claimed
+	head, err := f.blobStore.ReadFileHead(ctx, file.ObjectKey, helpers.SniffLength)
+	if err != nil {
+		return err
+	}
+	if mimeType := helpers.SniffMimeType(head, file.MimeType); mimeType != file.MimeType {
+		return fmt.Errorf("%w, expected %s but the content is %s", errUploadMismatch, file.MimeType, mimeType)
+	}
+
+	// checksum verified by the store on upload
+	sum := info.Sha256
+	if sum == "" {
+		return fmt.Errorf("%w, it was uploaded without a checksum", errUploadMismatch)
+	}
+
+	// move to the object named by the content, unless it is stored already
+	uploadName := file.ObjectKey
+	userID := ""
+	if file.UserID != nil {
+		userID = *file.UserID
+	}
+	file.Sha256 = sum
+	file.ObjectKey = f.blobKey(userID, sum)
+	err = f.ensureBlob(ctx, file.ObjectKey, uploadName)
+	if err != nil {
+		return err
+	}
+
+	// mark file ready, pointing at the blob
+	file.Status = model.FileStatusReady
+	err = f.fileRepository.AttachBlob(ctx, file, uploadName)
+	if err != nil {
+		return err
+	}
+
+	// the unused blob may have been swept before the reference was taken
+	err = f.ensureBlob(ctx, file.ObjectKey, uploadName)
+	if err != nil {
+		return err
+	}
+	f.deleteObjects(ctx, []string{uploadName})
+
+	// scan for malware
+	return f.scanUploaded(ctx, file)
+}
+
+// blobKey names the object of content with the given hash. Unless content is
+// deduplicated globally, each user has their own copy.
+func (f *fileUploader) blobKey(userID string, sum string) string {
+	if f.dedupGlobally {
+		return fmt.Sprintf("blobs/%s/%s", sum[:2], sum)
+	}
+	return fmt.Sprintf("blobs/%s/%s/%s", userID, sum[:2], sum)
+}
+
+// ensureBlob copies the upload at sourceName to the blob objectName, unless
+// the same content is stored there already.
+func (f *fileUploader) ensureBlob(ctx context.Context, objectName string, sourceName string) error {
+	info, err := f.blobStore.StatFile(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		return nil
+	}
+	return f.blobStore.CopyFile(ctx, sourceName, objectName)
+}
+
+// newFile validates mimeType against the allowed categories and names the
+// file and its object.
+func (f *fileUploader) newFile(in fileUpload, mimeType string) (*model.File, error) {
+	// validate mimetype
+	allowed := false
+	for _, category := range in.Categories {
+		if helpers.IsMimeTypeAllowed(mimeType, category) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, errMimeTypeNotAllowed
+	}
+
+	// get extension
+	ext, err := helpers.GetExtensionFromMimeType(mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New().String()
+	fileName := fmt.Sprintf("%s_%s.%s", time.Now().Format("20060102"), in.Name, ext)
+	year, month, _ := time.Now().Date()
+	objectName := fmt.Sprintf("%s/%d/%s/%s_%s", in.Folder, year, month, fileID, fileName)
+
+	return &model.File{
+		ID:         fileID,
+		UserID:     &in.UserID,
+		Name:       fileName,
+		MimeType:   mimeType,
+		ObjectKey:  objectName,
+		ScanStatus: model.ScanStatusPending,
+	}, nil
+}
+
+// presignFiles fills in the download url of files, nil files and files not
+// cleared by the malware scan are skipped.
+func presignFiles(ctx context.Context, blobStore blobrepo.BlobStore, fileScanner scanner.FileScanner, files ...*model.File) error {
+	for _, file := range files {
+		if file == nil || !servable(fileScanner, file) {
+			continue
+		}
+
+		download, err := blobStore.PresignDownload(ctx, file.ObjectKey)
+		if err != nil {
+			return err
+		}
+		file.Url = download.URL
+		file.UrlExpiresAt = &download.ExpiresAt
+
+		for i := range file.Variants {
+			download, err := blobStore.PresignDownload(ctx, file.Variants[i].ObjectKey)
+			if err != nil {
+				return err
+			}
+			file.Variants[i].Url = download.URL
+			file.Variants[i].UrlExpiresAt = &download.ExpiresAt
+		}
+	}
+	return nil
+}
+
+// purge deletes file right away, first the objects it owns and then its row.
+// That releases the shared blobs it points at, the file sweep deletes those
+// once nothing else uses them.
+func (f *fileUploader) purge(ctx context.Context, file *model.File) error {
+	objectNames := []string{file.ObjectKey}
+	for _, variant := range file.Variants {
+		objectNames = append(objectNames, variant.ObjectKey)
+	}
+
+	blobs, err := f.fileRepository.FindBlobKeys(ctx, objectNames)
+	if err != nil {
+		return err
+	}
+	for _, objectName := range objectNames {
+		if blobs[objectName] {
+			continue
+		}
+		if err := deleteObject(ctx, f.blobStore, objectName); err != nil {
+			return err
+		}
+	}
+
+	return f.fileRepository.Purge(ctx, file)
+}
+
+func (f *fileUploader) tooLarge(maxSize int64) error {
+	return fmt.Errorf("%w, file size must be less than %dMB", errFileTooLarge, maxSize/1024/1024)
+}
+
+// limitedReader fails with err once more than remaining bytes are read, so
+// oversized uploads are cut off while streaming instead of after buffering.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	read      int64
+	err       error
+	exceeded  bool
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.exceeded {
+		return 0, r.err
+	}
+
+	// read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	if int64(n) > r.remaining {
+		r.exceeded = true
+		return 0, r.err
+	}
+	r.remaining -= int64(n)
+	r.read += int64(n)
+	return n, err
+}
+
+// uploadErrorStatus maps upload pipeline errors to an http status.
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errFileExceedsQuota), errors.Is(err, errFileTooLarge), errors.Is(err, helpers.ErrImageTooLarge), errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errStorageQuotaExceeded), errors.Is(err, postgresrepo.ErrStorageQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, errMimeTypeNotAllowed), errors.Is(err, helpers.ErrMimeTypeUnsupported), errors.Is(err, errFileEmpty), errors.Is(err, helpers.ErrImageInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, errUploadMissing), errors.Is(err, errUploadMismatch):
+		return http.StatusConflict
+	case errors.Is(err, errFileInfected):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
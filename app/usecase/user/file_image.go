@@ -0,0 +1,174 @@
+package usecase_user
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// avatarMaxSide bounds the stored avatar itself, the variants are smaller.
+	avatarMaxSide = 1024
+)
+
+// avatarSizes are the square variants generated for every avatar.
+var avatarSizes = []int{64, 256, 512}
+
+// imageLimits keep decoding below roughly 100MB of pixels.
+var imageLimits = helpers.ImageLimits{
+	MaxSide:   10000,
+	MaxPixels: 25_000_000,
+}
+
+// uploadImage stores a processed square copy of the image instead of the
+// upload itself, metadata like EXIF and GPS is dropped on the way. Each size
+// is stored as a variant of the file.
+func (f *fileUploader) uploadImage(ctx context.Context, in fileUpload, maxSide int, sizes []int) (*model.File, error) {
+	// validate declared size before reading anything
+	if in.MaxSize > 0 && in.Size > in.MaxSize {
+		return nil, f.tooLarge(in.MaxSize)
+	}
+
+	// read image, it is decoded as a whole anyway
+	data, err := io.ReadAll(io.LimitReader(in.Body, in.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > in.MaxSize {
+		return nil, f.tooLarge(in.MaxSize)
+	}
+	if len(data) == 0 {
+		return nil, errFileEmpty
+	}
+
+	// decode within limits
+	img, format, err := helpers.DecodeImage(data, imageLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	// crop, resize and encode
+	square := helpers.SquareImage(img, maxSide)
+	processed, err := helpers.EncodeImage(square, format)
+	if err != nil {
+		return nil, err
+	}
+	total := int64(len(processed.Data))
+
+	variants := make([]*helpers.EncodedImage, len(sizes))
+	for i, size := range sizes {
+		variants[i], err = helpers.EncodeImage(helpers.SquareImage(square, size), format)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(variants[i].Data))
+	}
+
+	// check storage quota
+	_, quota, err := f.checkQuota(ctx, in.UserID, total)
+	if err != nil {
+		return nil, err
+	}
+
+	// set objectName, image and variants are stored as blobs named by their
+	// content
+	newFile, err := f.newFile(in, processed.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	newFile.Size = int64(len(processed.Data))
+	newFile.Status = model.FileStatusReady
+	newFile.Sha256 = sha256Hex(processed.Data)
+	newFile.ObjectKey = f.blobKey(in.UserID, newFile.Sha256)
+
+	// only re-encoded pixels are stored, nothing of the upload is left to scan
+	newFile.ScanStatus = model.ScanStatusClean
+
+	for i, size := range sizes {
+		sum := sha256Hex(variants[i].Data)
+		newFile.Variants = append(newFile.Variants, model.FileVariant{
+			ID:        uuid.New().String(),
+			FileID:    newFile.ID,
+			Name:      strconv.Itoa(size),
+			MimeType:  variants[i].MimeType,
+			Size:      int64(len(variants[i].Data)),
+			Width:     variants[i].Width,
+			Height:    variants[i].Height,
+			ObjectKey: f.blobKey(in.UserID, sum),
+			Sha256:    sum,
+		})
+	}
+
+	// upload image and variants not stored yet
+	err = f.putImageBlobs(ctx, newFile, processed, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	// save to database, variants are created along with the file and each
+	// takes a reference on its blob, together they count towards the quota
+	err = f.fileRepository.Create(ctx, newFile, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	// unused blobs may have been swept before the references were taken
+	err = f.putImageBlobs(ctx, newFile, processed, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile, nil
+}
+
+// putImageBlobs uploads the image and variants of file whose blob is missing.
+// Blobs left without a row by a failed upload are deleted by the file sweep.
+func (f *fileUploader) putImageBlobs(ctx context.Context, file *model.File, image *helpers.EncodedImage, variants []*helpers.EncodedImage) error {
+	err := f.putBlob(ctx, file.ObjectKey, image)
+	if err != nil {
+		return err
+	}
+	for i, variant := range file.Variants {
+		err = f.putBlob(ctx, variant.ObjectKey, variants[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileUploader) putBlob(ctx context.Context, objectName string, image *helpers.EncodedImage) error {
+	info, err := f.blobStore.StatFile(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		return nil
+	}
+	return f.blobStore.UploadFile(ctx, objectName, bytes.NewReader(image.Data), image.MimeType)
+}
+
+// deleteObjects removes objects no longer needed, like the upload a blob was
+// copied from. Failures are only logged, the file sweep deletes what is left.
+func (f *fileUploader) deleteObjects(ctx context.Context, objectNames []string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, objectName := range objectNames {
+		if err := deleteObject(ctx, f.blobStore, objectName); err != nil {
+			logrus.WithField("object", objectName).Error(err)
+		}
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package usecase_user
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// storageQuotas are the storage quotas in bytes. A user gets the quota of
+// their plan, or the default one for plans without a quota of their own,
+// unless the user has a quota set.
+type storageQuotas struct {
+	defaultQuota int64
+	plans        map[string]int64
+}
+
+// newStorageQuotas reads STORAGE_QUOTA_MB, default 100MB, and the plan
+// quotas in STORAGE_PLAN_QUOTAS_MB like "free:100,pro:10240".
+func newStorageQuotas() storageQuotas {
+	quotaMB := viper.GetInt64("STORAGE_QUOTA_MB")
+	if quotaMB <= 0 {
+		quotaMB = 100
+	}
+
+	quotas := storageQuotas{
+		defaultQuota: quotaMB * 1024 * 1024,
+		plans:        map[string]int64{},
+	}
+	for _, entry := range strings.Split(viper.GetString("STORAGE_PLAN_QUOTAS_MB"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		plan, mb, _ := strings.Cut(entry, ":")
+		quotaMB, err := strconv.ParseInt(strings.TrimSpace(mb), 10, 64)
+		if err != nil || quotaMB <= 0 {
+			logrus.Warnf("ignoring storage plan quota %q", entry)
+			continue
+		}
+		quotas.plans[strings.TrimSpace(plan)] = quotaMB * 1024 * 1024
+	}
+
+	return quotas
+}
+
+// quotaFor returns the quota of user in bytes, the default quota when the
+// user is unknown.
+func (q storageQuotas) quotaFor(user *model.User) int64 {
+	if user == nil {
+		return q.defaultQuota
+	}
+	if user.StorageQuotaMB != nil && *user.StorageQuotaMB > 0 {
+		return *user.StorageQuotaMB * 1024 * 1024
+	}
+	if quota, ok := q.plans[user.Plan]; ok {
+		return quota
+	}
+	return q.defaultQuota
+}
+
+// checkQuota returns the storage the user already uses and their quota. It
+// fails when size more bytes, -1 when unknown, don't fit in what is left of
+// the quota, or could never fit in it at all.
+func (f *fileUploader) checkQuota(ctx context.Context, userID string, size int64) (int64, int64, error) {
+	user, err := f.userRepository.FindOne(ctx, map[string]interface{}{
+		"id": userID,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	quota := f.storageQuotas.quotaFor(user)
+
+	usages, err := f.fileRepository.FetchUsage(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	used := int64(0)
+	for _, usage := range usages {
+		used += usage.Bytes
+	}
+
+	if size > quota {
+		return 0, 0, errFileExceedsQuota
+	}
+	if used >= quota || (size > 0 && used+size > quota) {
+		return 0, 0, errStorageQuotaExceeded
+	}
+	return used, quota, nil
+}
+
+// storageReport sums up the storage usage of user by file category against
+// their quota.
+func (f *fileUploader) storageReport(ctx context.Context, user *model.User) (*model.StorageReport, error) {
+	usages, err := f.fileRepository.FetchUsage(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.StorageReport{
+		Plan:       user.Plan,
+		QuotaBytes: f.storageQuotas.quotaFor(user),
+		Categories: []model.StorageCategoryUsage{},
+	}
+	categories := map[string]*model.StorageCategoryUsage{}
+	for _, usage := range usages {
+		report.UsedBytes += usage.Bytes
+		report.Files += usage.Files
+
+		name := helpers.GetMimeTypeCategory(usage.MimeType)
+		if name == "" {
+			name = "other"
+		}
+		category, ok := categories[name]
+		if !ok {
+			category = &model.StorageCategoryUsage{Category: name}
+			categories[name] = category
+		}
+		category.Bytes += usage.Bytes
+		category.Files += usage.Files
+	}
+
+	for _, category := range categories {
+		report.Categories = append(report.Categories, *category)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].Bytes != report.Categories[j].Bytes {
+			return report.Categories[i].Bytes > report.Categories[j].Bytes
+		}
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+
+	report.AvailableBytes = report.QuotaBytes - report.UsedBytes
+	if report.AvailableBytes < 0 {
+		report.AvailableBytes = 0
+	}
+
+	return report, nil
+}
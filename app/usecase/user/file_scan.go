@@ -0,0 +1,133 @@
+package usecase_user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-gorm/app/scanner"
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+// quarantinePrefix is where infected objects are moved, out of the folders
+// files are served from.
+const quarantinePrefix = "quarantine/"
+
+// unscannedFileBatchSize is how many files waiting for a scan are scanned at
+// once.
+const unscannedFileBatchSize = 20
+
+var errFileInfected = errors.New("file is infected")
+
+// scanFile runs the malware scan of a stored file. A clean file is marked as
+// such, an infected one quarantined and errFileInfected returned. Other
+// errors leave the file pending.
+func (f *fileUploader) scanFile(ctx context.Context, file *model.File) error {
+	if !f.fileScanner.Enabled() || file.ScanStatus == model.ScanStatusClean {
+		return nil
+	}
+	if file.ScanStatus == model.ScanStatusInfected {
+		return errFileInfected
+	}
+
+	// scan stored object
+	body, _, err := f.blobStore.OpenFile(ctx, file.ObjectKey)
+	if err != nil {
+		return err
+	}
+	result, err := f.fileScanner.Scan(ctx, body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	// mark clean
+	if !result.Infected {
+		file.ScanStatus = model.ScanStatusClean
+		return f.fileRepository.Update(ctx, file)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"file_id":   file.ID,
+		"signature": result.Signature,
+	}).Warn("infected file quarantined")
+
+	err = f.quarantine(ctx, file)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w with %s", errFileInfected, result.Signature)
+}
+
+// scanUploaded scans a file right after its upload. Only infected files fail
+// the upload, when the scanner is unavailable the scan job retries later.
+func (f *fileUploader) scanUploaded(ctx context.Context, file *model.File) error {
+	err := f.scanFile(ctx, file)
+	if err != nil && !errors.Is(err, errFileInfected) {
+		logrus.WithField("file_id", file.ID).Warn("scan failed, retried later: ", err)
+		return nil
+	}
+	return err
+}
+
+// quarantine copies the object of an infected file under quarantinePrefix,
+// owned by the file alone. The row points at the copy before the original
+// goes, so the object is never without a row. A shared blob is only released,
+// other files with the same content are quarantined by their own scan; an
+// owned original that fails to delete is left to the file sweep.
+func (f *fileUploader) quarantine(ctx context.Context, file *model.File) error {
+	objectName := file.ObjectKey
+	err := f.blobStore.CopyFile(ctx, objectName, quarantinePrefix+file.ID)
+	if err != nil {
+		return err
+	}
+
+	shared := file.Sha256 != ""
+	file.ObjectKey = quarantinePrefix + file.ID
+	file.Sha256 = ""
+	file.ScanStatus = model.ScanStatusInfected
+	err = f.fileRepository.Quarantine(ctx, file, objectName)
+	if err != nil {
+		return err
+	}
+
+	if !shared {
+		f.deleteObjects(ctx, []string{objectName})
+	}
+	return nil
+}
+
+// ScanPendingFiles scans files uploaded before the given time that are still
+// waiting for their scan, because the scanner was down or scanning was just
+// enabled. It returns how many were scanned.
+func (u *fileUsecase) ScanPendingFiles(ctx context.Context, before time.Time) (int, error) {
+	if !u.fileScanner.Enabled() {
+		return 0, nil
+	}
+
+	files, err := u.fileRepository.FetchUnscanned(ctx, before, unscannedFileBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	scanned := 0
+	for _, file := range files {
+		err = u.fileUploader.scanFile(ctx, file)
+		if err != nil && !errors.Is(err, errFileInfected) {
+			logrus.WithField("file_id", file.ID).Error(err)
+			continue
+		}
+		scanned++
+	}
+
+	return scanned, nil
+}
+
+// servable tells whether files may be handed out, with scanning enabled only
+// clean files are.
+func servable(fileScanner scanner.FileScanner, file *model.File) bool {
+	return !fileScanner.Enabled() || file.ScanStatus == model.ScanStatusClean
+}
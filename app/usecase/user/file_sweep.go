@@ -0,0 +1,221 @@
+package usecase_user
+
+import (
+	"context"
+	"time"
+
+	blobrepo "golang-gorm/app/repository/blob"
+	"golang-gorm/domain/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// fileSweepBatchSize is how many rows are checked at once,
+	// objectSweepBatchSize how many listed objects.
+	fileSweepBatchSize   = 100
+	objectSweepBatchSize = 1000
+
+	objectDeleteAttempts = 3
+	objectDeleteBackoff  = time.Second
+)
+
+// SweepFiles removes what no longer belongs to anything, in four passes:
+//   - files no user or todo points at, created before createdBefore, are
+//     moved to the trash
+//   - files trashed before deletedBefore lose the objects they own and then
+//     their row, which releases the shared blobs they point at
+//   - shared blobs nothing points at since createdBefore are deleted
+//   - objects without a file, variant or blob row, last modified before
+//     createdBefore, are deleted
+//
+// Objects go before rows, so a failed delete leaves the row to retry from. A
+// dry run only reports what would be deleted.
+func (u *fileUsecase) SweepFiles(ctx context.Context, createdBefore time.Time, deletedBefore time.Time, dryRun bool) (*model.FileSweepReport, error) {
+	report := &model.FileSweepReport{
+		DryRun:            dryRun,
+		UnreferencedFiles: []string{},
+		PurgedFiles:       []string{},
+		UnusedBlobs:       []string{},
+		OrphanedObjects:   []string{},
+		FailedObjects:     []string{},
+	}
+
+	// trash unreferenced files
+	afterID := ""
+	for {
+		files, err := u.fileRepository.FetchUnreferenced(ctx, createdBefore, afterID, fileSweepBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, file := range files {
+			if !dryRun {
+				err = u.fileRepository.DeleteOne(ctx, file)
+				if err != nil {
+					return report, err
+				}
+			}
+			report.UnreferencedFiles = append(report.UnreferencedFiles, file.ID)
+			afterID = file.ID
+		}
+		if len(files) < fileSweepBatchSize {
+			break
+		}
+	}
+
+	// purge trashed files
+	afterID = ""
+	for {
+		files, err := u.fileRepository.FetchDeleted(ctx, deletedBefore, afterID, fileSweepBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, file := range files {
+			afterID = file.ID
+			if !dryRun && !u.deleteFileObjects(ctx, file, report) {
+				continue
+			}
+			if !dryRun {
+				err = u.fileRepository.Purge(ctx, file)
+				if err != nil {
+					return report, err
+				}
+			}
+			report.PurgedFiles = append(report.PurgedFiles, file.ID)
+		}
+		if len(files) < fileSweepBatchSize {
+			break
+		}
+	}
+
+	// delete unused blobs
+	afterKey := ""
+	for {
+		blobs, err := u.fileRepository.FetchUnusedBlobs(ctx, createdBefore, afterKey, fileSweepBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, blob := range blobs {
+			afterKey = blob.ObjectKey
+			if !dryRun {
+				deleted, err := u.fileRepository.DeleteUnusedBlob(ctx, blob.ObjectKey, func(ctx context.Context) error {
+					return deleteObject(ctx, u.blobStore, blob.ObjectKey)
+				})
+				if err != nil {
+					logrus.WithField("object", blob.ObjectKey).Error(err)
+					report.FailedObjects = append(report.FailedObjects, blob.ObjectKey)
+					continue
+				}
+				if !deleted {
+					continue
+				}
+			}
+			report.UnusedBlobs = append(report.UnusedBlobs, blob.ObjectKey)
+			report.ReclaimedBytes += blob.Size
+		}
+		if len(blobs) < fileSweepBatchSize {
+			break
+		}
+	}
+
+	// delete objects without a row
+	startAfter := ""
+	for {
+		objects, err := u.blobStore.ListFiles(ctx, "", startAfter, objectSweepBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(objects) == 0 {
+			break
+		}
+		startAfter = objects[len(objects)-1].Key
+
+		// recent objects may belong to an upload that hasn't saved its row yet
+		keys := []string{}
+		for _, object := range objects {
+			if object.LastModified.Before(createdBefore) {
+				keys = append(keys, object.Key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		known, err := u.fileRepository.FindObjectKeys(ctx, keys)
+		if err != nil {
+			return report, err
+		}
+		for _, object := range objects {
+			if known[object.Key] || !object.LastModified.Before(createdBefore) {
+				continue
+			}
+			if !dryRun {
+				err = deleteObject(ctx, u.blobStore, object.Key)
+				if err != nil {
+					logrus.WithField("object", object.Key).Error(err)
+					report.FailedObjects = append(report.FailedObjects, object.Key)
+					continue
+				}
+			}
+			report.OrphanedObjects = append(report.OrphanedObjects, object.Key)
+			report.ReclaimedBytes += object.Size
+		}
+	}
+
+	return report, nil
+}
+
+// deleteFileObjects deletes the objects file and its variants own, shared
+// blobs are left to their reference count. It reports whether all of them
+// are gone, and counts what they took up.
+func (u *fileUsecase) deleteFileObjects(ctx context.Context, file *model.File, report *model.FileSweepReport) bool {
+	objectNames := []string{file.ObjectKey}
+	sizes := map[string]int64{file.ObjectKey: file.Size}
+	for _, variant := range file.Variants {
+		objectNames = append(objectNames, variant.ObjectKey)
+		sizes[variant.ObjectKey] = variant.Size
+	}
+
+	blobs, err := u.fileRepository.FindBlobKeys(ctx, objectNames)
+	if err != nil {
+		logrus.WithField("file_id", file.ID).Error(err)
+		return false
+	}
+
+	deleted := true
+	for _, objectName := range objectNames {
+		if blobs[objectName] {
+			continue
+		}
+		err := deleteObject(ctx, u.blobStore, objectName)
+		if err != nil {
+			logrus.WithField("file_id", file.ID).Error(err)
+			report.FailedObjects = append(report.FailedObjects, objectName)
+			deleted = false
+			continue
+		}
+		report.ReclaimedBytes += sizes[objectName]
+	}
+	return deleted
+}
+
+// deleteObject deletes an object, retrying with backoff. Deleting a missing
+// object succeeds, so retrying a delete that went through but failed to
+// answer is harmless.
+func deleteObject(ctx context.Context, blobStore blobrepo.BlobStore, objectName string) error {
+	backoff := objectDeleteBackoff
+	for attempt := 1; ; attempt++ {
+		err := blobStore.DeleteFile(ctx, objectName)
+		if err == nil || attempt == objectDeleteAttempts {
+			return err
+		}
+
+		logrus.WithField("object", objectName).Warnf("delete attempt %d failed: %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
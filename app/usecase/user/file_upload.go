@@ -0,0 +1,209 @@
+package usecase_user
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	blobrepo "golang-gorm/app/repository/blob"
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/scanner"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pendingUploadBatchSize is how many expired pending uploads are swept at once.
+	pendingUploadBatchSize = 100
+	// storageUsageBatchSize is how many users get their usage recomputed at once.
+	storageUsageBatchSize = 100
+)
+
+type fileUsecase struct {
+	fileRepository postgresrepo.FileRepository
+	userRepository postgresrepo.UserRepository
+	blobStore      blobrepo.BlobStore
+	fileScanner    scanner.FileScanner
+	fileUploader   *fileUploader
+	contextTimeout time.Duration
+	validate       *validator.Validate
+}
+
+func NewFileUsecase(d usecase.UsecaseDependency) FileUsecase {
+	return &fileUsecase{
+		fileRepository: d.FileRepository,
+		userRepository: d.UserRepository,
+		blobStore:      d.BlobStore,
+		fileScanner:    d.FileScanner,
+		fileUploader:   newFileUploader(d.FileRepository, d.UserRepository, d.BlobStore, d.FileScanner),
+		contextTimeout: d.Timeout,
+		validate:       d.Validate,
+	}
+}
+
+type FileUsecase interface {
+	CreateUploadURL(ctx context.Context, claim model.JWTClaimUser, payload request.CreateUploadURLRequest) helpers.Response
+	CompleteUpload(ctx context.Context, claim model.JWTClaimUser, fileID string) helpers.Response
+	CleanupPendingUploads(ctx context.Context, before time.Time) (int, error)
+	ScanPendingFiles(ctx context.Context, before time.Time) (int, error)
+	SweepFiles(ctx context.Context, createdBefore time.Time, deletedBefore time.Time, dryRun bool) (*model.FileSweepReport, error)
+	RecomputeStorageUsage(ctx context.Context) (int, error)
+}
+
+// PresignedUpload is a pending file and the request that uploads it.
+type PresignedUpload struct {
+	File   *model.File                `json:"file"`
+	Upload *blobrepo.PresignedRequest `json:"upload"`
+}
+
+func (u *fileUsecase) CreateUploadURL(ctx context.Context, claim model.JWTClaimUser, payload request.CreateUploadURLRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// reserve file, presigned uploads are linked as attachments
+	file, upload, err := u.fileUploader.reserve(ctx, fileUpload{
+		UserID:     claim.UserID,
+		Folder:     "attachments",
+		Name:       strings.TrimSuffix(filepath.Base(payload.Name), filepath.Ext(payload.Name)),
+		MimeType:   payload.MimeType,
+		Size:       payload.Size,
+		Sha256:     strings.ToLower(payload.Sha256),
+		Categories: []string{"image", "document", "archive"},
+		MaxSize:    maxAttachmentSize,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  uploadErrorStatus(err),
+		}
+	}
+
+	return helpers.Response{
+		Data: PresignedUpload{
+			File:   file,
+			Upload: upload,
+		},
+		Message: "success",
+		Status:  http.StatusCreated,
+	}
+}
+
+func (u *fileUsecase) CompleteUpload(ctx context.Context, claim model.JWTClaimUser, fileID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check file exist
+	file, err := u.fileRepository.FindOne(ctx, map[string]interface{}{
+		"id":      fileID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if file == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "file not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// verify uploaded object, completing twice is a no-op
+	err = u.fileUploader.complete(ctx, file)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  uploadErrorStatus(err),
+		}
+	}
+
+	// sign download url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, file)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    file,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+// CleanupPendingUploads deletes the objects and rows of uploads reserved
+// before the given time and never completed. It returns how many were swept.
+func (u *fileUsecase) CleanupPendingUploads(ctx context.Context, before time.Time) (int, error) {
+	files, err := u.fileRepository.FetchPending(ctx, before, pendingUploadBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	swept := 0
+	for _, file := range files {
+		// the object may be fully uploaded, or not at all
+		err = deleteObject(ctx, u.blobStore, file.ObjectKey)
+		if err != nil {
+			logrus.WithField("file_id", file.ID).Error(err)
+			continue
+		}
+
+		err = u.fileRepository.DeleteOne(ctx, file)
+		if err != nil {
+			return swept, err
+		}
+		swept++
+	}
+
+	return swept, nil
+}
+
+// RecomputeStorageUsage rebuilds the storage usage of every user from their
+// files, fixing any drift. It returns for how many users the usage was off.
+func (u *fileUsecase) RecomputeStorageUsage(ctx context.Context) (int, error) {
+	drifted := 0
+	for offset := 0; ; offset += storageUsageBatchSize {
+		users, err := u.userRepository.FetchList(ctx, offset, storageUsageBatchSize, map[string]interface{}{})
+		if err != nil {
+			return drifted, err
+		}
+
+		for _, user := range users {
+			changed, err := u.fileRepository.RecomputeUsage(ctx, user.ID)
+			if err != nil {
+				return drifted, err
+			}
+			if changed {
+				logrus.WithField("user_id", user.ID).Warn("storage usage had drifted")
+				drifted++
+			}
+		}
+
+		if len(users) < storageUsageBatchSize {
+			return drifted, nil
+		}
+	}
+}
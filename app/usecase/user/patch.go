@@ -0,0 +1,20 @@
+package usecase_user
+
+import (
+	"errors"
+	"net/http"
+
+	"golang-gorm/helpers"
+)
+
+// patchErrorStatus maps errors from helpers.ApplyPatch to a response status.
+func patchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, helpers.ErrUnsupportedPatchType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, helpers.ErrPatchTestFailed):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
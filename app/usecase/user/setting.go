@@ -1,28 +1,31 @@
 package usecase_user
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
-	"fmt"
+	blobrepo "golang-gorm/app/repository/blob"
 	postgresrepo "golang-gorm/app/repository/postgres"
-	s3repo "golang-gorm/app/repository/s3"
+	"golang-gorm/app/scanner"
 	"golang-gorm/app/usecase"
 	"golang-gorm/domain/model"
 	"golang-gorm/domain/request"
 	"golang-gorm/helpers"
+	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+const maxProfilePictureSize = int64(10 * 1024 * 1024)
+
 type settingUsecase struct {
 	userRepository postgresrepo.UserRepository
 	fileRepository postgresrepo.FileRepository
-	s3Repository   s3repo.S3Repo
+	blobStore      blobrepo.BlobStore
+	fileScanner    scanner.FileScanner
+	fileUploader   *fileUploader
 	contextTimeout time.Duration
 	validate       *validator.Validate
 }
@@ -31,7 +34,9 @@ func NewSettingUsecase(d usecase.UsecaseDependency) SettingUsecase {
 	return &settingUsecase{
 		userRepository: d.UserRepository,
 		fileRepository: d.FileRepository,
-		s3Repository:   d.S3Repository,
+		blobStore:      d.BlobStore,
+		fileScanner:    d.FileScanner,
+		fileUploader:   newFileUploader(d.FileRepository, d.UserRepository, d.BlobStore, d.FileScanner),
 		contextTimeout: d.Timeout,
 		validate:       d.Validate,
 	}
@@ -39,6 +44,8 @@ func NewSettingUsecase(d usecase.UsecaseDependency) SettingUsecase {
 
 type SettingUsecase interface {
 	UpdateProfile(ctx context.Context, claim model.JWTClaimUser, payload request.UserUpdateProfileRequest) helpers.Response
+	PatchProfile(ctx context.Context, claim model.JWTClaimUser, payload request.PatchRequest) helpers.Response
+	GetStorage(ctx context.Context, claim model.JWTClaimUser) helpers.Response
 }
 
 func (u *settingUsecase) UpdateProfile(ctx context.Context, claim model.JWTClaimUser, payload request.UserUpdateProfileRequest) helpers.Response {
@@ -53,7 +60,7 @@ func (u *settingUsecase) UpdateProfile(ctx context.Context, claim model.JWTClaim
 
 	// check user exist
 	user, err := u.userRepository.FindOne(ctx, map[string]interface{}{
-		"user_id": claim.UserID,
+		"id": claim.UserID,
 	})
 	if err != nil {
 		return helpers.Response{
@@ -67,22 +74,36 @@ func (u *settingUsecase) UpdateProfile(ctx context.Context, claim model.JWTClaim
 			Data:    nil,
 			Message: "user not found",
 			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
 		}
 	}
 
+	// open photo profile from multipart or base64
+	mimeType, body, size, err := openProfilePicture(payload)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+	defer body.Close()
+
 	// upload photo profile
-	file, err := u.uploadProfilePicture(ctx, payload.ProfilePicture, user.Name)
+	file, err := u.uploadProfilePicture(ctx, user.ID, user.Name, mimeType, body, size)
 	if err != nil {
 		return helpers.Response{
 			Data:    nil,
 			Message: err.Error(),
-			Status:  http.StatusInternalServerError,
+			Status:  uploadErrorStatus(err),
 		}
 	}
 
 	// update user
+	previousAvatarID := user.AvatarID
 	user.Name = payload.Name
 	user.AvatarID = &file.ID
+	user.Avatar = file
 
 	// save user
 	err = u.userRepository.Update(ctx, user)
@@ -94,6 +115,19 @@ func (u *settingUsecase) UpdateProfile(ctx context.Context, claim model.JWTClaim
 		}
 	}
 
+	// trash replaced avatar
+	u.trashAvatar(ctx, previousAvatarID)
+
+	// sign avatar url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, user.Avatar)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
 	return helpers.Response{
 		Data:    user,
 		Message: "success",
@@ -101,80 +135,198 @@ func (u *settingUsecase) UpdateProfile(ctx context.Context, claim model.JWTClaim
 	}
 }
 
-func (u *settingUsecase) uploadProfilePicture(ctx context.Context, base64Data string, name string) (*model.File, error) {
+func (u *settingUsecase) PatchProfile(ctx context.Context, claim model.JWTClaimUser, payload request.PatchRequest) helpers.Response {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
-	logrus.Info("upload profile picture")
+	// check user exist
+	user, err := u.userRepository.FindOne(ctx, map[string]interface{}{
+		"id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if user == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "user not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// apply patch to the current profile
+	patched := request.PatchProfileRequest{
+		Name: user.Name,
+	}
+	err = helpers.ApplyPatch(payload.ContentType, payload.Patch, &patched)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  patchErrorStatus(err),
+		}
+	}
 
-	// split metadata and data base64
-	parts := strings.SplitN(base64Data, ",", 2)
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid base64 data")
+	// validate patched profile
+	validationResponse, err := helpers.ValidateBody(u.validate, patched)
+	if err != nil {
+		return validationResponse
 	}
 
-	// get mimetype
-	meta := parts[0]
-	mimeType := strings.TrimPrefix(strings.Split(meta, ";")[0], "data:")
-	if mimeType == "" {
-		return nil, fmt.Errorf("unable to parse MIME type")
+	// collect changed columns
+	previousAvatarID := user.AvatarID
+	columns := []string{}
+	if patched.Name != user.Name {
+		user.Name = patched.Name
+		columns = append(columns, "name")
 	}
+	if patched.ProfilePicture != nil {
+		mimeType, data, err := helpers.ParseBase64DataURI(*patched.ProfilePicture)
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusBadRequest,
+			}
+		}
 
-	// validate mimetype
-	allowed := helpers.IsMimeTypeAllowed(mimeType, "image")
-	if !allowed {
-		return nil, fmt.Errorf("mimetype not allowed")
+		file, err := u.uploadProfilePicture(ctx, user.ID, user.Name, mimeType, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  uploadErrorStatus(err),
+			}
+		}
+		user.AvatarID = &file.ID
+		user.Avatar = file
+		columns = append(columns, "avatar_id")
 	}
 
-	// get extension
-	ext, err := helpers.GetExtensionFromMimeType(mimeType)
-	if err != nil {
-		return nil, err
+	// save changed columns
+	if len(columns) > 0 {
+		err = u.userRepository.UpdateColumns(ctx, user, columns...)
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
 	}
 
-	// decode base64
-	file, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		return nil, err
+	// trash replaced avatar
+	if patched.ProfilePicture != nil {
+		u.trashAvatar(ctx, previousAvatarID)
 	}
 
-	// get file size
-	fileSize := int64(len(file))
+	// sign avatar url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, user.Avatar)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
 
-	// validate size
-	maxSize := int64(10 * 1024 * 1024)
-	if fileSize > maxSize {
-		return nil, fmt.Errorf("file size must be less than 10MB")
+	return helpers.Response{
+		Data:    user,
+		Message: "success",
+		Status:  http.StatusOK,
 	}
+}
 
-	// set objectName
-	fileName := fmt.Sprintf("%s_%s.%s", time.Now().Format("20060102"), name, ext)
-	year, month, _ := time.Now().Date()
-	objectName := fmt.Sprintf("profile_pictures/%d/%s/%s", year, month, fileName)
+func (u *settingUsecase) GetStorage(ctx context.Context, claim model.JWTClaimUser) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
 
-	logrus.Info(objectName)
+	// check user exist
+	user, err := u.userRepository.FindOne(ctx, map[string]interface{}{
+		"id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if user == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "user not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
 
-	// upload to s3
-	url, err := u.s3Repository.UploadFile(ctx, objectName, file, mimeType)
+	// sum up usage by category
+	report, err := u.fileUploader.storageReport(ctx, user)
 	if err != nil {
-		return nil, err
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    report,
+		Message: "success",
+		Status:  http.StatusOK,
 	}
+}
 
-	logrus.Info(url)
+func (u *settingUsecase) uploadProfilePicture(ctx context.Context, userID string, name string, mimeType string, body io.Reader, size int64) (*model.File, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	logrus.Info("upload profile picture")
 
-	// save to database
-	newFile := model.File{
-		ID:       uuid.New().String(),
-		Name:     fileName,
-		MimeType: mimeType,
-		Size:     fileSize,
-		Url:      url,
+	return u.fileUploader.uploadImage(ctx, fileUpload{
+		UserID:     userID,
+		Folder:     "profile_pictures",
+		Name:       name,
+		MimeType:   mimeType,
+		Body:       body,
+		Size:       size,
+		Categories: []string{"image"},
+		MaxSize:    maxProfilePictureSize,
+	}, avatarMaxSide, avatarSizes)
+}
+
+// trashAvatar moves a replaced avatar to the trash, the file sweep deletes it
+// for good. Failures are only logged, the sweep finds unreferenced files
+// anyway.
+func (u *settingUsecase) trashAvatar(ctx context.Context, avatarID *string) {
+	if avatarID == nil {
+		return
 	}
 
-	err = u.fileRepository.Create(ctx, &newFile)
+	err := u.fileRepository.DeleteOne(ctx, &model.File{ID: *avatarID})
 	if err != nil {
-		return nil, err
+		logrus.WithField("file_id", *avatarID).Error(err)
+	}
+}
+
+// openProfilePicture returns the claimed mimetype, content and size of the
+// profile picture. Multipart files are streamed from the request.
+func openProfilePicture(payload request.UserUpdateProfileRequest) (string, io.ReadCloser, int64, error) {
+	// base64 data uri
+	if payload.Upload == nil {
+		mimeType, data, err := helpers.ParseBase64DataURI(payload.ProfilePicture)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		return mimeType, io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
 	}
 
-	return &newFile, nil
+	// multipart upload, the size is only known once it's read
+	return payload.Upload.ContentType, io.NopCloser(payload.Upload.Body), -1, nil
 }
@@ -0,0 +1,40 @@
+package usecase_user
+
+import (
+	"context"
+	"encoding/json"
+
+	"golang-gorm/app/pubsub"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+)
+
+type streamUsecase struct {
+	pubSub pubsub.PubSub
+}
+
+func NewStreamUsecase(d usecase.UsecaseDependency) StreamUsecase {
+	return &streamUsecase{
+		pubSub: d.PubSub,
+	}
+}
+
+type StreamUsecase interface {
+	Subscribe(ctx context.Context, claim model.JWTClaimUser) (<-chan []byte, error)
+	HandleTodoEvent(ctx context.Context, event model.TodoEvent) error
+}
+
+// Subscribe returns the JSON encoded todo events of the user until ctx is done.
+func (u *streamUsecase) Subscribe(ctx context.Context, claim model.JWTClaimUser) (<-chan []byte, error) {
+	return u.pubSub.Subscribe(ctx, pubsub.UserTopic(claim.UserID))
+}
+
+// HandleTodoEvent forwards todo events from the event bus to the owner's streams.
+func (u *streamUsecase) HandleTodoEvent(ctx context.Context, event model.TodoEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return u.pubSub.Publish(ctx, pubsub.UserTopic(event.UserID), payload)
+}
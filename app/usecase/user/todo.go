@@ -2,11 +2,15 @@ package usecase_user
 
 import (
 	"context"
+	"errors"
+	blobrepo "golang-gorm/app/repository/blob"
 	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/scanner"
 	"golang-gorm/app/usecase"
 	"golang-gorm/domain/model"
 	"golang-gorm/domain/request"
 	"golang-gorm/helpers"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
@@ -16,25 +20,54 @@ import (
 )
 
 type todoUsecase struct {
-	todoRepository postgresrepo.TodoRepository
-	contextTimeout time.Duration
-	validate       *validator.Validate
+	todoRepository           postgresrepo.TodoRepository
+	fileRepository           postgresrepo.FileRepository
+	todoAttachmentRepository postgresrepo.TodoAttachmentRepository
+	todoHistoryRepository    postgresrepo.TodoHistoryRepository
+	blobStore                blobrepo.BlobStore
+	fileScanner              scanner.FileScanner
+	fileUploader             *fileUploader
+	contextTimeout           time.Duration
+	validate                 *validator.Validate
 }
 
 func NewTodoUsecase(d usecase.UsecaseDependency) TodoUsecase {
 	return &todoUsecase{
-		todoRepository: d.TodoRepository,
-		contextTimeout: d.Timeout,
-		validate:       d.Validate,
+		todoRepository:           d.TodoRepository,
+		fileRepository:           d.FileRepository,
+		todoAttachmentRepository: d.TodoAttachmentRepository,
+		todoHistoryRepository:    d.TodoHistoryRepository,
+		blobStore:                d.BlobStore,
+		fileScanner:              d.FileScanner,
+		fileUploader:             newFileUploader(d.FileRepository, d.UserRepository, d.BlobStore, d.FileScanner),
+		contextTimeout:           d.Timeout,
+		validate:                 d.Validate,
 	}
 }
 
 type TodoUsecase interface {
 	GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse
 	GetOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response
+	GetOneByExternalID(ctx context.Context, claim model.JWTClaimUser, externalID string) helpers.Response
 	Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateTodoRequest) helpers.Response
 	UpdateOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.UpdateTodoRequest) helpers.Response
-	DeleteOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response
+	PatchOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.PatchRequest) helpers.Response
+	SyncPull(ctx context.Context, claim model.JWTClaimUser, payload request.SyncPullRequest) helpers.Response
+	SyncPush(ctx context.Context, claim model.JWTClaimUser, payload request.SyncPushRequest) helpers.Response
+	DeleteOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.DeleteTodoRequest) helpers.Response
+	Export(ctx context.Context, claim model.JWTClaimUser, payload request.ExportTodoRequest) helpers.Response
+	Import(ctx context.Context, claim model.JWTClaimUser, payload request.ImportTodoRequest, body io.Reader) helpers.Response
+	Move(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.MoveTodoRequest) helpers.Response
+	GetTrash(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse
+	RestoreOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response
+	PurgeOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response
+	GetHistory(ctx context.Context, claim model.JWTClaimUser, todoID string, query url.Values) helpers.PaginatedResponse
+
+	UploadAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.UploadAttachmentRequest) helpers.Response
+	GetAttachments(ctx context.Context, claim model.JWTClaimUser, todoID string, query url.Values) helpers.PaginatedResponse
+	GetAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, fileID string) helpers.Response
+	DeleteAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, fileID string) helpers.Response
+	GetAttachmentsByTodoIDs(ctx context.Context, claim model.JWTClaimUser, todoIDs []string) helpers.Response
 }
 
 func (u *todoUsecase) GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse {
@@ -47,6 +80,16 @@ func (u *todoUsecase) GetAll(ctx context.Context, claim model.JWTClaimUser, quer
 	filters := map[string]interface{}{
 		"user_id": claim.UserID,
 	}
+	switch status := model.TodoStatus(query.Get("status")); status {
+	case "":
+	case model.TodoStatusDone, model.TodoStatusNotStarted:
+		filters["status"] = status
+	default:
+		return helpers.PaginatedResponse{
+			Status:  http.StatusBadRequest,
+			Message: "invalid todo status",
+		}
+	}
 
 	// count first
 	totalData, err := u.todoRepository.Count(ctx, filters)
@@ -112,6 +155,39 @@ func (u *todoUsecase) GetOne(ctx context.Context, claim model.JWTClaimUser, todo
 			Data:    nil,
 			Message: "todo not found",
 			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	return helpers.Response{
+		Data:    todo,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) GetOneByExternalID(ctx context.Context, claim model.JWTClaimUser, externalID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"external_id": externalID,
+		"user_id":     claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
 		}
 	}
 
@@ -132,16 +208,90 @@ func (u *todoUsecase) Create(ctx context.Context, claim model.JWTClaimUser, payl
 		return validationResponse
 	}
 
+	// check client generated id not used yet, only among the user's own
+	// todos so the answer tells nothing about other users
+	if payload.ID != nil {
+		existing, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+			"id":           *payload.ID,
+			"user_id":      claim.UserID,
+			"with_trashed": true,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if existing != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: "id already exist",
+				Status:  http.StatusConflict,
+				Err:     helpers.ErrAlreadyExists,
+			}
+		}
+	}
+
+	// check external id not used yet
+	if payload.ExternalID != nil {
+		existing, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+			"user_id":      claim.UserID,
+			"external_id":  *payload.ExternalID,
+			"with_trashed": true,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if existing != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: "external_id already exist",
+				Status:  http.StatusConflict,
+				Err:     helpers.ErrAlreadyExists,
+			}
+		}
+	}
+
+	// place new todo at the end of the list
+	position, err := u.positionBetween(ctx, claim.UserID, "", nil, nil)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
 	// create todo
 	newTodo := model.Todo{
-		ID:     uuid.New().String(),
-		Name:   payload.Name,
-		UserID: claim.UserID,
-		Status: model.TodoStatusNotStarted,
+		ID:         uuid.New().String(),
+		Name:       payload.Name,
+		UserID:     claim.UserID,
+		ExternalID: payload.ExternalID,
+		Status:     model.TodoStatusNotStarted,
+		Position:   position,
+	}
+	if payload.ID != nil {
+		newTodo.ID = *payload.ID
+	}
+	if payload.Status != "" {
+		newTodo.Status = payload.Status
 	}
 
 	// save todo
 	err = u.todoRepository.Create(ctx, &newTodo)
+	if errors.Is(err, postgresrepo.ErrTodoIDTaken) {
+		return helpers.Response{
+			Data:    nil,
+			Message: "id can't be used, generate a new one",
+			Status:  http.StatusBadRequest,
+		}
+	}
 	if err != nil {
 		return helpers.Response{
 			Data:    nil,
@@ -174,10 +324,16 @@ func (u *todoUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, t
 		}
 	}
 	if todo == nil {
+		// If-Match, even the wildcard, fails on a missing todo
+		status := http.StatusBadRequest
+		if payload.IfMatch != nil {
+			status = http.StatusPreconditionFailed
+		}
 		return helpers.Response{
 			Data:    nil,
 			Message: "todo not found",
-			Status:  http.StatusBadRequest,
+			Status:  status,
+			Err:     helpers.ErrNotFound,
 		}
 	}
 
@@ -187,12 +343,45 @@ func (u *todoUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, t
 		return validationResponse
 	}
 
+	// check version
+	if (payload.Version != nil && *payload.Version != todo.Version) || !payload.IfMatch.Matches(todo.Version) {
+		return helpers.Response{
+			Data:    nil,
+			Message: postgresrepo.ErrTodoVersionConflict.Error(),
+			Status:  http.StatusPreconditionFailed,
+		}
+	}
+
 	// update todo
 	todo.Name = payload.Name
 	todo.Status = payload.Status
 
 	// save todo
 	err = u.todoRepository.UpdateOne(ctx, todo)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  todoSaveErrorStatus(err),
+		}
+	}
+
+	return helpers.Response{
+		Data:    todo,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) PatchOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.PatchRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
 	if err != nil {
 		return helpers.Response{
 			Data:    nil,
@@ -200,6 +389,72 @@ func (u *todoUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, t
 			Status:  http.StatusInternalServerError,
 		}
 	}
+	if todo == nil {
+		// If-Match, even the wildcard, fails on a missing todo
+		status := http.StatusBadRequest
+		if payload.IfMatch != nil {
+			status = http.StatusPreconditionFailed
+		}
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found",
+			Status:  status,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// check version
+	if (payload.Version != nil && *payload.Version != todo.Version) || !payload.IfMatch.Matches(todo.Version) {
+		return helpers.Response{
+			Data:    nil,
+			Message: postgresrepo.ErrTodoVersionConflict.Error(),
+			Status:  http.StatusPreconditionFailed,
+		}
+	}
+
+	// apply patch to the current todo
+	patched := request.PatchTodoRequest{
+		Name:    todo.Name,
+		Status:  todo.Status,
+		Version: todo.Version,
+	}
+	err = helpers.ApplyPatch(payload.ContentType, payload.Patch, &patched)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  patchErrorStatus(err),
+		}
+	}
+
+	// validate patched todo
+	validationResponse, err := helpers.ValidateBody(u.validate, patched)
+	if err != nil {
+		return validationResponse
+	}
+
+	// a patched version is an expected version
+	if patched.Version != todo.Version {
+		return helpers.Response{
+			Data:    nil,
+			Message: postgresrepo.ErrTodoVersionConflict.Error(),
+			Status:  http.StatusPreconditionFailed,
+		}
+	}
+
+	// update todo, only changed columns are written
+	todo.Name = patched.Name
+	todo.Status = patched.Status
+
+	// save todo
+	err = u.todoRepository.UpdateOne(ctx, todo)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  todoSaveErrorStatus(err),
+		}
+	}
 
 	return helpers.Response{
 		Data:    todo,
@@ -208,7 +463,7 @@ func (u *todoUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, t
 	}
 }
 
-func (u *todoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response {
+func (u *todoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.DeleteTodoRequest) helpers.Response {
 	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
@@ -225,10 +480,25 @@ func (u *todoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, t
 		}
 	}
 	if todo == nil {
+		// If-Match, even the wildcard, fails on a missing todo
+		status := http.StatusBadRequest
+		if payload.IfMatch != nil {
+			status = http.StatusPreconditionFailed
+		}
 		return helpers.Response{
 			Data:    nil,
 			Message: "todo not found",
-			Status:  http.StatusBadRequest,
+			Status:  status,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// check version
+	if (payload.Version != nil && *payload.Version != todo.Version) || !payload.IfMatch.Matches(todo.Version) {
+		return helpers.Response{
+			Data:    nil,
+			Message: postgresrepo.ErrTodoVersionConflict.Error(),
+			Status:  http.StatusPreconditionFailed,
 		}
 	}
 
@@ -238,7 +508,7 @@ func (u *todoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, t
 		return helpers.Response{
 			Data:    nil,
 			Message: err.Error(),
-			Status:  http.StatusInternalServerError,
+			Status:  todoSaveErrorStatus(err),
 		}
 	}
 
@@ -248,3 +518,225 @@ func (u *todoUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, t
 		Status:  http.StatusOK,
 	}
 }
+
+func (u *todoUsecase) GetTrash(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"user_id":      claim.UserID,
+		"only_trashed": true,
+	}
+
+	// count first
+	totalData, err := u.todoRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count todo",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	todos, err := u.todoRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch todo",
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    todos,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+func (u *todoUsecase) RestoreOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist in trash
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":           todoID,
+		"user_id":      claim.UserID,
+		"only_trashed": true,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found in trash",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// restore todo
+	err = u.todoRepository.RestoreOne(ctx, todo)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  todoSaveErrorStatus(err),
+		}
+	}
+	todo.DeletedAt = nil
+
+	return helpers.Response{
+		Data:    todo,
+		Message: "todo successfully restored",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) PurgeOne(ctx context.Context, claim model.JWTClaimUser, todoID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist in trash, live todos go through the trash first
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":           todoID,
+		"user_id":      claim.UserID,
+		"only_trashed": true,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found in trash",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// permanently delete todo
+	err = u.todoRepository.PurgeOne(ctx, todo)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    nil,
+		Message: "todo permanently deleted",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) GetHistory(ctx context.Context, claim model.JWTClaimUser, todoID string, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if todo == nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusBadRequest,
+			Message: "todo not found",
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"todo_id": todo.ID,
+	}
+
+	// count first
+	totalData, err := u.todoHistoryRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count todo history",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	histories, err := u.todoHistoryRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch todo history",
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    histories,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+// todoSaveErrorStatus maps a repository error on save to an http status.
+func todoSaveErrorStatus(err error) int {
+	if errors.Is(err, postgresrepo.ErrTodoVersionConflict) {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,402 @@
+package usecase_user
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxAttachmentSize = int64(25 * 1024 * 1024)
+
+func (u *todoUsecase) UploadAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.UploadAttachmentRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// link a completed presigned upload, or upload the file
+	var file *model.File
+	if payload.FileID != "" {
+		file, err = u.fileRepository.FindOne(ctx, map[string]interface{}{
+			"id":      payload.FileID,
+			"user_id": claim.UserID,
+			"status":  model.FileStatusReady,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if file == nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: "file not found",
+				Status:  http.StatusBadRequest,
+				Err:     helpers.ErrNotFound,
+			}
+		}
+		if file.ScanStatus == model.ScanStatusInfected {
+			return helpers.Response{
+				Data:    nil,
+				Message: errFileInfected.Error(),
+				Status:  uploadErrorStatus(errFileInfected),
+			}
+		}
+	} else {
+		// open file from multipart or base64
+		name, mimeType, body, size, err := u.openAttachment(payload)
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusBadRequest,
+			}
+		}
+		defer body.Close()
+
+		// upload file
+		file, err = u.fileUploader.upload(ctx, fileUpload{
+			UserID:     claim.UserID,
+			Folder:     "attachments",
+			Name:       name,
+			MimeType:   mimeType,
+			Body:       body,
+			Size:       size,
+			Categories: []string{"image", "document", "archive"},
+			MaxSize:    maxAttachmentSize,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  uploadErrorStatus(err),
+			}
+		}
+	}
+
+	// link file to todo, a file uploaded here is not left behind unlinked
+	err = u.todoAttachmentRepository.Create(ctx, &model.TodoAttachment{
+		TodoID: todo.ID,
+		FileID: file.ID,
+	})
+	if err != nil {
+		if payload.FileID == "" {
+			if err := u.fileUploader.purge(context.WithoutCancel(ctx), file); err != nil {
+				logrus.WithField("file_id", file.ID).Error(err)
+			}
+		}
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// sign download url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, file)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    file,
+		Message: "success",
+		Status:  http.StatusCreated,
+	}
+}
+
+// openAttachment returns the name, claimed mimetype, content and size of the
+// uploaded file. Multipart files are streamed from the request, not buffered.
+func (u *todoUsecase) openAttachment(payload request.UploadAttachmentRequest) (string, string, io.ReadCloser, int64, error) {
+	// base64 data uri
+	if payload.Upload == nil {
+		mimeType, data, err := helpers.ParseBase64DataURI(payload.File)
+		if err != nil {
+			return "", "", nil, 0, err
+		}
+
+		name := payload.Name
+		if name == "" {
+			name = "attachment"
+		}
+		return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)), mimeType, io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+	}
+
+	// multipart upload, the size is only known once it's read
+	name := payload.Name
+	if name == "" {
+		name = payload.Upload.Filename
+	}
+
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)), payload.Upload.ContentType, io.NopCloser(payload.Upload.Body), -1, nil
+}
+
+func (u *todoUsecase) GetAttachments(ctx context.Context, claim model.JWTClaimUser, todoID string, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if todo == nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusBadRequest,
+			Message: "todo not found",
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"todo_id": todo.ID,
+	}
+
+	// count first
+	totalData, err := u.fileRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count attachment",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	files, err := u.fileRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch attachment",
+		}
+	}
+
+	// sign download urls
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, files...)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    files,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+func (u *todoUsecase) GetAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, fileID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// check attachment exist
+	file, err := u.fileRepository.FindOne(ctx, map[string]interface{}{
+		"id":      fileID,
+		"todo_id": todo.ID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if file == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "attachment not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// sign download url
+	err = presignFiles(ctx, u.blobStore, u.fileScanner, file)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    file,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) DeleteAttachment(ctx context.Context, claim model.JWTClaimUser, todoID string, fileID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check attachment exist
+	response := u.GetAttachment(ctx, claim, todoID, fileID)
+	if response.Status != http.StatusOK {
+		return response
+	}
+	file := response.Data.(*model.File)
+
+	// unlink file from todo
+	err := u.todoAttachmentRepository.DeleteOne(ctx, &model.TodoAttachment{
+		TodoID: todoID,
+		FileID: file.ID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// delete file with its object, unless another todo links it too
+	linked, err := u.todoAttachmentRepository.FindOne(ctx, map[string]interface{}{
+		"file_id": file.ID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if linked == nil {
+		// failures are only logged, the file sweep finds unreferenced files
+		// anyway
+		if err := u.fileUploader.purge(ctx, file); err != nil {
+			logrus.WithField("file_id", file.ID).Error(err)
+		}
+	}
+
+	return helpers.Response{
+		Data:    nil,
+		Message: "attachment successfully deleted",
+		Status:  http.StatusOK,
+	}
+}
+
+// GetAttachmentsByTodoIDs returns the attachments of several todos at once,
+// keyed by todo id. Todos of other users are left out.
+func (u *todoUsecase) GetAttachmentsByTodoIDs(ctx context.Context, claim model.JWTClaimUser, todoIDs []string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	files, err := u.fileRepository.FetchByTodoIDs(ctx, claim.UserID, todoIDs)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "error fetch attachment",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// sign download urls
+	for _, todoFiles := range files {
+		err = presignFiles(ctx, u.blobStore, u.fileScanner, todoFiles...)
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	return helpers.Response{
+		Data:    files,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
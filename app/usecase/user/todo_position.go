@@ -0,0 +1,194 @@
+package usecase_user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+)
+
+var (
+	errTodoNeighborNotFound     = errors.New("neighbor todo not found")
+	errTodoNeighborsOutOfOrder  = errors.New("before_id must come before after_id")
+	errTodoNeighborsNotAdjacent = errors.New("before_id and after_id must be next to each other")
+	// errTodoNeighborsTied means adjacent neighbors share a position, there is
+	// no room between them until the positions are rebalanced
+	errTodoNeighborsTied = errors.New("neighbor todos share a position")
+)
+
+func (u *todoUsecase) Move(ctx context.Context, claim model.JWTClaimUser, todoID string, payload request.MoveTodoRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// check todo exist
+	todo, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      todoID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if todo == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "todo not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	// compute new position between neighbors
+	position, err := u.positionBetween(ctx, claim.UserID, todo.ID, payload.BeforeID, payload.AfterID)
+	if errors.Is(err, errTodoNeighborsTied) {
+		// rebalance and try once more
+		if err := u.todoRepository.RebalancePositions(ctx, claim.UserID); err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		position, err = u.positionBetween(ctx, claim.UserID, todo.ID, payload.BeforeID, payload.AfterID)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errTodoNeighborNotFound) || errors.Is(err, errTodoNeighborsOutOfOrder) || errors.Is(err, errTodoNeighborsNotAdjacent) || errors.Is(err, helpers.ErrInvalidRankRange) {
+			status = http.StatusBadRequest
+		}
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  status,
+		}
+	}
+
+	// save todo
+	todo.Position = position
+	err = u.todoRepository.UpdateOne(ctx, todo)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  todoSaveErrorStatus(err),
+		}
+	}
+
+	return helpers.Response{
+		Data:    todo,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+// positionBetween returns a position for todoID between the given neighbors.
+// A missing neighbor is looked up from the list, and without any neighbor the
+// position is at the end of the list.
+func (u *todoUsecase) positionBetween(ctx context.Context, userID, todoID string, beforeID, afterID *string) (string, error) {
+	filters := map[string]interface{}{
+		"user_id": userID,
+	}
+	// include trashed todos at the end so a restored todo never collides with a new one
+	endFilters := map[string]interface{}{
+		"user_id":      userID,
+		"with_trashed": true,
+	}
+	if todoID != "" {
+		filters["not_id"] = todoID
+		endFilters["not_id"] = todoID
+	}
+
+	before, err := u.findNeighbor(ctx, filters, beforeID)
+	if err != nil {
+		return "", err
+	}
+	after, err := u.findNeighbor(ctx, filters, afterID)
+	if err != nil {
+		return "", err
+	}
+	if before != nil && after != nil {
+		if err := u.checkNeighbors(ctx, filters, before, after); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case before != nil && after == nil:
+		after, err = u.todoRepository.FindAdjacent(ctx, filters, before.Position, false)
+	case before == nil && after != nil:
+		before, err = u.todoRepository.FindAdjacent(ctx, filters, after.Position, true)
+	case before == nil && after == nil:
+		before, err = u.todoRepository.FindAdjacent(ctx, endFilters, "", true)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var beforePosition, afterPosition string
+	if before != nil {
+		beforePosition = before.Position
+	}
+	if after != nil {
+		afterPosition = after.Position
+	}
+
+	return helpers.RankBetween(beforePosition, afterPosition)
+}
+
+// checkNeighbors makes sure the neighbors a client picked are in list order,
+// by position then id, before anything is written. Neighbors sharing a
+// position are only tied when nothing lies between them.
+func (u *todoUsecase) checkNeighbors(ctx context.Context, filters map[string]interface{}, before, after *model.Todo) error {
+	if before.Position > after.Position || (before.Position == after.Position && before.ID >= after.ID) {
+		return errTodoNeighborsOutOfOrder
+	}
+	if before.Position < after.Position {
+		return nil
+	}
+
+	between, err := u.todoRepository.Count(ctx, map[string]interface{}{
+		"user_id":    filters["user_id"],
+		"not_id":     filters["not_id"],
+		"position":   before.Position,
+		"id_between": [2]string{before.ID, after.ID},
+	})
+	if err != nil {
+		return err
+	}
+	if between > 0 {
+		return errTodoNeighborsNotAdjacent
+	}
+	return errTodoNeighborsTied
+}
+
+func (u *todoUsecase) findNeighbor(ctx context.Context, filters map[string]interface{}, neighborID *string) (*model.Todo, error) {
+	if neighborID == nil {
+		return nil, nil
+	}
+
+	neighbor, err := u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":      *neighborID,
+		"user_id": filters["user_id"],
+		"not_id":  filters["not_id"],
+	})
+	if err != nil {
+		return nil, err
+	}
+	if neighbor == nil {
+		return nil, errTodoNeighborNotFound
+	}
+
+	return neighbor, nil
+}
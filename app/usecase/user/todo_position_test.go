@@ -0,0 +1,166 @@
+package usecase_user
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// fakeTodoRepository keeps the todos of a single user in list order and
+// records what was written.
+type fakeTodoRepository struct {
+	postgresrepo.TodoRepository
+
+	todos      []*model.Todo
+	rebalanced int
+	updated    int
+	deleted    int
+}
+
+func (f *fakeTodoRepository) sorted(filters map[string]interface{}) []*model.Todo {
+	todos := []*model.Todo{}
+	for _, todo := range f.todos {
+		if notID, ok := filters["not_id"].(string); ok && todo.ID == notID {
+			continue
+		}
+		todos = append(todos, todo)
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if todos[i].Position != todos[j].Position {
+			return todos[i].Position < todos[j].Position
+		}
+		return todos[i].ID < todos[j].ID
+	})
+	return todos
+}
+
+func (f *fakeTodoRepository) FindOne(ctx context.Context, filters map[string]interface{}) (*model.Todo, error) {
+	for _, todo := range f.sorted(filters) {
+		if todo.ID == filters["id"] {
+			copied := *todo
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeTodoRepository) FindAdjacent(ctx context.Context, filters map[string]interface{}, position string, reverse bool) (*model.Todo, error) {
+	todos := f.sorted(filters)
+	if reverse {
+		for i := len(todos) - 1; i >= 0; i-- {
+			if position == "" || todos[i].Position < position {
+				return todos[i], nil
+			}
+		}
+		return nil, nil
+	}
+	for _, todo := range todos {
+		if position == "" || todo.Position > position {
+			return todo, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeTodoRepository) Count(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	count := int64(0)
+	idRange, _ := filters["id_between"].([2]string)
+	for _, todo := range f.sorted(filters) {
+		if todo.Position == filters["position"] && todo.ID > idRange[0] && todo.ID < idRange[1] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeTodoRepository) RebalancePositions(ctx context.Context, userID string) error {
+	f.rebalanced++
+	todos := f.sorted(map[string]interface{}{})
+	for i, position := range helpers.RankSequence(len(todos)) {
+		todos[i].Position = position
+	}
+	return nil
+}
+
+func (f *fakeTodoRepository) UpdateOne(ctx context.Context, todo *model.Todo) error {
+	f.updated++
+	for _, stored := range f.todos {
+		if stored.ID == todo.ID {
+			stored.Position = todo.Position
+		}
+	}
+	return nil
+}
+
+func (f *fakeTodoRepository) DeleteOne(ctx context.Context, todo *model.Todo) error {
+	f.deleted++
+	return nil
+}
+
+const (
+	todoA = "00000000-0000-4000-8000-00000000000a"
+	todoB = "00000000-0000-4000-8000-00000000000b"
+	todoC = "00000000-0000-4000-8000-00000000000c"
+	todoD = "00000000-0000-4000-8000-00000000000d"
+)
+
+func TestMoveTodo(t *testing.T) {
+	tests := []struct {
+		name       string
+		positions  map[string]string
+		beforeID   string
+		afterID    string
+		status     int
+		rebalanced int
+	}{
+		{"between ordered neighbors", map[string]string{todoA: "1", todoB: "2", todoC: "3"}, todoA, todoB, http.StatusOK, 0},
+		{"neighbors in the wrong order", map[string]string{todoA: "1", todoB: "2", todoC: "3"}, todoB, todoA, http.StatusBadRequest, 0},
+		{"tied neighbors in the wrong order", map[string]string{todoA: "1", todoB: "1", todoC: "3"}, todoB, todoA, http.StatusBadRequest, 0},
+		{"tied neighbors with a todo between", map[string]string{todoA: "1", todoB: "1", todoC: "3", todoD: "1"}, todoA, todoD, http.StatusBadRequest, 0},
+		{"tied adjacent neighbors", map[string]string{todoA: "1", todoB: "1", todoC: "3"}, todoA, todoB, http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeTodoRepository{}
+			for id, position := range tt.positions {
+				repo.todos = append(repo.todos, &model.Todo{ID: id, UserID: "user", Position: position})
+			}
+			u := &todoUsecase{
+				todoRepository: repo,
+				contextTimeout: time.Second,
+				validate:       validator.New(),
+			}
+
+			response := u.Move(context.Background(), model.JWTClaimUser{UserID: "user"}, todoC, request.MoveTodoRequest{
+				BeforeID: &tt.beforeID,
+				AfterID:  &tt.afterID,
+			})
+			if response.Status != tt.status {
+				t.Fatalf("got %d %q, want %d", response.Status, response.Message, tt.status)
+			}
+			if repo.rebalanced != tt.rebalanced {
+				t.Errorf("rebalanced %d times, want %d", repo.rebalanced, tt.rebalanced)
+			}
+			if tt.status != http.StatusOK && repo.updated != 0 {
+				t.Error("a refused move was saved")
+			}
+			if tt.status == http.StatusOK {
+				order := repo.sorted(map[string]interface{}{})
+				for i, todo := range order {
+					if todo.ID == todoC && (i == 0 || order[i-1].ID != tt.beforeID || i+1 == len(order) || order[i+1].ID != tt.afterID) {
+						t.Errorf("todo landed at %d of %v", i, order)
+					}
+				}
+			}
+		})
+	}
+}
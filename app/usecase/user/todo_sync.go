@@ -0,0 +1,248 @@
+package usecase_user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+)
+
+// SyncChanges is a page of changes since a sync token. When Reset is set the
+// client may have missed deletions and must replace its local copy with the
+// changes, which then start from the beginning.
+type SyncChanges struct {
+	Todos     []*model.Todo `json:"todos"`
+	Deleted   []string      `json:"deleted"`
+	NextToken string        `json:"next_token"`
+	HasMore   bool          `json:"has_more"`
+	Reset     bool          `json:"reset"`
+}
+
+const (
+	SyncResultApplied  = "applied"
+	SyncResultConflict = "conflict"
+	SyncResultNotFound = "not_found"
+	SyncResultRejected = "rejected"
+)
+
+// SyncResult reports what happened to a single client mutation. Todo is the
+// server copy after the mutation, or the conflicting copy on conflict.
+type SyncResult struct {
+	ID      string      `json:"id"`
+	Op      string      `json:"op"`
+	Result  string      `json:"result"`
+	Message string      `json:"message,omitempty"`
+	Todo    *model.Todo `json:"todo,omitempty"`
+}
+
+func (u *todoUsecase) SyncPull(ctx context.Context, claim model.JWTClaimUser, payload request.SyncPullRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	token, err := helpers.ParseSyncToken(payload.Since)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// read the sequence before the changes, everything up to it is committed
+	state, err := u.todoRepository.FindSyncState(ctx, claim.UserID)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// a todo changed after the token was purged since it was issued, its
+	// tombstone is gone so start over
+	reset := state.PurgedSeq > token.PurgedSeq && state.PurgedSeq > token.Seq
+	if reset {
+		token = helpers.SyncToken{}
+	}
+
+	// fetch one extra to know if there is more
+	todos, err := u.todoRepository.FetchChanges(ctx, claim.UserID, token.Seq, payload.Limit+1)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	changes := SyncChanges{
+		Todos:   []*model.Todo{},
+		Deleted: []string{},
+		HasMore: len(todos) > payload.Limit,
+		Reset:   reset,
+	}
+	if changes.HasMore {
+		todos = todos[:payload.Limit]
+	}
+
+	next := helpers.SyncToken{
+		Seq:       token.Seq,
+		PurgedSeq: state.PurgedSeq,
+	}
+	for _, todo := range todos {
+		if todo.DeletedAt != nil {
+			changes.Deleted = append(changes.Deleted, todo.ID)
+		} else {
+			changes.Todos = append(changes.Todos, todo)
+		}
+		next.Seq = todo.SyncSeq
+	}
+	if !changes.HasMore && state.Seq > next.Seq {
+		next.Seq = state.Seq
+	}
+	changes.NextToken = next.String()
+
+	return helpers.Response{
+		Data:    changes,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) SyncPush(ctx context.Context, claim model.JWTClaimUser, payload request.SyncPushRequest) helpers.Response {
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// mutations are applied one by one in order, each with its own timeout
+	results := make([]SyncResult, 0, len(payload.Mutations))
+	for _, mutation := range payload.Mutations {
+		var result SyncResult
+		switch mutation.Op {
+		case request.SyncOpCreate:
+			result = u.syncCreate(ctx, claim, mutation)
+		case request.SyncOpUpdate:
+			result = u.syncUpdate(ctx, claim, mutation)
+		case request.SyncOpDelete:
+			result = u.syncDelete(ctx, claim, mutation)
+		}
+		result.ID = mutation.ID
+		result.Op = mutation.Op
+		results = append(results, result)
+	}
+
+	return helpers.Response{
+		Data:    results,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *todoUsecase) syncCreate(ctx context.Context, claim model.JWTClaimUser, mutation request.SyncMutation) SyncResult {
+	// a retried create was already applied
+	existing, err := u.findSyncTodo(ctx, claim, mutation.ID)
+	if err != nil {
+		return SyncResult{Result: SyncResultRejected, Message: err.Error()}
+	}
+	if existing != nil {
+		return SyncResult{Result: SyncResultApplied, Todo: existing}
+	}
+
+	payload := request.CreateTodoRequest{
+		ID: &mutation.ID,
+	}
+	if mutation.Name != nil {
+		payload.Name = *mutation.Name
+	}
+	if mutation.Status != nil {
+		payload.Status = *mutation.Status
+	}
+
+	response := u.Create(ctx, claim, payload)
+	if response.Status != http.StatusCreated {
+		return SyncResult{Result: SyncResultRejected, Message: response.Message}
+	}
+
+	todo := response.Data.(model.Todo)
+	return SyncResult{Result: SyncResultApplied, Todo: &todo}
+}
+
+func (u *todoUsecase) syncUpdate(ctx context.Context, claim model.JWTClaimUser, mutation request.SyncMutation) SyncResult {
+	// only fields sent by the client are changed
+	fields := map[string]interface{}{}
+	if mutation.Name != nil {
+		fields["name"] = *mutation.Name
+	}
+	if mutation.Status != nil {
+		fields["status"] = *mutation.Status
+	}
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return SyncResult{Result: SyncResultRejected, Message: err.Error()}
+	}
+
+	response := u.PatchOne(ctx, claim, mutation.ID, request.PatchRequest{
+		ContentType: helpers.MergePatchContentType,
+		Patch:       patch,
+		Version:     mutation.Version,
+	})
+	return u.syncResultFromResponse(ctx, claim, mutation, response)
+}
+
+func (u *todoUsecase) syncDelete(ctx context.Context, claim model.JWTClaimUser, mutation request.SyncMutation) SyncResult {
+	response := u.DeleteOne(ctx, claim, mutation.ID, request.DeleteTodoRequest{
+		Version: mutation.Version,
+	})
+	return u.syncResultFromResponse(ctx, claim, mutation, response)
+}
+
+// syncResultFromResponse maps a todo usecase response to a sync result,
+// attaching the server copy on conflict so the client can resolve it.
+func (u *todoUsecase) syncResultFromResponse(ctx context.Context, claim model.JWTClaimUser, mutation request.SyncMutation, response helpers.Response) SyncResult {
+	switch response.Status {
+	case http.StatusOK:
+		todo, _ := response.Data.(*model.Todo)
+		return SyncResult{Result: SyncResultApplied, Todo: todo}
+	case http.StatusPreconditionFailed:
+		todo, err := u.findSyncTodo(ctx, claim, mutation.ID)
+		if err != nil {
+			return SyncResult{Result: SyncResultRejected, Message: err.Error()}
+		}
+		return SyncResult{Result: SyncResultConflict, Message: response.Message, Todo: todo}
+	}
+
+	// trashed todos are not found by the usecase, deleting one is a no-op
+	todo, err := u.findSyncTodo(ctx, claim, mutation.ID)
+	if err != nil {
+		return SyncResult{Result: SyncResultRejected, Message: err.Error()}
+	}
+	if todo != nil && todo.DeletedAt != nil && mutation.Op == request.SyncOpDelete {
+		return SyncResult{Result: SyncResultApplied}
+	}
+	if todo == nil || todo.DeletedAt != nil {
+		return SyncResult{Result: SyncResultNotFound, Message: "todo not found"}
+	}
+	return SyncResult{Result: SyncResultRejected, Message: response.Message}
+}
+
+func (u *todoUsecase) findSyncTodo(ctx context.Context, claim model.JWTClaimUser, todoID string) (*model.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	return u.todoRepository.FindOne(ctx, map[string]interface{}{
+		"id":           todoID,
+		"user_id":      claim.UserID,
+		"with_trashed": true,
+	})
+}
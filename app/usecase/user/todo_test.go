@@ -0,0 +1,53 @@
+package usecase_user
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestDeleteTodoIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		todoID  string
+		header  string
+		status  int
+		deleted bool
+	}{
+		{"wildcard on a missing todo", todoB, `*`, http.StatusPreconditionFailed, false},
+		{"tag on a missing todo", todoB, `"1"`, http.StatusPreconditionFailed, false},
+		{"no precondition on a missing todo", todoB, ``, http.StatusBadRequest, false},
+		{"wildcard", todoA, `*`, http.StatusOK, true},
+		{"listed version", todoA, `"1", "3"`, http.StatusOK, true},
+		{"unlisted version", todoA, `"1", "2"`, http.StatusPreconditionFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeTodoRepository{todos: []*model.Todo{{ID: todoA, UserID: "user", Version: 3}}}
+			u := &todoUsecase{
+				todoRepository: repo,
+				contextTimeout: time.Second,
+				validate:       validator.New(),
+			}
+
+			payload := request.DeleteTodoRequest{}
+			if tt.header != "" {
+				payload.IfMatch, _ = helpers.ParseIfMatch(tt.header)
+			}
+			response := u.DeleteOne(context.Background(), model.JWTClaimUser{UserID: "user"}, tt.todoID, payload)
+			if response.Status != tt.status {
+				t.Fatalf("got %d %q, want %d", response.Status, response.Message, tt.status)
+			}
+			if (repo.deleted > 0) != tt.deleted {
+				t.Errorf("deleted %d times", repo.deleted)
+			}
+		})
+	}
+}
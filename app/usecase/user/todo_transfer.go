@@ -0,0 +1,630 @@
+package usecase_user
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const exportBatchSize = 500
+
+// CalDAVExternalIDPrefix marks todos created by a CalDAV client, the rest of
+// the external id is the resource name picked by the client.
+const CalDAVExternalIDPrefix = "caldav:"
+
+// TodoExport is returned by Export, the handler sets the headers and then
+// streams the todos with Write.
+type TodoExport struct {
+	ContentType string
+	FileName    string
+	Write       func(ctx context.Context, w io.Writer) error
+}
+
+type todoImportRow struct {
+	Row    int      `json:"row"`
+	Action string   `json:"action"`
+	TodoID string   `json:"todo_id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+
+	request.ImportTodoRow
+
+	// exportedID is the id column of our own csv and json exports, it
+	// matches the todo it was exported from.
+	exportedID string
+}
+
+type todoImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Rows      []*todoImportRow `json:"rows"`
+}
+
+const (
+	todoImportActionCreate    = "create"
+	todoImportActionUpdate    = "update"
+	todoImportActionUnchanged = "unchanged"
+	todoImportActionError     = "error"
+)
+
+func (u *todoUsecase) Export(ctx context.Context, claim model.JWTClaimUser, payload request.ExportTodoRequest) helpers.Response {
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	export := &TodoExport{
+		FileName: fmt.Sprintf("todos_%s.%s", time.Now().Format("20060102"), payload.Format),
+	}
+
+	switch payload.Format {
+	case "csv":
+		export.ContentType = "text/csv; charset=utf-8"
+		export.Write = func(ctx context.Context, w io.Writer) error {
+			return u.exportCSV(ctx, claim.UserID, w)
+		}
+	case "ics":
+		export.ContentType = "text/calendar; charset=utf-8"
+		export.Write = func(ctx context.Context, w io.Writer) error {
+			return u.exportICal(ctx, claim.UserID, w)
+		}
+	default:
+		export.ContentType = "application/json; charset=utf-8"
+		export.Write = func(ctx context.Context, w io.Writer) error {
+			return u.exportJSON(ctx, claim.UserID, w)
+		}
+	}
+
+	return helpers.Response{
+		Data:    export,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+// eachTodo pages through all todos of the user in list order.
+func (u *todoUsecase) eachTodo(ctx context.Context, userID string, fn func(todo *model.Todo) error) error {
+	filters := map[string]interface{}{
+		"user_id": userID,
+	}
+
+	for offset := 0; ; offset += exportBatchSize {
+		todos, err := u.todoRepository.FetchList(ctx, offset, exportBatchSize, filters)
+		if err != nil {
+			return err
+		}
+
+		for _, todo := range todos {
+			if err := fn(todo); err != nil {
+				return err
+			}
+		}
+
+		if len(todos) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+func (u *todoUsecase) exportCSV(ctx context.Context, userID string, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"id", "external_id", "name", "status", "position", "created_at", "updated_at"})
+	if err != nil {
+		return err
+	}
+
+	err = u.eachTodo(ctx, userID, func(todo *model.Todo) error {
+		externalID := ""
+		if todo.ExternalID != nil {
+			externalID = *todo.ExternalID
+		}
+		return writer.Write([]string{
+			todo.ID,
+			externalID,
+			todo.Name,
+			string(todo.Status),
+			todo.Position,
+			todo.CreatedAt.Format(time.RFC3339),
+			todo.UpdatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (u *todoUsecase) exportJSON(ctx context.Context, userID string, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err := u.eachTodo(ctx, userID, func(todo *model.Todo) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		data, err := json.Marshal(todo)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+func (u *todoUsecase) exportICal(ctx context.Context, userID string, w io.Writer) error {
+	if err := helpers.WriteICalHeader(w); err != nil {
+		return err
+	}
+
+	err := u.eachTodo(ctx, userID, func(todo *model.Todo) error {
+		return helpers.WriteICalTodo(w, TodoToICal(todo))
+	})
+	if err != nil {
+		return err
+	}
+
+	return helpers.WriteICalFooter(w)
+}
+
+// TodoToICal maps a todo to a VTODO. Todos created over CalDAV keep the
+// UID chosen by the client.
+func TodoToICal(todo *model.Todo) helpers.ICalTodo {
+	status := "NEEDS-ACTION"
+	if todo.Status == model.TodoStatusDone {
+		status = "COMPLETED"
+	}
+
+	uid := todo.ID
+	if todo.ExternalID != nil && strings.HasPrefix(*todo.ExternalID, CalDAVExternalIDPrefix) {
+		uid = strings.TrimPrefix(*todo.ExternalID, CalDAVExternalIDPrefix)
+	}
+
+	return helpers.ICalTodo{
+		UID:          uid,
+		Summary:      todo.Name,
+		Status:       status,
+		Created:      todo.CreatedAt,
+		LastModified: todo.UpdatedAt,
+		SortOrder:    todo.Position,
+	}
+}
+
+// TodoStatusFromICal maps a VTODO status to a todo status.
+func TodoStatusFromICal(status string) model.TodoStatus {
+	if strings.EqualFold(status, "COMPLETED") {
+		return model.TodoStatusDone
+	}
+	return model.TodoStatusNotStarted
+}
+
+func (u *todoUsecase) Import(ctx context.Context, claim model.JWTClaimUser, payload request.ImportTodoRequest, body io.Reader) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// parse rows
+	rows, err := parseTodoImport(payload.Format, body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  status,
+		}
+	}
+
+	report := &todoImportReport{
+		DryRun: payload.DryRun == nil || *payload.DryRun,
+		Total:  len(rows),
+		Rows:   rows,
+	}
+
+	// validate rows
+	seen := map[string]bool{}
+	externalIDs := []string{}
+	exportedIDs := []string{}
+	for _, row := range rows {
+		if err := u.validate.Struct(row.ImportTodoRow); err != nil {
+			for _, fieldErr := range err.(validator.ValidationErrors) {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s failed on %s", fieldErr.Field(), fieldErr.Tag()))
+			}
+		}
+		if seen[row.ExternalID] {
+			row.Errors = append(row.Errors, "duplicate external_id in file")
+		}
+		seen[row.ExternalID] = true
+
+		if len(row.Errors) > 0 {
+			row.Action = todoImportActionError
+			report.Failed++
+			continue
+		}
+		externalIDs = append(externalIDs, row.ExternalID)
+		if _, err := uuid.Parse(row.exportedID); err == nil {
+			exportedIDs = append(exportedIDs, row.exportedID)
+		}
+	}
+
+	// find todos imported by a previous run
+	existing := map[string]*model.Todo{}
+	if len(externalIDs) > 0 {
+		todos, err := u.todoRepository.FetchList(ctx, 0, len(externalIDs), map[string]interface{}{
+			"user_id":      claim.UserID,
+			"external_ids": externalIDs,
+			"with_trashed": true,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		for _, todo := range todos {
+			existing[*todo.ExternalID] = todo
+		}
+	}
+
+	// find todos a re-imported export was made from
+	exported := map[string]*model.Todo{}
+	if len(exportedIDs) > 0 {
+		todos, err := u.todoRepository.FetchList(ctx, 0, len(exportedIDs), map[string]interface{}{
+			"user_id":      claim.UserID,
+			"ids":          exportedIDs,
+			"with_trashed": true,
+		})
+		if err != nil {
+			return helpers.Response{
+				Data:    nil,
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		for _, todo := range todos {
+			exported[todo.ID] = todo
+		}
+	}
+
+	// plan creates and updates
+	creates := []*model.Todo{}
+	updates := []*model.Todo{}
+	for _, row := range rows {
+		if row.Action == todoImportActionError {
+			continue
+		}
+
+		todo, ok := existing[row.ExternalID]
+		if !ok {
+			todo, ok = exported[row.exportedID]
+		}
+		switch {
+		case !ok:
+			externalID := row.ExternalID
+			todo = &model.Todo{
+				ID:         uuid.New().String(),
+				UserID:     claim.UserID,
+				ExternalID: &externalID,
+				Name:       row.Name,
+				Status:     row.Status,
+			}
+			creates = append(creates, todo)
+			row.Action = todoImportActionCreate
+			report.Created++
+		case todo.DeletedAt == nil && (todo.Name != row.Name || todo.Status != row.Status):
+			todo.Name = row.Name
+			todo.Status = row.Status
+			updates = append(updates, todo)
+			row.Action = todoImportActionUpdate
+			report.Updated++
+		default:
+			// unchanged, or deleted by the user since the last import
+			row.Action = todoImportActionUnchanged
+			report.Unchanged++
+		}
+		row.TodoID = todo.ID
+	}
+
+	if report.DryRun {
+		return helpers.Response{
+			Data:    report,
+			Message: "dry run, nothing imported",
+			Status:  http.StatusOK,
+		}
+	}
+	if report.Failed > 0 {
+		return helpers.Response{
+			Data:    report,
+			Message: "import has invalid rows, nothing imported",
+			Status:  http.StatusUnprocessableEntity,
+		}
+	}
+
+	// append new todos at the end of the list
+	position, err := u.positionBetween(ctx, claim.UserID, "", nil, nil)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	for _, todo := range creates {
+		todo.Position = position
+		position, _ = helpers.RankBetween(position, "")
+	}
+
+	// save todos
+	err = u.todoRepository.Import(ctx, creates, updates)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    report,
+		Message: "todos successfully imported",
+		Status:  http.StatusOK,
+	}
+}
+
+func parseTodoImport(format string, body io.Reader) ([]*todoImportRow, error) {
+	switch format {
+	case "csv":
+		return parseTodoImportCSV(body)
+	case "json":
+		return parseTodoImportJSON(body)
+	case "ics":
+		return parseTodoImportICal(body)
+	case "todoist":
+		return parseTodoImportTodoist(body)
+	case "trello":
+		return parseTodoImportTrello(body)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+func parseTodoImportCSV(body io.Reader) ([]*todoImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("csv must have a name column")
+	}
+
+	rows := []*todoImportRow{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv on line %d: %w", line, err)
+		}
+
+		column := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		externalID := column("external_id")
+		if externalID == "" {
+			externalID = column("id")
+		}
+		row := newTodoImportRow(line, "csv", externalID, column("name"), column("status"))
+		row.exportedID = column("id")
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseTodoImportJSON(body io.Reader) ([]*todoImportRow, error) {
+	var items []struct {
+		ID         interface{} `json:"id"`
+		ExternalID interface{} `json:"external_id"`
+		Name       string      `json:"name"`
+		Status     string      `json:"status"`
+	}
+	if err := decodeImportJSON(body, &items); err != nil {
+		return nil, err
+	}
+
+	rows := []*todoImportRow{}
+	for i, item := range items {
+		externalID := importID(item.ExternalID)
+		if externalID == "" {
+			externalID = importID(item.ID)
+		}
+		row := newTodoImportRow(i+1, "json", externalID, item.Name, item.Status)
+		row.exportedID = importID(item.ID)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseTodoImportICal(body io.Reader) ([]*todoImportRow, error) {
+	todos, err := helpers.ParseICalTodos(body)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*todoImportRow{}
+	for i, todo := range todos {
+		rows = append(rows, newTodoImportRow(i+1, "ics", todo.UID, todo.Summary, todo.Status))
+	}
+
+	return rows, nil
+}
+
+// parseTodoImportTodoist accepts both the REST task list and the sync
+// export, which wraps the tasks in "items".
+func parseTodoImportTodoist(body io.Reader) ([]*todoImportRow, error) {
+	type todoistTask struct {
+		ID          interface{} `json:"id"`
+		Content     string      `json:"content"`
+		Checked     interface{} `json:"checked"`
+		IsCompleted bool        `json:"is_completed"`
+	}
+
+	var raw json.RawMessage
+	if err := decodeImportJSON(body, &raw); err != nil {
+		return nil, err
+	}
+
+	var tasks []todoistTask
+	if err := decodeImportJSON(bytes.NewReader(raw), &tasks); err != nil {
+		var export struct {
+			Items []todoistTask `json:"items"`
+		}
+		if err := decodeImportJSON(bytes.NewReader(raw), &export); err != nil {
+			return nil, errors.New("invalid todoist export")
+		}
+		tasks = export.Items
+	}
+
+	rows := []*todoImportRow{}
+	for i, task := range tasks {
+		status := "NotStarted"
+		if task.IsCompleted || task.Checked == true || fmt.Sprint(task.Checked) == "1" {
+			status = "Done"
+		}
+		rows = append(rows, newTodoImportRow(i+1, "todoist", prefixImportID("todoist", importID(task.ID)), task.Content, status))
+	}
+
+	return rows, nil
+}
+
+func parseTodoImportTrello(body io.Reader) ([]*todoImportRow, error) {
+	var board struct {
+		Cards []struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			Closed      bool   `json:"closed"`
+			DueComplete bool   `json:"dueComplete"`
+		} `json:"cards"`
+	}
+	if err := decodeImportJSON(body, &board); err != nil {
+		return nil, err
+	}
+
+	rows := []*todoImportRow{}
+	for i, card := range board.Cards {
+		status := "NotStarted"
+		if card.Closed || card.DueComplete {
+			status = "Done"
+		}
+		rows = append(rows, newTodoImportRow(i+1, "trello", prefixImportID("trello", card.ID), card.Name, status))
+	}
+
+	return rows, nil
+}
+
+func decodeImportJSON(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return nil
+}
+
+func newTodoImportRow(line int, format, externalID, name, status string) *todoImportRow {
+	// rows without an id get a stable one so re-running the import is idempotent
+	if externalID == "" {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", format, line, name)))
+		externalID = "row:" + hex.EncodeToString(sum[:16])
+	}
+
+	return &todoImportRow{
+		Row: line,
+		ImportTodoRow: request.ImportTodoRow{
+			ExternalID: externalID,
+			Name:       strings.TrimSpace(name),
+			Status:     parseImportStatus(status),
+		},
+	}
+}
+
+func parseImportStatus(status string) model.TodoStatus {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "done", "completed", "complete", "true", "1", "x":
+		return model.TodoStatusDone
+	case "", "notstarted", "not_started", "todo", "needs-action", "in-process", "false", "0":
+		return model.TodoStatusNotStarted
+	default:
+		return model.TodoStatus(status)
+	}
+}
+
+func importID(id interface{}) string {
+	if id == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(id))
+}
+
+func prefixImportID(source, id string) string {
+	if id == "" {
+		return ""
+	}
+	return source + ":" + id
+}
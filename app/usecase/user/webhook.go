@@ -0,0 +1,439 @@
+package usecase_user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/app/usecase"
+	"golang-gorm/domain/model"
+	"golang-gorm/domain/request"
+	"golang-gorm/helpers"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+const maxWebhooksPerUser = 20
+
+type webhookUsecase struct {
+	webhookRepository         postgresrepo.WebhookRepository
+	webhookDeliveryRepository postgresrepo.WebhookDeliveryRepository
+	deliverer                 *webhookDeliverer
+	contextTimeout            time.Duration
+	validate                  *validator.Validate
+}
+
+func NewWebhookUsecase(d usecase.UsecaseDependency) WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepository:         d.WebhookRepository,
+		webhookDeliveryRepository: d.WebhookDeliveryRepository,
+		deliverer: newWebhookDeliverer(d.WebhookRepository, d.WebhookDeliveryRepository, webhookDeliveryConfig{
+			MaxAttempts:          viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			DisableAfter:         viper.GetInt("WEBHOOK_DISABLE_AFTER"),
+			AllowPrivateNetworks: viper.GetBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS"),
+		}),
+		contextTimeout: d.Timeout,
+		validate:       d.Validate,
+	}
+}
+
+type WebhookUsecase interface {
+	GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse
+	GetOne(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response
+	Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateWebhookRequest) helpers.Response
+	UpdateOne(ctx context.Context, claim model.JWTClaimUser, webhookID string, payload request.UpdateWebhookRequest) helpers.Response
+	DeleteOne(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response
+	SendTest(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response
+	GetDeliveries(ctx context.Context, claim model.JWTClaimUser, webhookID string, query url.Values) helpers.PaginatedResponse
+	HandleTodoEvent(ctx context.Context, event model.TodoEvent) error
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+// WebhookWithSecret is only returned on creation, the secret is not shown again.
+type WebhookWithSecret struct {
+	*model.Webhook
+	Secret string `json:"secret"`
+}
+
+func (u *webhookUsecase) GetAll(ctx context.Context, claim model.JWTClaimUser, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"user_id": claim.UserID,
+	}
+
+	// count first
+	totalData, err := u.webhookRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count webhook",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	webhooks, err := u.webhookRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch webhook",
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    webhooks,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+func (u *webhookUsecase) GetOne(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	webhook, err := u.webhookRepository.FindOne(ctx, map[string]interface{}{
+		"id":      webhookID,
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if webhook == nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: "webhook not found",
+			Status:  http.StatusBadRequest,
+			Err:     helpers.ErrNotFound,
+		}
+	}
+
+	return helpers.Response{
+		Data:    webhook,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *webhookUsecase) Create(ctx context.Context, claim model.JWTClaimUser, payload request.CreateWebhookRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// check webhook limit
+	total, err := u.webhookRepository.Count(ctx, map[string]interface{}{
+		"user_id": claim.UserID,
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	if total >= maxWebhooksPerUser {
+		return helpers.Response{
+			Data:    nil,
+			Message: "webhook limit reached",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	secret, err := helpers.GenerateWebhookSecret()
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// create webhook
+	webhook := model.Webhook{
+		ID:         uuid.New().String(),
+		UserID:     claim.UserID,
+		URL:        payload.URL,
+		Secret:     secret,
+		EventTypes: payload.EventTypes,
+		IsActive:   true,
+	}
+
+	// save webhook
+	err = u.webhookRepository.Create(ctx, &webhook)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data: WebhookWithSecret{
+			Webhook: &webhook,
+			Secret:  webhook.Secret,
+		},
+		Message: "success",
+		Status:  http.StatusCreated,
+	}
+}
+
+func (u *webhookUsecase) UpdateOne(ctx context.Context, claim model.JWTClaimUser, webhookID string, payload request.UpdateWebhookRequest) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// validate payload
+	validationResponse, err := helpers.ValidateBody(u.validate, payload)
+	if err != nil {
+		return validationResponse
+	}
+
+	// check webhook exist
+	response := u.GetOne(ctx, claim, webhookID)
+	if response.Status != http.StatusOK {
+		return response
+	}
+	webhook := response.Data.(*model.Webhook)
+
+	// update webhook
+	webhook.URL = payload.URL
+	webhook.EventTypes = payload.EventTypes
+	if payload.IsActive != nil {
+		// re-enabling gives the endpoint a fresh start
+		if *payload.IsActive && !webhook.IsActive {
+			webhook.FailureCount = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.IsActive = *payload.IsActive
+	}
+
+	// save webhook
+	err = u.webhookRepository.Update(ctx, webhook)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    webhook,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *webhookUsecase) DeleteOne(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check webhook exist
+	response := u.GetOne(ctx, claim, webhookID)
+	if response.Status != http.StatusOK {
+		return response
+	}
+	webhook := response.Data.(*model.Webhook)
+
+	// delete webhook
+	err := u.webhookRepository.DeleteOne(ctx, webhook)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    nil,
+		Message: "webhook successfully deleted",
+		Status:  http.StatusOK,
+	}
+}
+
+// SendTest delivers a test event right away and returns the delivery. Test
+// deliveries are attempted once and don't count towards disabling the webhook.
+func (u *webhookUsecase) SendTest(ctx context.Context, claim model.JWTClaimUser, webhookID string) helpers.Response {
+	// check webhook exist
+	response := u.GetOne(ctx, claim, webhookID)
+	if response.Status != http.StatusOK {
+		return response
+	}
+	webhook := response.Data.(*model.Webhook)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":          uuid.New().String(),
+		"type":        model.WebhookEventTest,
+		"user_id":     claim.UserID,
+		"occurred_at": time.Now(),
+	})
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	delivery, err := u.deliverer.sendTest(ctx, webhook, payload)
+	if err != nil {
+		return helpers.Response{
+			Data:    nil,
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return helpers.Response{
+		Data:    delivery,
+		Message: "success",
+		Status:  http.StatusOK,
+	}
+}
+
+func (u *webhookUsecase) GetDeliveries(ctx context.Context, claim model.JWTClaimUser, webhookID string, query url.Values) helpers.PaginatedResponse {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// check webhook exist
+	response := u.GetOne(ctx, claim, webhookID)
+	if response.Status != http.StatusOK {
+		return helpers.PaginatedResponse{
+			Status:  response.Status,
+			Message: response.Message,
+			Err:     response.Err,
+		}
+	}
+
+	// get offset & limit
+	page, offset, limit := helpers.GetOffsetLimit(query)
+
+	filters := map[string]interface{}{
+		"webhook_id": webhookID,
+	}
+
+	// count first
+	totalData, err := u.webhookDeliveryRepository.Count(ctx, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error count delivery",
+		}
+	}
+
+	if totalData == 0 {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusOK,
+			Message: "success",
+			Data:    []interface{}{},
+			Meta: map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": totalData,
+			},
+		}
+	}
+
+	// fetch data
+	deliveries, err := u.webhookDeliveryRepository.FetchList(ctx, offset, limit, filters)
+	if err != nil {
+		return helpers.PaginatedResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "error fetch delivery",
+		}
+	}
+
+	return helpers.PaginatedResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    deliveries,
+		Meta: map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": totalData,
+		},
+	}
+}
+
+// HandleTodoEvent queues a delivery of the event for every active webhook of
+// the user subscribed to it. Deliveries are sent by DeliverDue, an event
+// relayed twice doesn't queue a second delivery.
+func (u *webhookUsecase) HandleTodoEvent(ctx context.Context, event model.TodoEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	webhooks, err := u.webhookRepository.FetchList(ctx, 0, maxWebhooksPerUser, map[string]interface{}{
+		"user_id":    event.UserID,
+		"is_active":  true,
+		"event_type": string(event.Type),
+	})
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]*model.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			Payload:       payload,
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	return u.webhookDeliveryRepository.Create(ctx, deliveries)
+}
+
+// DeliverDue attempts the pending deliveries that are due and returns how
+// many were attempted.
+func (u *webhookUsecase) DeliverDue(ctx context.Context) (int, error) {
+	return u.deliverer.deliverDue(ctx)
+}
@@ -0,0 +1,257 @@
+package usecase_user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	postgresrepo "golang-gorm/app/repository/postgres"
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookTimeout         = 10 * time.Second
+	webhookBatchSize       = 50
+	webhookBaseBackoff     = time.Minute
+	webhookMaxBackoff      = 6 * time.Hour
+	webhookResponseMaxSize = 1024
+)
+
+var errWebhookPrivateAddress = errors.New("webhook url resolves to a private address")
+
+type webhookDeliveryConfig struct {
+	// MaxAttempts is how often a delivery is tried before it is given up
+	MaxAttempts int
+	// DisableAfter is how many deliveries in a row may be given up before
+	// the webhook is disabled
+	DisableAfter int
+	// AllowPrivateNetworks lets webhooks reach loopback and private
+	// addresses, for local development only
+	AllowPrivateNetworks bool
+}
+
+// webhookDeliverer sends webhook deliveries and records their outcome. It is
+// shared by the delivery job and the test endpoint.
+type webhookDeliverer struct {
+	webhookRepository         postgresrepo.WebhookRepository
+	webhookDeliveryRepository postgresrepo.WebhookDeliveryRepository
+	client                    *http.Client
+	config                    webhookDeliveryConfig
+}
+
+func newWebhookDeliverer(webhookRepository postgresrepo.WebhookRepository, webhookDeliveryRepository postgresrepo.WebhookDeliveryRepository, config webhookDeliveryConfig) *webhookDeliverer {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.DisableAfter <= 0 {
+		config.DisableAfter = 5
+	}
+
+	return &webhookDeliverer{
+		webhookRepository:         webhookRepository,
+		webhookDeliveryRepository: webhookDeliveryRepository,
+		client:                    newWebhookClient(config.AllowPrivateNetworks),
+		config:                    config,
+	}
+}
+
+// newWebhookClient returns a client that doesn't follow redirects and, unless
+// allowPrivate is set, refuses to connect to internal addresses so webhooks
+// can't be used to probe the network the app runs in.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (d *webhookDeliverer) deliverDue(ctx context.Context) (int, error) {
+	// the lease outlasts an attempt so nobody else picks the delivery meanwhile
+	deliveries, err := d.webhookDeliveryRepository.ClaimDue(ctx, webhookBatchSize, 2*webhookTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			logrus.WithField("delivery_id", delivery.ID).Error(err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *webhookDeliverer) deliver(ctx context.Context, delivery *model.WebhookDelivery) error {
+	webhook, err := d.webhookRepository.FindOne(ctx, map[string]interface{}{
+		"id": delivery.WebhookID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// webhook deleted or disabled since the delivery was queued
+	if webhook == nil || !webhook.IsActive {
+		message := "webhook is disabled"
+		if webhook == nil {
+			message = "webhook was deleted"
+		}
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = &message
+		return d.webhookDeliveryRepository.Update(ctx, delivery)
+	}
+
+	succeeded := d.attempt(ctx, webhook, delivery)
+	switch {
+	case succeeded:
+		delivery.Status = model.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(webhookBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.webhookDeliveryRepository.Update(ctx, delivery); err != nil {
+		return err
+	}
+
+	// track the endpoint's health per delivery, not per attempt
+	switch delivery.Status {
+	case model.WebhookDeliverySucceeded:
+		return d.webhookRepository.RecordSuccess(ctx, webhook.ID)
+	case model.WebhookDeliveryFailed:
+		updated, err := d.webhookRepository.RecordFailure(ctx, webhook.ID, d.config.DisableAfter)
+		if err != nil {
+			return err
+		}
+		if !updated.IsActive && webhook.IsActive {
+			logrus.WithField("webhook_id", webhook.ID).Warn("webhook disabled after repeated failures")
+		}
+	}
+
+	return nil
+}
+
+func (d *webhookDeliverer) sendTest(ctx context.Context, webhook *model.Webhook, payload []byte) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: webhook.ID,
+		EventID:   uuid.New().String(),
+		EventType: model.WebhookEventTest,
+		Payload:   payload,
+		Status:    model.WebhookDeliveryFailed,
+	}
+	if d.attempt(ctx, webhook, delivery) {
+		delivery.Status = model.WebhookDeliverySucceeded
+	}
+
+	if err := d.webhookDeliveryRepository.Create(ctx, []*model.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// attempt sends the delivery once and records the response on it. It reports
+// whether the endpoint answered with a 2xx status.
+func (d *webhookDeliverer) attempt(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) bool {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.ResponseBody = nil
+	delivery.Error = nil
+
+	status, body, err := d.send(ctx, webhook, delivery)
+	if err != nil {
+		message := err.Error()
+		delivery.Error = &message
+		return false
+	}
+
+	delivery.ResponseStatus = &status
+	delivery.ResponseBody = &body
+	if status < 200 || status >= 300 {
+		message := fmt.Sprintf("endpoint responded with status %d", status)
+		delivery.Error = &message
+		return false
+	}
+
+	return true
+}
+
+func (d *webhookDeliverer) send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-gorm-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", helpers.SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseMaxSize))
+	return resp.StatusCode, string(bytes.ToValidUTF8(body, nil)), nil
+}
+
+// webhookBackoff doubles the wait after every failed attempt, with up to 10%
+// jitter so failing endpoints aren't retried in lockstep.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff/10)+1))
+}
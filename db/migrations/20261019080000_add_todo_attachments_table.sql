@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE files ADD COLUMN user_id UUID REFERENCES users(id);
+UPDATE files SET user_id = users.id FROM users WHERE users.avatar_id = files.id;
+
+CREATE INDEX idx_files_user_id ON files (user_id); -- +create index
+
+CREATE TABLE IF NOT EXISTS todo_attachments (
+    "todo_id" UUID NOT NULL,
+    "file_id" UUID NOT NULL,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("todo_id", "file_id"),
+    FOREIGN KEY ("todo_id") REFERENCES todos("id") ON DELETE CASCADE,
+    FOREIGN KEY ("file_id") REFERENCES files("id") ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS todo_attachments;
+DROP INDEX IF EXISTS idx_files_user_id;
+ALTER TABLE files DROP COLUMN user_id;
+-- +goose StatementEnd
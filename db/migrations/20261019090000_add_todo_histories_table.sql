@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS todo_histories (
+    "id" UUID PRIMARY KEY NOT NULL,
+    "todo_id" UUID NOT NULL,
+    "user_id" UUID NOT NULL,
+    "event" varchar(50) NOT NULL,
+    "changes" jsonb,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("todo_id") REFERENCES todos("id") ON DELETE CASCADE,
+    FOREIGN KEY ("user_id") REFERENCES users("id")
+);
+
+CREATE INDEX idx_todo_histories_todo_id ON todo_histories (todo_id, created_at); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todo_histories_todo_id;
+DROP TABLE IF EXISTS todo_histories;
+-- +goose StatementEnd
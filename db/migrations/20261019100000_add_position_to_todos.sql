@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE todos ADD COLUMN position varchar(255) COLLATE "C";
+
+-- rank existing todos in creation order
+UPDATE todos SET position = ranked.position
+FROM (
+    SELECT id, lpad(row_number() OVER (PARTITION BY user_id ORDER BY created_at, id)::text, 8, '0') || 'V' AS position
+    FROM todos
+) ranked
+WHERE todos.id = ranked.id;
+
+ALTER TABLE todos ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX idx_todos_user_id_position ON todos (user_id, position); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_user_id_position;
+ALTER TABLE todos DROP COLUMN position;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE todos ADD COLUMN external_id varchar(255);
+
+CREATE UNIQUE INDEX idx_todos_user_id_external_id ON todos (user_id, external_id) WHERE external_id IS NOT NULL; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_user_id_external_id;
+ALTER TABLE todos DROP COLUMN external_id;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    "user_id" UUID NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "fingerprint" varchar(64) NOT NULL,
+    "status" int NOT NULL DEFAULT 0,
+    "content_type" varchar(255),
+    "body" bytea,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" timestamp NOT NULL,
+    PRIMARY KEY ("user_id", "key"),
+    FOREIGN KEY ("user_id") REFERENCES users("id") ON DELETE CASCADE
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_idempotency_keys_expires_at;
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE todos ADD COLUMN sync_seq bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS sync_states (
+    "user_id" UUID PRIMARY KEY NOT NULL,
+    "seq" bigint NOT NULL DEFAULT 0,
+    "purged_seq" bigint NOT NULL DEFAULT 0,
+    FOREIGN KEY ("user_id") REFERENCES users("id") ON DELETE CASCADE
+);
+
+-- number existing todos per user in the order they were last changed
+UPDATE todos SET sync_seq = ranked.seq
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY updated_at, id) AS seq FROM todos
+) AS ranked
+WHERE todos.id = ranked.id;
+
+INSERT INTO sync_states (user_id, seq)
+SELECT user_id, MAX(sync_seq) FROM todos GROUP BY user_id;
+
+CREATE INDEX idx_todos_user_id_sync_seq ON todos (user_id, sync_seq); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_user_id_sync_seq;
+DROP TABLE IF EXISTS sync_states;
+ALTER TABLE todos DROP COLUMN sync_seq;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhooks (
+    "id" UUID PRIMARY KEY NOT NULL,
+    "user_id" UUID NOT NULL,
+    "url" varchar(2048) NOT NULL,
+    "secret" varchar(255) NOT NULL,
+    "event_types" jsonb NOT NULL DEFAULT '[]',
+    "is_active" boolean NOT NULL DEFAULT true,
+    "failure_count" int NOT NULL DEFAULT 0,
+    "disabled_at" timestamp,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamp,
+    FOREIGN KEY ("user_id") REFERENCES users("id") ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks (user_id) WHERE deleted_at IS NULL; -- +create index
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    "id" UUID PRIMARY KEY NOT NULL,
+    "webhook_id" UUID NOT NULL,
+    "event_id" UUID NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "payload" jsonb NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" int NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamp,
+    "last_attempt_at" timestamp,
+    "response_status" int,
+    "response_body" text,
+    "error" text,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("webhook_id") REFERENCES webhooks("id") ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at); -- +create index
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_webhook_deliveries_pending;
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_id;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhooks_user_id;
+DROP TABLE IF EXISTS webhooks;
+-- +goose StatementEnd
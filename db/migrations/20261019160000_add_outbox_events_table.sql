@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS outbox_events (
+    "id" bigserial PRIMARY KEY NOT NULL,
+    "event_id" UUID NOT NULL UNIQUE,
+    "aggregate_type" varchar(50) NOT NULL,
+    "aggregate_id" UUID NOT NULL,
+    "user_id" UUID NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "payload" jsonb NOT NULL,
+    "attempts" int NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_error" text,
+    "delivered_sinks" jsonb NOT NULL DEFAULT '[]',
+    "published_at" timestamp,
+    "failed_at" timestamp,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (aggregate_type, aggregate_id, id) WHERE published_at IS NULL AND failed_at IS NULL; -- +create index
+CREATE INDEX idx_outbox_events_published_at ON outbox_events (published_at) WHERE published_at IS NOT NULL; -- +create index
+
+-- the relay delivers at least once, a redelivered event must not queue a second webhook delivery
+CREATE UNIQUE INDEX idx_webhook_deliveries_webhook_event ON webhook_deliveries (webhook_id, event_id); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_event;
+DROP INDEX IF EXISTS idx_outbox_events_published_at;
+DROP INDEX IF EXISTS idx_outbox_events_unpublished;
+DROP TABLE IF EXISTS outbox_events;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE files ADD COLUMN "object_key" varchar(1024);
+ALTER TABLE files ADD COLUMN "status" varchar(20) NOT NULL DEFAULT 'ready';
+
+-- pending uploads are swept once their presigned url has long expired
+CREATE INDEX idx_files_pending ON files (created_at) WHERE status = 'pending' AND deleted_at IS NULL; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_files_pending;
+ALTER TABLE files DROP COLUMN "status";
+ALTER TABLE files DROP COLUMN "object_key";
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- urls were S3_ENDPOINT/bucket/key, the endpoint has no path of its own
+UPDATE files SET "object_key" = regexp_replace("url", '^[a-z][a-z0-9+.-]*://[^/]+/[^/]+/', '') WHERE "object_key" IS NULL;
+
+ALTER TABLE files ALTER COLUMN "object_key" SET NOT NULL;
+ALTER TABLE files DROP COLUMN "url";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the endpoint and bucket aren't known here, the key is the best we have
+ALTER TABLE files ADD COLUMN "url" varchar(1024);
+UPDATE files SET "url" = "object_key";
+ALTER TABLE files ALTER COLUMN "url" SET NOT NULL;
+ALTER TABLE files ALTER COLUMN "object_key" DROP NOT NULL;
+-- +goose StatementEnd
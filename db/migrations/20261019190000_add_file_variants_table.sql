@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS file_variants (
+    "id" UUID PRIMARY KEY NOT NULL,
+    "file_id" UUID NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "mime_type" varchar(255) NOT NULL,
+    "size" bigint NOT NULL,
+    "width" int NOT NULL,
+    "height" int NOT NULL,
+    "object_key" varchar(1024) NOT NULL,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("file_id") REFERENCES files("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_file_variants_file_id_name ON file_variants (file_id, name); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_file_variants_file_id_name;
+DROP TABLE IF EXISTS file_variants;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the file sweep looks files up by their references and objects by key
+CREATE INDEX idx_users_avatar_id ON users (avatar_id); -- +create index
+CREATE INDEX idx_todo_attachments_file_id ON todo_attachments (file_id); -- +create index
+CREATE INDEX idx_files_object_key ON files (object_key); -- +create index
+CREATE INDEX idx_file_variants_object_key ON file_variants (object_key); -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_file_variants_object_key;
+DROP INDEX IF EXISTS idx_files_object_key;
+DROP INDEX IF EXISTS idx_todo_attachments_file_id;
+DROP INDEX IF EXISTS idx_users_avatar_id;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- existing files were never scanned, they are picked up once scanning is enabled
+ALTER TABLE files ADD COLUMN "scan_status" varchar(20) NOT NULL DEFAULT 'pending';
+
+CREATE INDEX idx_files_scan_pending ON files (created_at) WHERE scan_status = 'pending' AND status = 'ready' AND deleted_at IS NULL; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_files_scan_pending;
+ALTER TABLE files DROP COLUMN "scan_status";
+-- +goose StatementEnd
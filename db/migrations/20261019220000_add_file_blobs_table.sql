@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE files ADD COLUMN "sha256" char(64);
+ALTER TABLE file_variants ADD COLUMN "sha256" char(64);
+
+-- objects shared by files with the same content, existing objects stay
+-- owned by their single file
+CREATE TABLE IF NOT EXISTS file_blobs (
+    "object_key" varchar(1024) PRIMARY KEY,
+    "sha256" char(64) NOT NULL,
+    "size" bigint NOT NULL,
+    "mime_type" varchar(255) NOT NULL,
+    "ref_count" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_file_blobs_unused ON file_blobs (updated_at) WHERE ref_count <= 0; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS file_blobs;
+ALTER TABLE file_variants DROP COLUMN "sha256";
+ALTER TABLE files DROP COLUMN "sha256";
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN "plan" varchar(50) NOT NULL DEFAULT 'free';
+ALTER TABLE users ADD COLUMN "storage_quota_mb" bigint;
+
+-- bytes and count of the live files of each user by mimetype, variants
+-- count towards their file
+CREATE TABLE IF NOT EXISTS storage_usages (
+    "user_id" UUID NOT NULL,
+    "mime_type" varchar(255) NOT NULL,
+    "bytes" bigint NOT NULL DEFAULT 0,
+    "files" bigint NOT NULL DEFAULT 0,
+    "updated_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("user_id", "mime_type")
+);
+
+INSERT INTO storage_usages (user_id, mime_type, bytes, files)
+SELECT user_id, mime_type,
+    SUM(size + COALESCE((SELECT SUM(size) FROM file_variants WHERE file_variants.file_id = files.id), 0)),
+    COUNT(*)
+FROM files
+WHERE user_id IS NOT NULL AND deleted_at IS NULL
+GROUP BY user_id, mime_type;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS storage_usages;
+ALTER TABLE users DROP COLUMN "storage_quota_mb";
+ALTER TABLE users DROP COLUMN "plan";
+-- +goose StatementEnd
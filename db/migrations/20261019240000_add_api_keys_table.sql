@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS api_keys (
+    "id" UUID PRIMARY KEY NOT NULL,
+    "user_id" UUID NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "prefix" varchar(20) NOT NULL,
+    "key_hash" varchar(64) NOT NULL,
+    "last_used_at" timestamp,
+    "created_at" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" timestamp,
+    FOREIGN KEY ("user_id") REFERENCES users("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash); -- +create index
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id) WHERE deleted_at IS NULL; -- +create index
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+DROP INDEX IF EXISTS idx_api_keys_key_hash;
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
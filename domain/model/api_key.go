@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// APIKey lets a client that can't log in, like a CalDAV client, act as the
+// user. Only the sha256 of the key is stored, Prefix is kept so the user can
+// tell their keys apart.
+type APIKey struct {
+	ID         string     `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	UserID     string     `gorm:"column:user_id;type:uuid;not null" json:"user_id"`
+	Name       string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	Prefix     string     `gorm:"column:prefix;type:varchar(20);not null" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;type:varchar(64);not null;unique" json:"-"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	DeletedAt  *time.Time `gorm:"column:deleted_at;index" json:"-"`
+
+	User *User `gorm:"foreignKey:user_id;references:id" json:"-"`
+}
+
+func (m *APIKey) TableName() string {
+	return "api_keys"
+}
@@ -2,19 +2,108 @@ package model
 
 import "time"
 
+type FileStatus string
+
+const (
+	// FileStatusPending is a presigned upload the client hasn't completed.
+	FileStatusPending FileStatus = "pending"
+	FileStatusReady   FileStatus = "ready"
+)
+
+// ScanStatus is what the malware scan found, pending until a scanner looked
+// at the file.
+type ScanStatus string
+
+const (
+	ScanStatusPending  ScanStatus = "pending"
+	ScanStatusClean    ScanStatus = "clean"
+	ScanStatusInfected ScanStatus = "infected"
+)
+
+// File is a stored file. Sha256 is set once the object is a FileBlob shared
+// with every file of the same content, until the file is quarantined.
 type File struct {
-	ID        string     `gorm:"column:id;type:uuid;primary_key" json:"id"`
-	Name      string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
-	MimeType  string     `gorm:"column:mime_type;type:varchar(255);not null" json:"mime_type"`
-	Size      int64      `gorm:"column:size;type:bigint;not null" json:"size"`
-	Url       string     `gorm:"column:url;type:varchar(255);not null" json:"url"`
-	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
-	DeletedAt *time.Time `gorm:"column:deleted_at;index" json:"-"`
-
-	User []User `gorm:"foreignKey:avatar_id;references:id;constraint:OnDelete:SET NULL" json:"user,omitempty"`
+	ID         string     `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	UserID     *string    `gorm:"column:user_id;type:uuid" json:"user_id"`
+	Name       string     `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	MimeType   string     `gorm:"column:mime_type;type:varchar(255);not null" json:"mime_type"`
+	Size       int64      `gorm:"column:size;type:bigint;not null" json:"size"`
+	ObjectKey  string     `gorm:"column:object_key;type:varchar(1024);not null" json:"-"`
+	Sha256     string     `gorm:"column:sha256;type:char(64)" json:"sha256,omitempty"`
+	Status     FileStatus `gorm:"column:status;type:varchar(20);not null;default:ready" json:"status"`
+	ScanStatus ScanStatus `gorm:"column:scan_status;type:varchar(20);not null;default:pending" json:"scan_status"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+	DeletedAt  *time.Time `gorm:"column:deleted_at;index" json:"-"`
+
+	// Url is a presigned download url filled in on read, objects are private.
+	Url          string     `gorm:"-" json:"url,omitempty"`
+	UrlExpiresAt *time.Time `gorm:"-" json:"url_expires_at,omitempty"`
+
+	User     []User        `gorm:"foreignKey:avatar_id;references:id;constraint:OnDelete:SET NULL" json:"user,omitempty"`
+	Variants []FileVariant `gorm:"foreignKey:FileID;references:ID;constraint:OnDelete:CASCADE" json:"variants,omitempty"`
 }
 
 func (m *File) TableName() string {
 	return "files"
 }
+
+// FileVariant is a processed rendition of a file, like a resized avatar.
+// Name tells the variants of a file apart, e.g. "64" for the 64px avatar.
+type FileVariant struct {
+	ID        string    `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	FileID    string    `gorm:"column:file_id;type:uuid;not null" json:"file_id"`
+	Name      string    `gorm:"column:name;type:varchar(50);not null" json:"name"`
+	MimeType  string    `gorm:"column:mime_type;type:varchar(255);not null" json:"mime_type"`
+	Size      int64     `gorm:"column:size;type:bigint;not null" json:"size"`
+	Width     int       `gorm:"column:width;not null" json:"width"`
+	Height    int       `gorm:"column:height;not null" json:"height"`
+	ObjectKey string    `gorm:"column:object_key;type:varchar(1024);not null" json:"-"`
+	Sha256    string    `gorm:"column:sha256;type:char(64)" json:"sha256,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+
+	// Url is a presigned download url filled in on read.
+	Url          string     `gorm:"-" json:"url,omitempty"`
+	UrlExpiresAt *time.Time `gorm:"-" json:"url_expires_at,omitempty"`
+}
+
+func (m *FileVariant) TableName() string {
+	return "file_variants"
+}
+
+// FileBlob is a stored object shared by every file and variant with the same
+// content, its key derives from the content hash. RefCount is how many rows
+// point at it, the file sweep deletes blobs nothing points at anymore.
+type FileBlob struct {
+	ObjectKey string    `gorm:"column:object_key;type:varchar(1024);primary_key" json:"-"`
+	Sha256    string    `gorm:"column:sha256;type:char(64);not null" json:"sha256"`
+	Size      int64     `gorm:"column:size;type:bigint;not null" json:"size"`
+	MimeType  string    `gorm:"column:mime_type;type:varchar(255);not null" json:"mime_type"`
+	RefCount  int64     `gorm:"column:ref_count;type:bigint;not null;default:0" json:"ref_count"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (m *FileBlob) TableName() string {
+	return "file_blobs"
+}
+
+// FileSweepReport lists what a file sweep deleted, or would have deleted on
+// a dry run.
+type FileSweepReport struct {
+	DryRun bool `json:"dry_run"`
+
+	// UnreferencedFiles are moved to the trash, PurgedFiles were in the
+	// trash long enough to be removed along with their objects.
+	UnreferencedFiles []string `json:"unreferenced_files"`
+	PurgedFiles       []string `json:"purged_files"`
+
+	// UnusedBlobs are shared objects whose last file was purged.
+	UnusedBlobs []string `json:"unused_blobs"`
+
+	// OrphanedObjects have no file or variant row. FailedObjects could not
+	// be deleted and are tried again on the next sweep.
+	OrphanedObjects []string `json:"orphaned_objects"`
+	FailedObjects   []string `json:"failed_objects"`
+	ReclaimedBytes  int64    `json:"reclaimed_bytes"`
+}
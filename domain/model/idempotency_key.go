@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyKey is the stored outcome of a request sent with an
+// Idempotency-Key header. Status stays zero while the request is in flight.
+type IdempotencyKey struct {
+	UserID      string    `gorm:"column:user_id;type:uuid;primary_key" json:"user_id"`
+	Key         string    `gorm:"column:key;type:varchar(255);primary_key" json:"key"`
+	Fingerprint string    `gorm:"column:fingerprint;type:varchar(64);not null" json:"fingerprint"`
+	Status      int       `gorm:"column:status;type:int;not null;default:0" json:"status"`
+	ContentType string    `gorm:"column:content_type;type:varchar(255)" json:"content_type"`
+	Body        []byte    `gorm:"column:body;type:bytea" json:"-"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
+}
+
+func (m *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// IsPending reports whether the request holding the key hasn't finished yet.
+func (m *IdempotencyKey) IsPending() bool {
+	return m.Status == 0
+}
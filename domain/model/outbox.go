@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxAggregate string
+
+const (
+	OutboxAggregateTodo OutboxAggregate = "todo"
+	OutboxAggregateUser OutboxAggregate = "user"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// that raised it. The relay publishes it to the sinks afterwards, so an event
+// is never lost when the process dies between saving and publishing.
+type OutboxEvent struct {
+	ID             int64           `gorm:"column:id;primary_key;autoIncrement" json:"id"`
+	EventID        string          `gorm:"column:event_id;type:uuid;not null" json:"event_id"`
+	AggregateType  OutboxAggregate `gorm:"column:aggregate_type;type:varchar(50);not null" json:"aggregate_type"`
+	AggregateID    string          `gorm:"column:aggregate_id;type:uuid;not null" json:"aggregate_id"`
+	UserID         string          `gorm:"column:user_id;type:uuid;not null" json:"user_id"`
+	EventType      string          `gorm:"column:event_type;type:varchar(100);not null" json:"event_type"`
+	Payload        json.RawMessage `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Attempts       int             `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time       `gorm:"column:next_attempt_at;not null" json:"next_attempt_at"`
+	LastError      *string         `gorm:"column:last_error" json:"last_error"`
+	DeliveredSinks StringList      `gorm:"column:delivered_sinks;type:jsonb;not null;default:'[]'" json:"delivered_sinks"`
+	PublishedAt    *time.Time      `gorm:"column:published_at" json:"published_at"`
+	FailedAt       *time.Time      `gorm:"column:failed_at" json:"failed_at"`
+	CreatedAt      time.Time       `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+}
+
+func (m *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// Delivered reports whether the sink already received the event on an
+// earlier attempt.
+func (m *OutboxEvent) Delivered(sink string) bool {
+	for _, delivered := range m.DeliveredSinks {
+		if delivered == sink {
+			return true
+		}
+	}
+	return false
+}
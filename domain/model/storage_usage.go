@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// StorageUsage is how many bytes the live files of a user with the same
+// mimetype take, variants included. It is kept up to date along with the
+// files and can be recomputed from them.
+type StorageUsage struct {
+	UserID    string    `gorm:"column:user_id;type:uuid;primary_key" json:"user_id"`
+	MimeType  string    `gorm:"column:mime_type;type:varchar(255);primary_key" json:"mime_type"`
+	Bytes     int64     `gorm:"column:bytes;type:bigint;not null;default:0" json:"bytes"`
+	Files     int64     `gorm:"column:files;type:bigint;not null;default:0" json:"files"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (m *StorageUsage) TableName() string {
+	return "storage_usages"
+}
+
+// StorageReport is the storage a user uses against their quota.
+type StorageReport struct {
+	Plan           string                 `json:"plan"`
+	QuotaBytes     int64                  `json:"quota_bytes"`
+	UsedBytes      int64                  `json:"used_bytes"`
+	AvailableBytes int64                  `json:"available_bytes"`
+	Files          int64                  `json:"files"`
+	Categories     []StorageCategoryUsage `json:"categories"`
+}
+
+// StorageCategoryUsage is the usage of one file category, like image or
+// document.
+type StorageCategoryUsage struct {
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+	Files    int64  `json:"files"`
+}
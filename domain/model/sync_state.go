@@ -0,0 +1,15 @@
+package model
+
+// SyncState holds the per user change sequence used by offline sync. Seq is
+// bumped on every todo write and PurgedSeq is the highest sequence of a todo
+// that was permanently deleted, so clients that may have missed its tombstone
+// can be told to resync.
+type SyncState struct {
+	UserID    string `gorm:"column:user_id;type:uuid;primary_key" json:"user_id"`
+	Seq       int64  `gorm:"column:seq;type:bigint;not null;default:0" json:"seq"`
+	PurgedSeq int64  `gorm:"column:purged_seq;type:bigint;not null;default:0" json:"purged_seq"`
+}
+
+func (m *SyncState) TableName() string {
+	return "sync_states"
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type TodoAttachment struct {
+	TodoID    string    `gorm:"column:todo_id;type:uuid;primary_key" json:"todo_id"`
+	FileID    string    `gorm:"column:file_id;type:uuid;primary_key" json:"file_id"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+}
+
+func (m *TodoAttachment) TableName() string {
+	return "todo_attachments"
+}
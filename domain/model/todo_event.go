@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type TodoEventType string
+
+const (
+	TodoEventCreated  TodoEventType = "todo.created"
+	TodoEventUpdated  TodoEventType = "todo.updated"
+	TodoEventDeleted  TodoEventType = "todo.deleted"
+	TodoEventRestored TodoEventType = "todo.restored"
+	TodoEventPurged   TodoEventType = "todo.purged"
+)
+
+// TodoEvent is raised whenever a todo changes. It is pushed to the owner's
+// stream clients and webhooks as is.
+type TodoEvent struct {
+	ID         string        `json:"id"`
+	Type       TodoEventType `json:"type"`
+	UserID     string        `json:"user_id"`
+	Todo       Todo          `json:"todo"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type TodoHistory struct {
+	ID        string           `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	TodoID    string           `gorm:"column:todo_id;type:uuid;not null" json:"todo_id"`
+	UserID    string           `gorm:"column:user_id;type:uuid;not null" json:"user_id"`
+	Event     TodoHistoryEvent `gorm:"column:event;type:varchar(50);not null" json:"event"`
+	Changes   Changes          `gorm:"column:changes;type:jsonb" json:"changes,omitempty"`
+	CreatedAt time.Time        `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+}
+
+func (m *TodoHistory) TableName() string {
+	return "todo_histories"
+}
+
+type TodoHistoryEvent string
+
+const (
+	TodoHistoryEventCreated       TodoHistoryEvent = "created"
+	TodoHistoryEventRenamed       TodoHistoryEvent = "renamed"
+	TodoHistoryEventStatusChanged TodoHistoryEvent = "status_changed"
+	TodoHistoryEventMoved         TodoHistoryEvent = "moved"
+	TodoHistoryEventUpdated       TodoHistoryEvent = "updated"
+	TodoHistoryEventDeleted       TodoHistoryEvent = "deleted"
+	TodoHistoryEventRestored      TodoHistoryEvent = "restored"
+)
+
+// TodoHistoryEventFromChanges picks the most specific event describing the changes.
+func TodoHistoryEventFromChanges(changes Changes) TodoHistoryEvent {
+	if len(changes) == 1 {
+		if _, ok := changes["name"]; ok {
+			return TodoHistoryEventRenamed
+		}
+		if _, ok := changes["status"]; ok {
+			return TodoHistoryEventStatusChanged
+		}
+		if _, ok := changes["position"]; ok {
+			return TodoHistoryEventMoved
+		}
+	}
+	return TodoHistoryEventUpdated
+}
+
+// Change holds the old and new value of a single column.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Changes maps a column name to its change, stored as jsonb.
+type Changes map[string]Change
+
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("unsupported type for changes: %T", value)
+	}
+}
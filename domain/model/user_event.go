@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type UserEventType string
+
+const (
+	UserEventRegistered UserEventType = "user.registered"
+	UserEventUpdated    UserEventType = "user.updated"
+)
+
+// UserEvent is raised whenever a user registers or changes their profile.
+type UserEvent struct {
+	ID         string        `json:"id"`
+	Type       UserEventType `json:"type"`
+	UserID     string        `json:"user_id"`
+	User       User          `json:"user"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
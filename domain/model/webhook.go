@@ -0,0 +1,97 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WebhookEventAll subscribes a webhook to every event type.
+const WebhookEventAll = "*"
+
+// WebhookEventTest is only sent by the "send test event" endpoint.
+const WebhookEventTest = "webhook.test"
+
+type Webhook struct {
+	ID           string     `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	UserID       string     `gorm:"column:user_id;type:uuid;not null" json:"user_id"`
+	URL          string     `gorm:"column:url;type:varchar(2048);not null" json:"url"`
+	Secret       string     `gorm:"column:secret;type:varchar(255);not null" json:"-"`
+	EventTypes   StringList `gorm:"column:event_types;type:jsonb;not null" json:"event_types"`
+	IsActive     bool       `gorm:"column:is_active;not null;default:true" json:"is_active"`
+	FailureCount int        `gorm:"column:failure_count;not null;default:0" json:"failure_count"`
+	DisabledAt   *time.Time `gorm:"column:disabled_at" json:"disabled_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+	DeletedAt    *time.Time `gorm:"column:deleted_at;index" json:"-"`
+}
+
+func (m *Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes reports whether the webhook wants events of eventType.
+func (m *Webhook) Subscribes(eventType string) bool {
+	for _, t := range m.EventTypes {
+		if t == WebhookEventAll || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// latest attempt. Pending deliveries are retried at NextAttemptAt.
+type WebhookDelivery struct {
+	ID             string                `gorm:"column:id;type:uuid;primary_key" json:"id"`
+	WebhookID      string                `gorm:"column:webhook_id;type:uuid;not null" json:"webhook_id"`
+	EventID        string                `gorm:"column:event_id;type:uuid;not null" json:"event_id"`
+	EventType      string                `gorm:"column:event_type;type:varchar(100);not null" json:"event_type"`
+	Payload        json.RawMessage       `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	Attempts       int                   `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time            `gorm:"column:next_attempt_at" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `gorm:"column:last_attempt_at" json:"last_attempt_at"`
+	ResponseStatus *int                  `gorm:"column:response_status" json:"response_status"`
+	ResponseBody   *string               `gorm:"column:response_body;type:text" json:"response_body"`
+	Error          *string               `gorm:"column:error;type:text" json:"error"`
+	CreatedAt      time.Time             `gorm:"column:created_at;autoCreateTime:milli" json:"created_at"`
+	UpdatedAt      time.Time             `gorm:"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (m *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// StringList is a list of strings stored as jsonb.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("unsupported type for string list: %T", value)
+	}
+}
@@ -0,0 +1,5 @@
+package request
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
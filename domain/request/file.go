@@ -0,0 +1,21 @@
+package request
+
+import "io"
+
+// CreateUploadURLRequest reserves a file for a presigned upload. The client
+// must upload exactly size bytes with the given mime type and hex SHA-256
+// checksum.
+type CreateUploadURLRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	MimeType string `json:"mime_type" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+	Sha256   string `json:"sha256" validate:"required,len=64,hexadecimal"`
+}
+
+// FilePart is a file streamed from a multipart request as it arrives, its
+// size is unknown until Body is read to the end.
+type FilePart struct {
+	Filename    string
+	ContentType string
+	Body        io.Reader
+}
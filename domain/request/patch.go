@@ -0,0 +1,12 @@
+package request
+
+import "golang-gorm/helpers"
+
+// PatchRequest carries a raw RFC 7396 merge patch or RFC 6902 JSON patch as
+// received, ContentType tells them apart.
+type PatchRequest struct {
+	ContentType string
+	Patch       []byte
+	Version     *int64
+	IfMatch     *helpers.IfMatch
+}
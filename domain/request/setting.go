@@ -1,6 +1,16 @@
 package request
 
+// UserUpdateProfileRequest takes the profile picture as a multipart upload or
+// as a base64 data uri in json.
 type UserUpdateProfileRequest struct {
-	Name           string `json:"name" validate:"required"`
-	ProfilePicture string `json:"profile_picture" validate:"required"`
+	Name           string    `json:"name" form:"name" validate:"required"`
+	ProfilePicture string    `json:"profile_picture" form:"-" validate:"required_without=Upload"`
+	Upload         *FilePart `json:"-" form:"-" validate:"required_without=ProfilePicture"`
+}
+
+// PatchProfileRequest is the patchable representation of a user profile. A
+// profile picture is only uploaded when the patch sets one.
+type PatchProfileRequest struct {
+	Name           string  `json:"name" validate:"required,max=255"`
+	ProfilePicture *string `json:"profile_picture,omitempty"`
 }
@@ -0,0 +1,28 @@
+package request
+
+import "golang-gorm/domain/model"
+
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+)
+
+type SyncPullRequest struct {
+	Since string `form:"since"`
+	Limit int    `form:"limit,default=500" validate:"min=1,max=1000"`
+}
+
+type SyncPushRequest struct {
+	Mutations []SyncMutation `json:"mutations" validate:"required,min=1,max=100,dive"`
+}
+
+// SyncMutation is a change made by a client while offline. Creates carry a
+// client generated id, Version is the version the client based its change on.
+type SyncMutation struct {
+	Op      string            `json:"op" validate:"required,oneof=create update delete"`
+	ID      string            `json:"id" validate:"required,uuid"`
+	Name    *string           `json:"name,omitempty"`
+	Status  *model.TodoStatus `json:"status,omitempty"`
+	Version *int64            `json:"version,omitempty" validate:"omitempty,min=1"`
+}
@@ -1,12 +1,63 @@
 package request
 
-import "golang-gorm/domain/model"
+import (
+	"golang-gorm/domain/model"
+	"golang-gorm/helpers"
+)
 
 type CreateTodoRequest struct {
-	Name string `json:"name" validate:"required"`
+	ID         *string          `json:"id" validate:"omitempty,uuid"`
+	Name       string           `json:"name" validate:"required"`
+	Status     model.TodoStatus `json:"status" validate:"omitempty,todo_status"`
+	ExternalID *string          `json:"external_id" validate:"omitempty,max=255"`
 }
 
 type UpdateTodoRequest struct {
-	Name   string           `json:"name" validate:"required"`
-	Status model.TodoStatus `json:"status" validate:"required,todo_status"`
+	Name    string           `json:"name" validate:"required"`
+	Status  model.TodoStatus `json:"status" validate:"required,todo_status"`
+	Version *int64           `json:"version" validate:"omitempty,min=1"`
+	IfMatch *helpers.IfMatch `json:"-"`
+}
+
+// PatchTodoRequest is the patchable representation of a todo. Patches are
+// applied to the current todo rendered as this struct, so fields missing from
+// the patch keep their stored value.
+type PatchTodoRequest struct {
+	Name    string           `json:"name" validate:"required,max=255"`
+	Status  model.TodoStatus `json:"status" validate:"required,todo_status"`
+	Version int64            `json:"version" validate:"min=1"`
+}
+
+type DeleteTodoRequest struct {
+	Version *int64           `json:"version" validate:"omitempty,min=1"`
+	IfMatch *helpers.IfMatch `json:"-"`
+}
+
+type MoveTodoRequest struct {
+	BeforeID *string `json:"before_id" validate:"omitempty,uuid"`
+	AfterID  *string `json:"after_id" validate:"omitempty,uuid"`
+}
+
+// UploadAttachmentRequest takes the file as a multipart upload, base64 in
+// json, or the id of a completed presigned upload.
+type UploadAttachmentRequest struct {
+	Name   string    `json:"name" form:"name"`
+	File   string    `json:"file" form:"-" validate:"required_without_all=Upload FileID"`
+	Upload *FilePart `json:"-" form:"-" validate:"required_without_all=File FileID"`
+	FileID string    `json:"file_id" form:"file_id" validate:"omitempty,uuid"`
+}
+
+type ExportTodoRequest struct {
+	Format string `form:"format,default=json" validate:"required,oneof=csv json ics"`
+}
+
+type ImportTodoRequest struct {
+	Format string `form:"format" validate:"required,oneof=csv json ics todoist trello"`
+	DryRun *bool  `form:"dry_run"`
+}
+
+type ImportTodoRow struct {
+	ExternalID string           `json:"external_id" validate:"required,max=255"`
+	Name       string           `json:"name" validate:"required,max=255"`
+	Status     model.TodoStatus `json:"status" validate:"required,todo_status"`
 }
@@ -0,0 +1,12 @@
+package request
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=* todo.created todo.updated todo.deleted todo.restored todo.purged"`
+}
+
+type UpdateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=* todo.created todo.updated todo.deleted todo.restored todo.purged"`
+	IsActive   *bool    `json:"is_active"`
+}
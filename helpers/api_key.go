@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every api key, so they can be told apart from
+// passwords.
+const APIKeyPrefix = "tk_"
+
+// apiKeyDisplayLength is how much of a key is kept to show it to its owner.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey returns a random api key.
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// IsAPIKey reports whether value looks like an api key.
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, APIKeyPrefix) && len(value) > apiKeyDisplayLength
+}
+
+// HashAPIKey returns the hash an api key is stored and looked up by.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyDisplayPrefix returns the start of key shown to its owner.
+func APIKeyDisplayPrefix(key string) string {
+	if len(key) < apiKeyDisplayLength {
+		return key
+	}
+	return key[:apiKeyDisplayLength]
+}
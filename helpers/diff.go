@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"golang-gorm/domain/model"
+)
+
+// ignoredDiffColumns are bookkeeping columns that never show up in a diff.
+var ignoredDiffColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"version":    true,
+	"sync_seq":   true,
+}
+
+// DiffModel compares two values of the same gorm model column by column and
+// returns the changed columns. Relations and fields without a column tag are
+// skipped, so new columns are tracked without touching this function.
+func DiffModel(oldModel, newModel interface{}) model.Changes {
+	changes := model.Changes{}
+
+	oldValue := reflect.Indirect(reflect.ValueOf(oldModel))
+	newValue := reflect.Indirect(reflect.ValueOf(newModel))
+	if oldValue.Type() != newValue.Type() || oldValue.Kind() != reflect.Struct {
+		return changes
+	}
+
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		column := gormColumn(field)
+		if column == "" || ignoredDiffColumns[column] || !isDiffableType(field.Type) {
+			continue
+		}
+
+		oldField := reflect.Indirect(oldValue.Field(i))
+		newField := reflect.Indirect(newValue.Field(i))
+		if oldField.IsValid() && newField.IsValid() && reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+		if !oldField.IsValid() && !newField.IsValid() {
+			continue
+		}
+
+		changes[column] = model.Change{
+			Old: valueOrNil(oldField),
+			New: valueOrNil(newField),
+		}
+	}
+
+	return changes
+}
+
+func gormColumn(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if strings.HasPrefix(part, "column:") {
+			return strings.TrimPrefix(part, "column:")
+		}
+	}
+	return ""
+}
+
+func isDiffableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map:
+		return false
+	case reflect.Struct:
+		return t == reflect.TypeOf(time.Time{})
+	default:
+		return true
+	}
+}
+
+func valueOrNil(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
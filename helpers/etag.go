@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatch is the precondition of an If-Match header. It fails when the
+// resource doesn't exist, otherwise the wildcard matches any version and a
+// list of tags the versions listed.
+type IfMatch struct {
+	Any      bool
+	Versions []int64
+}
+
+// ParseIfMatch parses an If-Match header holding the wildcard or a comma
+// separated list of tags. Weak tags and tags not made by ETag never match,
+// If-Match uses the strong comparison. It fails on a malformed header.
+func ParseIfMatch(header string) (*IfMatch, bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return &IfMatch{Any: true}, true
+	}
+
+	ifMatch := &IfMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		opaque := strings.TrimPrefix(tag, "W/")
+		if len(opaque) < 2 || !strings.HasPrefix(opaque, `"`) || !strings.HasSuffix(opaque, `"`) {
+			return nil, false
+		}
+		if opaque != tag {
+			continue
+		}
+		if version, err := strconv.ParseInt(strings.Trim(opaque, `"`), 10, 64); err == nil {
+			ifMatch.Versions = append(ifMatch.Versions, version)
+		}
+	}
+	return ifMatch, true
+}
+
+// Matches reports whether the current version of an existing resource
+// satisfies the precondition, a nil precondition always does.
+func (m *IfMatch) Matches(version int64) bool {
+	if m == nil || m.Any {
+		return true
+	}
+	for _, v := range m.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ETagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison required for If-None-Match.
+func ETagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
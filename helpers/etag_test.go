@@ -0,0 +1,38 @@
+package helpers
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		ok      bool
+		matches []int64
+		misses  []int64
+	}{
+		{`*`, true, []int64{1, 7}, nil},
+		{`"3"`, true, []int64{3}, []int64{2, 4}},
+		{`"2", "5" ,"9"`, true, []int64{2, 5, 9}, []int64{3}},
+		{`W/"3"`, true, nil, []int64{3}},
+		{`W/"3", "4"`, true, []int64{4}, []int64{3}},
+		{`"abc"`, true, nil, []int64{1}},
+		{`3`, false, nil, nil},
+		{`"3", 4`, false, nil, nil},
+	}
+	for _, tt := range tests {
+		ifMatch, ok := ParseIfMatch(tt.header)
+		if ok != tt.ok {
+			t.Errorf("%s: parsed %v, want %v", tt.header, ok, tt.ok)
+			continue
+		}
+		for _, version := range tt.matches {
+			if !ifMatch.Matches(version) {
+				t.Errorf("%s: version %d doesn't match", tt.header, version)
+			}
+		}
+		for _, version := range tt.misses {
+			if ifMatch.Matches(version) {
+				t.Errorf("%s: version %d matches", tt.header, version)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// ICalTodo is the subset of an iCalendar VTODO component the app understands.
+type ICalTodo struct {
+	UID          string
+	Summary      string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+	SortOrder    string
+}
+
+// WriteICalHeader starts a VCALENDAR stream.
+func WriteICalHeader(w io.Writer) error {
+	return writeICalLines(w,
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//golang-gorm//todo//EN",
+	)
+}
+
+// WriteICalFooter ends a VCALENDAR stream.
+func WriteICalFooter(w io.Writer) error {
+	return writeICalLines(w, "END:VCALENDAR")
+}
+
+// WriteICalTodo writes a single VTODO component.
+func WriteICalTodo(w io.Writer, todo ICalTodo) error {
+	lines := []string{
+		"BEGIN:VTODO",
+		"UID:" + escapeICalText(todo.UID),
+		"DTSTAMP:" + todo.LastModified.UTC().Format(icalTimeFormat),
+		"SUMMARY:" + escapeICalText(todo.Summary),
+		"STATUS:" + todo.Status,
+		"CREATED:" + todo.Created.UTC().Format(icalTimeFormat),
+		"LAST-MODIFIED:" + todo.LastModified.UTC().Format(icalTimeFormat),
+	}
+	if todo.Status == "COMPLETED" {
+		lines = append(lines, "COMPLETED:"+todo.LastModified.UTC().Format(icalTimeFormat))
+	}
+	if todo.SortOrder != "" {
+		lines = append(lines, "X-APPLE-SORT-ORDER:"+escapeICalText(todo.SortOrder))
+	}
+	lines = append(lines, "END:VTODO")
+
+	return writeICalLines(w, lines...)
+}
+
+// ParseICalTodos reads every VTODO component from an iCalendar stream.
+func ParseICalTodos(r io.Reader) ([]ICalTodo, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []ICalTodo{}
+	var current *ICalTodo
+	for _, line := range lines {
+		name, value, ok := splitICalLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			current = &ICalTodo{}
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			if current == nil {
+				return nil, fmt.Errorf("unexpected END:VTODO")
+			}
+			todos = append(todos, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = unescapeICalText(value)
+		case name == "SUMMARY":
+			current.Summary = unescapeICalText(value)
+		case name == "STATUS":
+			current.Status = strings.ToUpper(value)
+		case name == "CREATED":
+			current.Created, _ = time.Parse(icalTimeFormat, value)
+		case name == "LAST-MODIFIED":
+			current.LastModified, _ = time.Parse(icalTimeFormat, value)
+		case name == "X-APPLE-SORT-ORDER":
+			current.SortOrder = unescapeICalText(value)
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated VTODO")
+	}
+
+	return todos, nil
+}
+
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICalLine returns the property name without parameters and its value.
+func splitICalLine(line string) (string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", "", false
+	}
+	name := line[:colon]
+	if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+		name = name[:semicolon]
+	}
+	return strings.ToUpper(name), line[colon+1:], true
+}
+
+func writeICalLines(w io.Writer, lines ...string) error {
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICalLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// foldICalLine splits lines longer than 75 octets without breaking a rune.
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space
+		limit = 74
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func escapeICalText(text string) string {
+	return icalEscaper.Replace(text)
+}
+
+func unescapeICalText(text string) string {
+	return icalUnescaper.Replace(text)
+}
@@ -0,0 +1,107 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrImageInvalid  = errors.New("image could not be decoded")
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// decodableImageFormats are the formats DecodeImage accepts, other decoders
+// registered by imported packages are ignored.
+var decodableImageFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+}
+
+// ImageLimits bound the decoded size of an image, a few kilobytes of
+// compressed data can claim dimensions that take gigabytes to decode.
+type ImageLimits struct {
+	MaxSide   int
+	MaxPixels int
+}
+
+// EncodedImage is an image ready to be stored.
+type EncodedImage struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// DecodeImage checks the dimensions in the header against limits before
+// decoding, then decodes and applies the EXIF orientation. The decoded image
+// carries no metadata, so encoding it again strips EXIF including GPS. The
+// first frame of animated images is used.
+func DecodeImage(data []byte, limits ImageLimits) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !decodableImageFormats[format] {
+		return nil, "", ErrImageInvalid
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", ErrImageInvalid
+	}
+	if config.Width > limits.MaxSide || config.Height > limits.MaxSide || config.Width*config.Height > limits.MaxPixels {
+		return nil, "", fmt.Errorf("%w, at most %dx%d and %d pixels", ErrImageTooLarge, limits.MaxSide, limits.MaxSide, limits.MaxPixels)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", ErrImageInvalid
+	}
+
+	return img, format, nil
+}
+
+// SquareImage center-crops img to a square and scales it down to at most
+// side pixels. Smaller images are not scaled up.
+func SquareImage(img image.Image, side int) image.Image {
+	bounds := img.Bounds()
+	square := min(bounds.Dx(), bounds.Dy())
+	img = imaging.CropCenter(img, square, square)
+
+	if square > side {
+		img = imaging.Resize(img, side, side, imaging.Lanczos)
+	}
+	return img
+}
+
+// EncodeImage encodes img as JPEG when it came from a JPEG and as PNG
+// otherwise, keeping transparency.
+func EncodeImage(img image.Image, sourceFormat string) (*EncodedImage, error) {
+	buffer := &bytes.Buffer{}
+	encoded := &EncodedImage{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	switch sourceFormat {
+	case "jpeg":
+		encoded.MimeType = "image/jpeg"
+		if err := jpeg.Encode(buffer, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+	default:
+		encoded.MimeType = "image/png"
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(buffer, img); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded.Data = buffer.Bytes()
+	return encoded, nil
+}
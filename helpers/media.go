@@ -1,10 +1,22 @@
 package helpers
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrMimeTypeUnsupported is returned for mime types without a known file
+// extension.
+var ErrMimeTypeUnsupported = errors.New("unsupported mime type")
 
 func GetExtensionFromMimeType(mimeType string) (string, error) {
 	switch mimeType {
-	case "image/jpeg":
+	case "image/jpeg", "image/jpg":
 		return "jpg", nil
 	case "image/png":
 		return "png", nil
@@ -14,14 +26,56 @@ func GetExtensionFromMimeType(mimeType string) (string, error) {
 		return "webp", nil
 	case "application/pdf":
 		return "pdf", nil
+	case "text/plain":
+		return "txt", nil
+	case "text/csv":
+		return "csv", nil
+	case "application/msword":
+		return "doc", nil
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return "docx", nil
+	case "application/vnd.ms-excel":
+		return "xls", nil
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return "xlsx", nil
+	case "application/vnd.ms-powerpoint":
+		return "ppt", nil
+	case "application/vnd.openxmlformats-officedocument.presentationml.presentation":
+		return "pptx", nil
+	case "application/zip", "application/x-zip-compressed":
+		return "zip", nil
+	case "application/gzip", "application/x-gzip":
+		return "gz", nil
+	case "application/x-tar":
+		return "tar", nil
+	case "application/x-7z-compressed":
+		return "7z", nil
 	default:
-		return "", fmt.Errorf("unsupported mime type: %s", mimeType)
+		return "", fmt.Errorf("%w: %s", ErrMimeTypeUnsupported, mimeType)
 	}
 }
 
 var allowedMimeTypes = map[string][]string{
-	"image":    {"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp"},
-	"document": {"application/pdf"},
+	"image": {"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp"},
+	"document": {
+		"application/pdf",
+		"text/plain",
+		"text/csv",
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.ms-excel",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+	"archive": {
+		"application/zip",
+		"application/x-zip-compressed",
+		"application/gzip",
+		"application/x-gzip",
+		"application/x-tar",
+		"application/x-7z-compressed",
+	},
 }
 
 func IsMimeTypeAllowed(mimeType string, category string) bool {
@@ -36,3 +90,97 @@ func IsMimeTypeAllowed(mimeType string, category string) bool {
 	}
 	return false
 }
+
+// GetMimeTypeCategory returns the whitelist category a mime type belongs to,
+// or an empty string when it is not whitelisted at all.
+func GetMimeTypeCategory(mimeType string) string {
+	for category := range allowedMimeTypes {
+		if IsMimeTypeAllowed(mimeType, category) {
+			return category
+		}
+	}
+	return ""
+}
+
+// ParseBase64DataURI splits a "data:<mime>;base64,<data>" string into its
+// mime type and decoded content.
+func ParseBase64DataURI(dataURI string) (string, []byte, error) {
+	// split metadata and data base64
+	parts := strings.SplitN(dataURI, ",", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("invalid base64 data")
+	}
+
+	// get mimetype
+	meta := parts[0]
+	mimeType := strings.TrimPrefix(strings.Split(meta, ";")[0], "data:")
+	if mimeType == "" {
+		return "", nil, fmt.Errorf("unable to parse MIME type")
+	}
+
+	// decode base64
+	data, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return mimeType, data, nil
+}
+
+// SniffLength is how many leading bytes SniffMimeType looks at.
+const SniffLength = 512
+
+// sniffCompatible lists the claimed mime types that content sniffing can't
+// tell apart from the detected one, like office documents that are zip
+// archives.
+var sniffCompatible = map[string][]string{
+	"application/zip": {
+		"application/x-zip-compressed",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+	"application/x-gzip": {"application/gzip"},
+	"text/plain":         {"text/csv"},
+	"application/x-ole-storage": {
+		"application/msword",
+		"application/vnd.ms-excel",
+		"application/vnd.ms-powerpoint",
+	},
+}
+
+var (
+	oleSignature      = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	sevenZipSignature = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+)
+
+// SniffMimeType detects the mime type of a file from its first bytes. The
+// claimed type is only kept when the content can't be told apart from it,
+// so a renamed executable doesn't pass as an image.
+func SniffMimeType(head []byte, claimed string) string {
+	detected := http.DetectContentType(head)
+	if mediaType, _, err := mime.ParseMediaType(detected); err == nil {
+		detected = mediaType
+	}
+
+	// formats net/http doesn't know about
+	if detected == "application/octet-stream" {
+		switch {
+		case bytes.HasPrefix(head, oleSignature):
+			detected = "application/x-ole-storage"
+		case bytes.HasPrefix(head, sevenZipSignature):
+			detected = "application/x-7z-compressed"
+		case len(head) >= 262 && string(head[257:262]) == "ustar":
+			detected = "application/x-tar"
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(claimed); err == nil {
+		for _, compatible := range sniffCompatible[detected] {
+			if mediaType == compatible {
+				return mediaType
+			}
+		}
+	}
+	return detected
+}
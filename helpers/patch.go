@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var ErrUnsupportedPatchType = errors.New("unsupported patch content type, use " + MergePatchContentType + " or " + JSONPatchContentType)
+
+// ErrPatchTestFailed is returned when a JSON patch test operation fails.
+var ErrPatchTestFailed = jsonpatch.ErrTestFailed
+
+// ApplyPatch applies an RFC 7396 merge patch or an RFC 6902 JSON patch to the
+// JSON representation of target and decodes the result back into it. The
+// patch format is picked from contentType, plain application/json is treated
+// as a merge patch. Fields the patch removes are reset to their zero value
+// and unknown fields are rejected.
+func ApplyPatch(contentType string, patch []byte, target interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return ErrUnsupportedPatchType
+	}
+
+	document, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case MergePatchContentType, "application/json", "":
+		patched, err = jsonpatch.MergePatch(document, patch)
+	case JSONPatchContentType:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(document)
+		}
+	default:
+		return ErrUnsupportedPatchType
+	}
+	if err != nil {
+		return err
+	}
+
+	// start from a zero value so removed fields don't keep their old value
+	value := reflect.ValueOf(target).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
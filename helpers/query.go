@@ -2,8 +2,19 @@ package helpers
 
 import "gorm.io/gorm"
 
+// CommonFilter applies the filters shared by every repository. Soft-deleted
+// rows are hidden unless "with_trashed" (include them) or "only_trashed"
+// (return nothing else) is set to true.
 func CommonFilter(query *gorm.DB, filter map[string]interface{}) *gorm.DB {
-	query = query.Where("deleted_at IS NULL")
+	onlyTrashed, _ := filter["only_trashed"].(bool)
+	withTrashed, _ := filter["with_trashed"].(bool)
+	switch {
+	case onlyTrashed:
+		query = query.Where("deleted_at IS NOT NULL")
+	case !withTrashed:
+		query = query.Where("deleted_at IS NULL")
+	}
+
 	if id, ok := filter["id"].(string); ok {
 		query = query.Where("id = ?", id)
 	}
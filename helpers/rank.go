@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+)
+
+// rankAlphabet is in ascii order so ranks sort correctly with COLLATE "C".
+const rankAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRankRange = errors.New("rank range is empty")
+
+// RankBetween returns a lexicographic rank that sorts strictly between before
+// and after. An empty before means the start of the list and an empty after
+// means the end of the list. Ranks never end with the lowest digit, so there
+// is always room to insert another rank in front of any of them.
+func RankBetween(before, after string) (string, error) {
+	if after != "" && before >= after {
+		return "", ErrInvalidRankRange
+	}
+	return rankMidpoint(before, after), nil
+}
+
+// RankSequence returns n evenly spaced ranks of the same length, used to
+// rebalance a list whose ranks have grown too long.
+func RankSequence(n int) []string {
+	base := uint64(len(rankAlphabet))
+
+	// pick a width leaving at least one full digit of room between neighbours
+	width := 1
+	space := base
+	for space/uint64(n+1) < base && width < 10 {
+		width++
+		space *= base
+	}
+	step := space / uint64(n+1)
+
+	ranks := make([]string, n)
+	for i := range ranks {
+		rank := encodeRank((uint64(i)+1)*step, width)
+		if rank[len(rank)-1] == rankAlphabet[0] {
+			rank += string(rankAlphabet[base/2])
+		}
+		ranks[i] = rank
+	}
+
+	return ranks
+}
+
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// keep the common prefix, treating missing digits of a as zero
+		n := 0
+		for n < len(b) && rankDigit(a, n) == strings.IndexByte(rankAlphabet, b[n]) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(rankTail(a, n), b[n:])
+		}
+	}
+
+	digitA := rankDigit(a, 0)
+	digitB := len(rankAlphabet)
+	if b != "" {
+		digitB = strings.IndexByte(rankAlphabet, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		switch {
+		case b == "":
+			// appending: step by one so repeated appends grow slowly
+			return string(rankAlphabet[digitA+1])
+		case a == "":
+			// prepending: step by one towards the start
+			return string(rankAlphabet[digitB-1])
+		default:
+			return string(rankAlphabet[(digitA+digitB+1)/2])
+		}
+	}
+
+	// first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankAlphabet[digitA]) + rankMidpoint(rankTail(a, 1), "")
+}
+
+func rankDigit(rank string, i int) int {
+	if i >= len(rank) {
+		return 0
+	}
+	return strings.IndexByte(rankAlphabet, rank[i])
+}
+
+func rankTail(rank string, i int) string {
+	if i >= len(rank) {
+		return ""
+	}
+	return rank[i:]
+}
+
+func encodeRank(value uint64, width int) string {
+	base := uint64(len(rankAlphabet))
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = rankAlphabet[value%base]
+		value /= base
+	}
+	return string(buf)
+}
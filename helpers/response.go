@@ -1,5 +1,15 @@
 package helpers
 
+import "errors"
+
+// ErrNotFound and ErrAlreadyExists tell what a failed response is about,
+// for deliveries that have a status of their own for it. Usecases report
+// both as bad requests over http.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
 type (
 	ValidationError struct {
 		Error       bool
@@ -14,6 +24,7 @@ type Response struct {
 	Message    string            `json:"message"`
 	Validation []ValidationError `json:"validation,omitempty"`
 	Data       interface{}       `json:"data,omitempty"`
+	Err        error             `json:"-"`
 }
 
 type PaginatedResponse struct {
@@ -21,6 +32,7 @@ type PaginatedResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
 	Meta    interface{} `json:"meta"`
+	Err     error       `json:"-"`
 }
 
 func BuildResponse(data *Response) map[string]interface{} {
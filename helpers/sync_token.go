@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+// SyncToken is the opaque change token handed to sync clients. Seq is the
+// last change the client has seen and PurgedSeq the purge watermark when the
+// token was issued.
+type SyncToken struct {
+	Seq       int64
+	PurgedSeq int64
+}
+
+func (t SyncToken) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("v1:%d:%d", t.Seq, t.PurgedSeq)))
+}
+
+// ParseSyncToken decodes a token produced by SyncToken.String. An empty
+// token is the zero token, which asks for everything.
+func ParseSyncToken(token string) (SyncToken, error) {
+	if token == "" {
+		return SyncToken{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return SyncToken{}, ErrInvalidSyncToken
+	}
+
+	var t SyncToken
+	if _, err := fmt.Sscanf(string(raw), "v1:%d:%d", &t.Seq, &t.PurgedSeq); err != nil || t.Seq < 0 || t.PurgedSeq < 0 {
+		return SyncToken{}, ErrInvalidSyncToken
+	}
+
+	return t, nil
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"golang-gorm/app/config"
+	"golang-gorm/app/delivery/grpc/interceptor"
 	"golang-gorm/app/delivery/http/middleware"
+	userv1 "golang-gorm/proto/user/v1"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func init() {
@@ -46,6 +58,10 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// stop on interrupt or terminate
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// connect db
 	db := config.GetDB(viper.GetString("DB_URL"))
 
@@ -55,22 +71,57 @@ func main() {
 	// add logger
 	ginEngine.Use(middleware.Logger(io.MultiWriter(writers...)))
 
+	// init grpc, login and register work without a token
+	authInterceptor := interceptor.NewAuthInterceptor(
+		userv1.AuthService_Register_FullMethodName,
+		userv1.AuthService_Login_FullMethodName,
+	)
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authInterceptor.Unary()),
+		grpc.ChainStreamInterceptor(authInterceptor.Stream()),
+	)
+
 	// set bootstrap
 	bootstrapConfig := config.BootstrapConfig{
-		GinEngine: ginEngine,
-		DB:        db,
-		Validator: config.NewValidator(),
-		Timeout:   timeoutContext,
+		Context:    ctx,
+		GinEngine:  ginEngine,
+		GRPCServer: grpcServer,
+		DB:         db,
+		Validator:  config.NewValidator(),
+		Timeout:    timeoutContext,
 	}
 	config.Bootstrap(bootstrapConfig)
 
+	// grpc health and reflection
+	healthServer := health.NewServer()
+	for service := range grpcServer.GetServiceInfo() {
+		healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+
+	// run grpc beside gin
+	grpcPort := viper.GetString("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		logrus.Fatal("failed to listen for grpc: ", err)
+	}
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			logrus.Error("grpc server stopped: ", err)
+		}
+	}()
+
 	// cors
 	ginEngine.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match", "If-None-Match", "Idempotency-Key"},
 		AllowCredentials: true,
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Idempotent-Replayed"},
 		MaxAge:           12 * time.Hour,
 	}))
 
@@ -78,5 +129,24 @@ func main() {
 	port := viper.GetString("PORT")
 
 	// run gin
-	ginEngine.Run(":" + port)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: ginEngine,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatal("failed to serve http: ", err)
+		}
+	}()
+
+	// shut down gracefully, the canceled context stops the background jobs
+	<-ctx.Done()
+	logrus.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logrus.Error("http server shutdown: ", err)
+	}
+	grpcServer.GracefulStop()
 }